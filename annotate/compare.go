@@ -0,0 +1,140 @@
+package annotate
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+const (
+	// side by side panels are scaled to a shared height no taller than this
+	maxPanelHeight = 1024
+	// masks for Overlap are rasterised with their longest side at most this
+	maxMaskEdge = 512
+)
+
+// Panel is one labelled image of a side by side comparison
+type Panel struct {
+	Label string
+	Image image.Image
+}
+
+// SideBySide lays panels out left to right at a shared height, each captioned
+// with its label, and encodes them as a PNG
+func SideBySide(panels ...Panel) ([]byte, error) {
+	const (
+		gap     = 8
+		caption = 20
+	)
+	height := maxPanelHeight
+	for _, panel := range panels {
+		height = min(height, panel.Image.Bounds().Dy())
+	}
+
+	widths := make([]int, len(panels))
+	total := gap
+	for i, panel := range panels {
+		b := panel.Image.Bounds()
+		widths[i] = max(1, b.Dx()*height/max(1, b.Dy()))
+		total += widths[i] + gap
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, total, height+caption+gap))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.NRGBA{0x20, 0x20, 0x20, 0xff}), image.Point{}, draw.Src)
+	x := gap
+	for i, panel := range panels {
+		target := image.Rect(x, caption, x+widths[i], caption+height)
+		xdraw.ApproxBiLinear.Scale(canvas, target, panel.Image, panel.Image.Bounds(), draw.Src, nil)
+		d := font.Drawer{
+			Dst:  canvas,
+			Src:  image.White,
+			Face: basicfont.Face7x13,
+			Dot:  fixed.P(x, caption-6),
+		}
+		d.DrawString(panel.Label)
+		x += widths[i] + gap
+	}
+	return encode(canvas)
+}
+
+// Overlap is the intersection over union of the areas covered by a and b.
+// Only boxes and polygons cover an area; ok is false when neither side does.
+func Overlap(a, b []appschema.Region) (iou float64, ok bool) {
+	a, b = areas(a), areas(b)
+	if len(a) == 0 && len(b) == 0 {
+		return 0, false
+	}
+
+	var lo, hi appschema.Point
+	for i, region := range append(append([]appschema.Region{}, a...), b...) {
+		rlo, rhi := region.Bounds()
+		if i == 0 {
+			lo, hi = rlo, rhi
+			continue
+		}
+		lo.X, lo.Y = min(lo.X, rlo.X), min(lo.Y, rlo.Y)
+		hi.X, hi.Y = max(hi.X, rhi.X), max(hi.Y, rhi.Y)
+	}
+	scale := min(1, maxMaskEdge/max(hi.X-lo.X, hi.Y-lo.Y, 1))
+	size := image.Pt(int((hi.X-lo.X)*scale)+1, int((hi.Y-lo.Y)*scale)+1)
+
+	maskA, maskB := mask(a, lo, scale, size), mask(b, lo, scale, size)
+	var inter, union int
+	for i := range maskA.Pix {
+		inA, inB := maskA.Pix[i] >= 0x80, maskB.Pix[i] >= 0x80
+		if inA && inB {
+			inter++
+		}
+		if inA || inB {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0, false
+	}
+	return float64(inter) / float64(union), true
+}
+
+// areas keeps the regions that cover an area
+func areas(regions []appschema.Region) []appschema.Region {
+	var out []appschema.Region
+	for _, region := range regions {
+		if region.Type == appschema.RegionBox || region.Type == appschema.RegionPolygon {
+			out = append(out, region)
+		}
+	}
+	return out
+}
+
+// mask rasterises regions, shifted by origin and scaled, into an alpha mask
+func mask(regions []appschema.Region, origin appschema.Point, scale float64, size image.Point) *image.Alpha {
+	m := image.NewAlpha(image.Rectangle{Max: size})
+	if len(regions) == 0 {
+		return m
+	}
+	r := vector.NewRasterizer(size.X, size.Y)
+	at := func(p appschema.Point) (float32, float32) {
+		return float32((p.X - origin.X) * scale), float32((p.Y - origin.Y) * scale)
+	}
+	for _, region := range regions {
+		points := region.Points
+		if region.Type == appschema.RegionBox {
+			lo, hi := points[0], points[1]
+			points = []appschema.Point{lo, {X: hi.X, Y: lo.Y}, hi, {X: lo.X, Y: hi.Y}}
+		}
+		r.MoveTo(at(points[0]))
+		for _, p := range points[1:] {
+			r.LineTo(at(p))
+		}
+		r.ClosePath()
+	}
+	r.Draw(m, m.Bounds(), image.Opaque, image.Point{})
+	return m
+}
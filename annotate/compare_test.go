@@ -0,0 +1,75 @@
+package annotate
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math"
+	"testing"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+func TestOverlap(t *testing.T) {
+	box := func(x0, y0, x1, y1 float64) appschema.Region {
+		return appschema.Region{Type: appschema.RegionBox, Points: []appschema.Point{{X: x0, Y: y0}, {X: x1, Y: y1}}}
+	}
+	triangle := appschema.Region{Type: appschema.RegionPolygon, Points: []appschema.Point{{X: 0, Y: 0}, {X: 100, Y: 0}, {X: 0, Y: 100}}}
+	point := appschema.Region{Type: appschema.RegionPoint, Points: []appschema.Point{{X: 5, Y: 5}}}
+
+	tests := []struct {
+		name   string
+		a, b   []appschema.Region
+		want   float64
+		wantOK bool
+	}{
+		{name: "same box", a: []appschema.Region{box(0, 0, 100, 100)}, b: []appschema.Region{box(0, 0, 100, 100)}, want: 1, wantOK: true},
+		{name: "disjoint", a: []appschema.Region{box(0, 0, 100, 100)}, b: []appschema.Region{box(200, 200, 300, 300)}, want: 0, wantOK: true},
+		{name: "half", a: []appschema.Region{box(0, 0, 100, 100)}, b: []appschema.Region{box(0, 0, 50, 100)}, want: 0.5, wantOK: true},
+		{name: "triangle in its box", a: []appschema.Region{triangle}, b: []appschema.Region{box(0, 0, 100, 100)}, want: 0.5, wantOK: true},
+		{name: "one side empty", a: []appschema.Region{box(0, 0, 100, 100)}, want: 0, wantOK: true},
+		{name: "points cover no area", a: []appschema.Region{point}, b: []appschema.Region{point}},
+		{name: "nothing", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Overlap(tt.a, tt.b)
+			if ok != tt.wantOK || math.Abs(got-tt.want) > 0.03 {
+				t.Fatalf("Overlap = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	tests := []struct {
+		name      string
+		sizes     []image.Point
+		wantWidth int
+		// panel height plus the caption and gap
+		wantHeight int
+	}{
+		{name: "same size", sizes: []image.Point{{100, 50}, {100, 50}}, wantWidth: 8 + 100 + 8 + 100 + 8, wantHeight: 50 + 28},
+		{name: "scaled to the shorter", sizes: []image.Point{{200, 100}, {100, 50}}, wantWidth: 8 + 100 + 8 + 100 + 8, wantHeight: 50 + 28},
+		{name: "capped height", sizes: []image.Point{{2048, 2048}}, wantWidth: 8 + 1024 + 8, wantHeight: 1024 + 28},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var panels []Panel
+			for _, size := range tt.sizes {
+				panels = append(panels, Panel{Label: "scan", Image: image.NewRGBA(image.Rectangle{Max: size})})
+			}
+			out, err := SideBySide(panels...)
+			if err != nil {
+				t.Fatalf("SideBySide: %v", err)
+			}
+			img, err := png.Decode(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("output is not a PNG: %v", err)
+			}
+			if b := img.Bounds(); b.Dx() != tt.wantWidth || b.Dy() != tt.wantHeight {
+				t.Fatalf("image is %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
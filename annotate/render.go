@@ -0,0 +1,224 @@
+// Package annotate draws a scan's detected regions over the analysed image.
+package annotate
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"maps"
+	"math"
+	"slices"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// palette colours metrics in the order they appear in the result
+var palette = []color.NRGBA{
+	{0xe6, 0x19, 0x4b, 0xff},
+	{0x3c, 0xb4, 0x4b, 0xff},
+	{0xff, 0xe1, 0x19, 0xff},
+	{0x43, 0x63, 0xd8, 0xff},
+	{0xf5, 0x82, 0x31, 0xff},
+	{0x91, 0x1e, 0xb4, 0xff},
+	{0x42, 0xd4, 0xf4, 0xff},
+	{0xf0, 0x32, 0xe6, 0xff},
+	{0xbf, 0xef, 0x45, 0xff},
+	{0x46, 0x99, 0x90, 0xff},
+}
+
+// fillAlpha is the opacity of filled regions, so the skin stays visible
+const fillAlpha = 0x50
+
+// Metric is one legend entry and the regions drawn for it
+type Metric struct {
+	Name    string
+	Label   string
+	Color   color.NRGBA
+	Regions []appschema.Region
+}
+
+// Metrics lists the result's metrics, quantitative first and each by name,
+// with the colour each is drawn in
+func Metrics(result *appschema.FaceScanData) []Metric {
+	var metrics []Metric
+	add := func(name, label string, regions []appschema.Region) {
+		metrics = append(metrics, Metric{
+			Name:    name,
+			Label:   label,
+			Color:   palette[len(metrics)%len(palette)],
+			Regions: regions,
+		})
+	}
+	for _, name := range slices.Sorted(maps.Keys(result.Quantitative)) {
+		metric := result.Quantitative[name]
+		add(name, fmt.Sprintf("%s %.1f%%", name, metric.Percentage), metric.Regions)
+	}
+	for _, name := range slices.Sorted(maps.Keys(result.Qualitative)) {
+		metric := result.Qualitative[name]
+		state := "absent"
+		if metric.IsPresent {
+			state = "present"
+		}
+		add(name, name+" "+state, metric.Regions)
+	}
+	return metrics
+}
+
+// Render draws result's regions over the image in data as Draw does and
+// encodes it as a PNG
+func Render(data []byte, result *appschema.FaceScanData) ([]byte, error) {
+	canvas, err := Draw(data, result)
+	if err != nil {
+		return nil, err
+	}
+	return encode(canvas)
+}
+
+// Draw draws result's regions over the image in data, colour-coded by metric
+// with a legend in the top left corner. Coordinates are taken to be in data's
+// pixel space.
+func Draw(data []byte, result *appschema.FaceScanData) (*image.RGBA, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	bounds := src.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), src, bounds.Min, draw.Src)
+
+	metrics := Metrics(result)
+	// strokes and the legend grow with the image so they stay legible
+	stroke := max(2, float32(min(bounds.Dx(), bounds.Dy()))/250)
+	for _, metric := range metrics {
+		for _, region := range metric.Regions {
+			drawRegion(canvas, region, metric.Color, stroke)
+		}
+	}
+	if len(metrics) > 0 {
+		drawLegend(canvas, metrics, max(1, bounds.Dx()/500))
+	}
+	return canvas, nil
+}
+
+func encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func drawRegion(dst *image.RGBA, region appschema.Region, c color.NRGBA, stroke float32) {
+	points := make([]image.Point, len(region.Points))
+	for i, p := range region.Points {
+		points[i] = image.Pt(int(math.Round(p.X)), int(math.Round(p.Y)))
+	}
+	switch region.Type {
+	case appschema.RegionPoint:
+		fill(dst, circle(points[0], stroke*2), c)
+		return
+	case appschema.RegionBox:
+		lo, hi := points[0], points[1]
+		points = []image.Point{lo, {hi.X, lo.Y}, hi, {lo.X, hi.Y}}
+		fallthrough
+	case appschema.RegionPolygon:
+		translucent := c
+		translucent.A = fillAlpha
+		fill(dst, [][]image.Point{points}, translucent)
+		points = append(points, points[0])
+	}
+
+	var outline [][]image.Point
+	for i := 1; i < len(points); i++ {
+		outline = append(outline, segment(points[i-1], points[i], stroke))
+	}
+	fill(dst, outline, c)
+}
+
+// fill paints the union of polygons in c
+func fill(dst *image.RGBA, polygons [][]image.Point, c color.NRGBA) {
+	bounds := dst.Bounds()
+	r := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	r.DrawOp = draw.Over
+	for _, polygon := range polygons {
+		if len(polygon) < 3 {
+			continue
+		}
+		r.MoveTo(float32(polygon[0].X), float32(polygon[0].Y))
+		for _, p := range polygon[1:] {
+			r.LineTo(float32(p.X), float32(p.Y))
+		}
+		r.ClosePath()
+	}
+	r.Draw(dst, bounds, image.NewUniform(c), image.Point{})
+}
+
+// segment is the quad covering a line from a to b of the given width
+func segment(a, b image.Point, width float32) []image.Point {
+	dx, dy := float64(b.X-a.X), float64(b.Y-a.Y)
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return nil
+	}
+	nx := int(math.Round(-dy / length * float64(width) / 2))
+	ny := int(math.Round(dx / length * float64(width) / 2))
+	return []image.Point{
+		{a.X + nx, a.Y + ny}, {b.X + nx, b.Y + ny},
+		{b.X - nx, b.Y - ny}, {a.X - nx, a.Y - ny},
+	}
+}
+
+func circle(center image.Point, radius float32) [][]image.Point {
+	const sides = 16
+	polygon := make([]image.Point, sides)
+	for i := range polygon {
+		angle := 2 * math.Pi * float64(i) / sides
+		polygon[i] = image.Pt(
+			center.X+int(math.Round(float64(radius)*math.Cos(angle))),
+			center.Y+int(math.Round(float64(radius)*math.Sin(angle))),
+		)
+	}
+	return [][]image.Point{polygon}
+}
+
+// drawLegend renders one row per metric at 1x and scales it onto dst
+func drawLegend(dst *image.RGBA, metrics []Metric, scale int) {
+	const (
+		padding = 6
+		row     = 16
+		swatch  = 10
+	)
+	face := basicfont.Face7x13
+	width := 0
+	for _, metric := range metrics {
+		width = max(width, font.MeasureString(face, metric.Label).Ceil())
+	}
+
+	legend := image.NewRGBA(image.Rect(0, 0, padding*3+swatch+width, padding*2+row*len(metrics)))
+	draw.Draw(legend, legend.Bounds(), image.NewUniform(color.NRGBA{0, 0, 0, 0xb0}), image.Point{}, draw.Src)
+	for i, metric := range metrics {
+		top := padding + i*row
+		draw.Draw(legend, image.Rect(padding, top+3, padding+swatch, top+3+swatch), image.NewUniform(metric.Color), image.Point{}, draw.Src)
+		d := font.Drawer{
+			Dst:  legend,
+			Src:  image.White,
+			Face: face,
+			Dot:  fixed.P(padding*2+swatch, top+face.Ascent+1),
+		}
+		d.DrawString(metric.Label)
+	}
+
+	size := legend.Bounds().Size().Mul(scale)
+	target := image.Rectangle{Min: image.Pt(padding*scale, padding*scale)}
+	target.Max = target.Min.Add(size)
+	xdraw.NearestNeighbor.Scale(dst, target, legend, legend.Bounds(), draw.Over, nil)
+}
@@ -0,0 +1,131 @@
+package annotate
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// grey encodes a w×h mid-grey PNG
+func grey(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0x80, 0x80, 0x80, 0xff}), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMetrics(t *testing.T) {
+	result := &appschema.FaceScanData{
+		Quantitative: map[string]appschema.QuantitativeMetric{
+			"wrinkles": {Percentage: 12.34},
+			"acne":     {Percentage: 5},
+		},
+		Qualitative: map[string]appschema.QualitativeMetric{
+			"redness":    {IsPresent: true},
+			"dark_spots": {IsPresent: false},
+		},
+	}
+	want := []struct{ name, label string }{
+		{"acne", "acne 5.0%"},
+		{"wrinkles", "wrinkles 12.3%"},
+		{"dark_spots", "dark_spots absent"},
+		{"redness", "redness present"},
+	}
+
+	metrics := Metrics(result)
+	if len(metrics) != len(want) {
+		t.Fatalf("%d metrics, want %d", len(metrics), len(want))
+	}
+	for i, w := range want {
+		if metrics[i].Name != w.name || metrics[i].Label != w.label || metrics[i].Color != palette[i] {
+			t.Errorf("metric %d = %s %q %v, want %s %q %v", i, metrics[i].Name, metrics[i].Label, metrics[i].Color, w.name, w.label, palette[i])
+		}
+	}
+}
+
+func TestDraw(t *testing.T) {
+	data := grey(t, 400, 300)
+	box := appschema.Region{Type: appschema.RegionBox, Points: []appschema.Point{{X: 200, Y: 150}, {X: 300, Y: 250}}}
+	tests := []struct {
+		name string
+		data []byte
+		// regions are drawn for the wrinkles metric
+		regions []appschema.Region
+		// changed and unchanged are pixels expected to differ from the
+		// source and to match it
+		changed, unchanged []image.Point
+		wantErr            bool
+	}{
+		{name: "not an image", data: []byte("nope"), wantErr: true},
+		{
+			name:      "box",
+			data:      data,
+			regions:   []appschema.Region{box},
+			changed:   []image.Point{{250, 200}, {200, 200}},
+			unchanged: []image.Point{{350, 280}, {150, 280}},
+		},
+		{
+			name:      "point",
+			data:      data,
+			regions:   []appschema.Region{{Type: appschema.RegionPoint, Points: []appschema.Point{{X: 250, Y: 200}}}},
+			changed:   []image.Point{{250, 200}},
+			unchanged: []image.Point{{280, 200}},
+		},
+		{
+			name:      "no regions draws only the legend",
+			data:      data,
+			unchanged: []image.Point{{250, 200}, {399, 299}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &appschema.FaceScanData{Quantitative: map[string]appschema.QuantitativeMetric{
+				"wrinkles": {Percentage: 10, Regions: tt.regions},
+			}}
+			canvas, err := Draw(tt.data, result)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Draw = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if canvas.Bounds().Dx() != 400 || canvas.Bounds().Dy() != 300 {
+				t.Fatalf("canvas is %v, want the source's 400x300", canvas.Bounds())
+			}
+			source := color.RGBA{0x80, 0x80, 0x80, 0xff}
+			for _, p := range tt.changed {
+				if canvas.RGBAAt(p.X, p.Y) == source {
+					t.Errorf("pixel %v was not drawn over", p)
+				}
+			}
+			for _, p := range tt.unchanged {
+				if got := canvas.RGBAAt(p.X, p.Y); got != source {
+					t.Errorf("pixel %v = %v, want the source's %v", p, got, source)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderEncodesPNG(t *testing.T) {
+	out, err := Render(grey(t, 64, 48), &appschema.FaceScanData{})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("Render output is not a PNG: %v", err)
+	}
+	if img.Bounds().Dx() != 64 || img.Bounds().Dy() != 48 {
+		t.Fatalf("rendered %v, want 64x48", img.Bounds())
+	}
+}
@@ -0,0 +1,135 @@
+// Package cache keeps analysis results by image content so re-uploads of the
+// same photo skip the analyzer.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+var ErrMiss = errors.New("cache miss")
+
+// Backend persists results beyond the in-memory LRU, e.g. across restarts
+type Backend interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, expiresAt time.Time) error
+	// Purge removes entries that expired before now
+	Purge(ctx context.Context, now time.Time) (int, error)
+}
+
+// Cache is an LRU of results in front of an optional Backend
+type Cache struct {
+	lru     *lru
+	backend Backend
+	ttl     time.Duration
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type Stats struct {
+	Entries int   `json:"entries"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+}
+
+// New keeps up to size results in memory, each for ttl. backend may be nil.
+func New(size int, ttl time.Duration, backend Backend) *Cache {
+	return &Cache{lru: newLRU(size), backend: backend, ttl: ttl}
+}
+
+// Key addresses a result by who uploaded the image, what analysed it and the
+// image's bytes. The owner is part of the key so one user's upload never
+// answers another's.
+func Key(ownerID, analysis, version string, data []byte) string {
+	content := sha256.Sum256(data)
+	key := sha256.New()
+	for _, part := range []string{ownerID, analysis, version, hex.EncodeToString(content[:])} {
+		key.Write([]byte(part))
+		key.Write([]byte{0})
+	}
+	return hex.EncodeToString(key.Sum(nil))
+}
+
+// Get returns the cached result for key, promoting backend hits into memory
+func (c *Cache) Get(ctx context.Context, key string) (*appschema.FaceScanData, bool) {
+	now := time.Now()
+	value, ok := c.lru.Get(key, now)
+	if !ok && c.backend != nil {
+		var err error
+		value, err = c.backend.Get(ctx, key)
+		if err != nil && !errors.Is(err, ErrMiss) {
+			log.Printf("[cache] Backend get failed: %v", err)
+		}
+		if ok = err == nil; ok {
+			c.lru.Set(key, value, now.Add(c.ttl))
+		}
+	}
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	// entries are stored encoded so callers never share mutable state with the cache
+	var result appschema.FaceScanData
+	if err := json.Unmarshal(value, &result); err != nil {
+		log.Printf("[cache] Dropping undecodable entry: %v", err)
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	return &result, true
+}
+
+// Set caches result under key; failures only cost a future analysis
+func (c *Cache) Set(ctx context.Context, key string, result *appschema.FaceScanData) {
+	value, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("[cache] Encode failed: %v", err)
+		return
+	}
+
+	expiresAt := time.Now().Add(c.ttl)
+	c.lru.Set(key, value, expiresAt)
+	if c.backend != nil {
+		if err := c.backend.Set(ctx, key, value, expiresAt); err != nil {
+			log.Printf("[cache] Backend set failed: %v", err)
+		}
+	}
+}
+
+func (c *Cache) Stats() Stats {
+	return Stats{Entries: c.lru.Len(), Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// StartJanitor purges expired backend entries every interval until ctx ends;
+// the LRU drops its own on access
+func (c *Cache) StartJanitor(ctx context.Context, interval time.Duration) {
+	if c.backend == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			removed, err := c.backend.Purge(ctx, now)
+			if err != nil {
+				log.Printf("[cache] Purge failed: %v", err)
+			} else if removed > 0 {
+				log.Printf("[cache] Purged %d expired results", removed)
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+func TestKey(t *testing.T) {
+	base := Key("u1", "face_scan", "v1", []byte("image"))
+	tests := []struct {
+		name string
+		key  string
+		same bool
+	}{
+		{name: "same inputs", key: Key("u1", "face_scan", "v1", []byte("image")), same: true},
+		{name: "another owner", key: Key("u2", "face_scan", "v1", []byte("image"))},
+		{name: "another analysis", key: Key("u1", "skin_tone", "v1", []byte("image"))},
+		{name: "another version", key: Key("u1", "face_scan", "v2", []byte("image"))},
+		{name: "other bytes", key: Key("u1", "face_scan", "v1", []byte("image2"))},
+		// the separator keeps parts from running into each other
+		{name: "shifted parts", key: Key("u1f", "ace_scan", "v1", []byte("image"))},
+	}
+	for _, tt := range tests {
+		if (tt.key == base) != tt.same {
+			t.Errorf("%s: key equal %v, want %v", tt.name, tt.key == base, tt.same)
+		}
+	}
+}
+
+func TestLRU(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	tests := []struct {
+		name string
+		run  func(l *lru)
+		want map[string]bool
+	}{
+		{
+			name: "evicts the oldest",
+			run: func(l *lru) {
+				l.Set("a", nil, later)
+				l.Set("b", nil, later)
+				l.Set("c", nil, later)
+			},
+			want: map[string]bool{"a": false, "b": true, "c": true},
+		},
+		{
+			name: "get promotes",
+			run: func(l *lru) {
+				l.Set("a", nil, later)
+				l.Set("b", nil, later)
+				l.Get("a", now)
+				l.Set("c", nil, later)
+			},
+			want: map[string]bool{"a": true, "b": false, "c": true},
+		},
+		{
+			name: "set replaces in place",
+			run: func(l *lru) {
+				l.Set("a", nil, later)
+				l.Set("b", nil, later)
+				l.Set("a", []byte("2"), later)
+				l.Set("c", nil, later)
+			},
+			want: map[string]bool{"a": true, "b": false, "c": true},
+		},
+		{
+			name: "expired entries miss",
+			run: func(l *lru) {
+				l.Set("a", nil, now.Add(-time.Second))
+				l.Set("b", nil, later)
+			},
+			want: map[string]bool{"a": false, "b": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLRU(2)
+			tt.run(l)
+			for key, want := range tt.want {
+				if _, ok := l.Get(key, now); ok != want {
+					t.Errorf("Get(%q) = %v, want %v", key, ok, want)
+				}
+			}
+			if l.Len() > 2 {
+				t.Errorf("Len = %d, over the size", l.Len())
+			}
+		})
+	}
+}
+
+func TestCacheGetSet(t *testing.T) {
+	backend, err := NewSQLiteBackend(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	tests := []struct {
+		name    string
+		backend Backend
+	}{
+		{name: "memory only"},
+		{name: "sqlite backend", backend: backend},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := New(1, time.Hour, tt.backend)
+			if _, ok := c.Get(ctx, "k1"); ok {
+				t.Fatal("empty cache hit")
+			}
+			c.Set(ctx, "k1", &appschema.FaceScanData{SchemaVersion: 1, AnnotatedURL: "/a"})
+
+			got, ok := c.Get(ctx, "k1")
+			if !ok || got.SchemaVersion != 1 || got.AnnotatedURL != "/a" {
+				t.Fatalf("Get = %+v, %v", got, ok)
+			}
+			// callers get their own copy
+			got.AnnotatedURL = "/changed"
+			if again, _ := c.Get(ctx, "k1"); again.AnnotatedURL != "/a" {
+				t.Fatalf("cached entry changed to %q through a returned result", again.AnnotatedURL)
+			}
+
+			// a second key evicts the first from memory; the backend still has it
+			c.Set(ctx, "k2", &appschema.FaceScanData{SchemaVersion: 2})
+			_, ok = c.Get(ctx, "k1")
+			if ok != (tt.backend != nil) {
+				t.Fatalf("Get(k1) after eviction = %v, want %v", ok, tt.backend != nil)
+			}
+			if stats := c.Stats(); stats.Entries != 1 || stats.Hits < 2 || stats.Misses < 1 {
+				t.Fatalf("Stats = %+v", stats)
+			}
+		})
+	}
+}
+
+func TestSQLiteBackendPurge(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewSQLiteBackend(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	now := time.Now()
+	backend.Set(ctx, "expired", []byte("{}"), now.Add(-time.Minute))
+	backend.Set(ctx, "live", []byte("{}"), now.Add(time.Hour))
+
+	if _, err := backend.Get(ctx, "expired"); err != ErrMiss {
+		t.Fatalf("Get(expired) = %v, want ErrMiss", err)
+	}
+	removed, err := backend.Purge(ctx, now)
+	if err != nil || removed != 1 {
+		t.Fatalf("Purge = %d, %v; want 1", removed, err)
+	}
+	if _, err := backend.Get(ctx, "live"); err != nil {
+		t.Fatalf("Get(live) = %v", err)
+	}
+}
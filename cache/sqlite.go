@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS results (
+	key        TEXT PRIMARY KEY,
+	value      BLOB NOT NULL,
+	expires_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS results_expires ON results (expires_at);
+`
+
+// SQLiteBackend persists cached results in a SQLite database file
+type SQLiteBackend struct {
+	db *sql.DB
+}
+
+func NewSQLiteBackend(path string) (*SQLiteBackend, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteBackend{db: db}, nil
+}
+
+func (b *SQLiteBackend) Close() error {
+	return b.db.Close()
+}
+
+func (b *SQLiteBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := b.db.QueryRowContext(ctx, `SELECT value FROM results WHERE key = ? AND expires_at >= ?`, key, time.Now().UnixMilli()).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMiss
+	}
+	return value, err
+}
+
+func (b *SQLiteBackend) Set(ctx context.Context, key string, value []byte, expiresAt time.Time) error {
+	_, err := b.db.ExecContext(ctx, `
+		INSERT INTO results (key, value, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at`,
+		key, value, expiresAt.UnixMilli(),
+	)
+	return err
+}
+
+func (b *SQLiteBackend) Purge(ctx context.Context, now time.Time) (int, error) {
+	res, err := b.db.ExecContext(ctx, `DELETE FROM results WHERE expires_at < ?`, now.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	removed, err := res.RowsAffected()
+	return int(removed), err
+}
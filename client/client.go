@@ -0,0 +1,349 @@
+// Package client is a Go SDK for the face log upload and stream endpoints.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// ProgressFunc receives every event delivered on a stream
+type ProgressFunc func(event *appschema.EventMessage)
+
+type Client struct {
+	// BaseURL includes the API version prefix, e.g. http://localhost:8080/api/v1
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+	// AdminKey is sent as X-Admin-Key on admin routes
+	AdminKey string
+
+	// OnProgress is called for each event while AnalyzeFace waits for a result
+	OnProgress ProgressFunc
+
+	// reconnect backoff for Stream
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxReconnects caps consecutive failed reconnects; 0 retries until ctx ends
+	MaxReconnects int
+}
+
+// Image is an upload payload. The filename extension is validated by the server.
+type Image struct {
+	Filename string
+	Data     io.Reader
+}
+
+// UploadResult is the JSON body returned by the upload endpoint
+type UploadResult struct {
+	Message  string `json:"message"`
+	JobID    string `json:"job_id"`
+	StreamID string `json:"stream"`
+	Images   int    `json:"images"`
+}
+
+// APIError is returned when the server answers with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error %d: %s", e.StatusCode, e.Message)
+}
+
+// EventError wraps a terminal error event received on a stream
+type EventError struct {
+	Event *appschema.EventMessage
+}
+
+func (e *EventError) Error() string {
+	return fmt.Sprintf("scan failed (%d): %s", e.Event.Code, e.Event.Message)
+}
+
+var ErrStreamEnded = errors.New("stream ended before a terminal event")
+
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{},
+		MinBackoff: 500 * time.Millisecond,
+		MaxBackoff: 30 * time.Second,
+	}
+}
+
+// ImageFromFile opens path as an upload payload. The caller closes the file.
+func ImageFromFile(path string) (*Image, *os.File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Image{Filename: filepath.Base(path), Data: file}, file, nil
+}
+
+// Upload posts one or more images to the upload endpoint, publishing progress on
+// streamID. Several images are scanned as a batch that ends with batch_done.
+func (c *Client) Upload(ctx context.Context, streamID string, images ...*Image) (*UploadResult, error) {
+	if len(images) == 0 {
+		return nil, errors.New("no images to upload")
+	}
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, image := range images {
+		part, err := writer.CreateFormFile("image", image.Filename)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(part, image.Data); err != nil {
+			return nil, fmt.Errorf("failed to read image %s: %w", image.Filename, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/facelog/upload?stream=%s", c.BaseURL, url.QueryEscape(streamID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	c.authorize(req)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var result UploadResult
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("invalid upload response: %w", err)
+		}
+	}
+	if result.StreamID == "" {
+		result.StreamID = streamID
+	}
+	return &result, nil
+}
+
+// AnalyzeFace uploads image on a fresh stream and blocks until the scan finishes,
+// reporting intermediate events to OnProgress.
+func (c *Client) AnalyzeFace(ctx context.Context, image *Image) (*appschema.FaceScanData, error) {
+	event, err := c.analyze(ctx, uuid.NewString(), []*Image{image})
+	if err != nil {
+		return nil, err
+	}
+	result, _ := event.Data.(*appschema.FaceScanData)
+	if result == nil {
+		return nil, ErrStreamEnded
+	}
+	return result, nil
+}
+
+// AnalyzeFaces uploads images as one batch and blocks until every image has been
+// scanned. Images that failed are reported in the summary rather than as an error.
+func (c *Client) AnalyzeFaces(ctx context.Context, images ...*Image) (*appschema.BatchSummary, error) {
+	return c.AnalyzeFacesOn(ctx, uuid.NewString(), images...)
+}
+
+// AnalyzeFacesOn is AnalyzeFaces publishing progress on streamID, so others can
+// follow the scan too
+func (c *Client) AnalyzeFacesOn(ctx context.Context, streamID string, images ...*Image) (*appschema.BatchSummary, error) {
+	if len(images) == 0 {
+		return nil, errors.New("no images to upload")
+	}
+	event, err := c.analyze(ctx, streamID, images)
+	if err != nil {
+		return nil, err
+	}
+	// a single image finishes with done rather than batch_done
+	if data, ok := event.Data.(*appschema.FaceScanData); ok {
+		return &appschema.BatchSummary{
+			Total:     1,
+			Succeeded: 1,
+			Images: []appschema.BatchImageResult{{
+				Filename: images[0].Filename,
+				Status:   appschema.JobSucceeded,
+				Result:   data,
+			}},
+		}, nil
+	}
+	summary, _ := event.Data.(*appschema.BatchSummary)
+	if summary == nil {
+		return nil, ErrStreamEnded
+	}
+	return summary, nil
+}
+
+// analyze uploads images on streamID and returns its successful terminal event
+func (c *Client) analyze(ctx context.Context, streamID string, images []*Image) (*appschema.EventMessage, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ready := make(chan struct{})
+	var (
+		result    *appschema.EventMessage
+		streamErr = make(chan error, 1)
+	)
+
+	go func() {
+		streamErr <- c.Stream(ctx, streamID, func(event *appschema.EventMessage) error {
+			switch event.Event {
+			case faceanalyze_events.EventReady:
+				select {
+				case <-ready:
+				default:
+					close(ready)
+				}
+			case faceanalyze_events.EventCompleted, faceanalyze_events.EventBatchCompleted:
+				result = event
+			}
+			if c.OnProgress != nil {
+				c.OnProgress(event)
+			}
+			return nil
+		})
+	}()
+
+	// the hub drops events for streams nobody listens to, so subscribe first
+	select {
+	case <-ready:
+	case err := <-streamErr:
+		if err == nil {
+			err = ErrStreamEnded
+		}
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	uploadErr := make(chan error, 1)
+	go func() {
+		_, err := c.Upload(ctx, streamID, images...)
+		uploadErr <- err
+	}()
+
+	for {
+		select {
+		case err := <-uploadErr:
+			if err != nil {
+				return nil, err
+			}
+			uploadErr = nil
+		case err := <-streamErr:
+			if err != nil {
+				return nil, err
+			}
+			if result == nil {
+				return nil, ErrStreamEnded
+			}
+			return result, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// CancelJob stops a queued or running job. Its stream then ends with a
+// cancelled event, which Stream returns as *EventError.
+func (c *Client) CancelJob(ctx context.Context, jobID string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.BaseURL+"/jobs/"+url.PathEscape(jobID), nil)
+	if err != nil {
+		return err
+	}
+	c.authorize(req)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp)
+}
+
+// StreamInfo describes an active stream as reported by the admin API
+type StreamInfo struct {
+	ID          string `json:"id"`
+	Subscribers int    `json:"subscribers"`
+	LastEventID uint64 `json:"last_event_id"`
+}
+
+// ListStreams returns the active streams. It requires AdminKey.
+func (c *Client) ListStreams(ctx context.Context) ([]StreamInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/admin/streams", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Admin-Key", c.AdminKey)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Streams []StreamInfo `json:"streams"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid streams response: %w", err)
+	}
+	return body.Streams, nil
+}
+
+func (c *Client) authorize(req *http.Request) {
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+}
+
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+
+	var body struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil {
+		if body.Message != "" {
+			apiErr.Message = body.Message
+		} else if body.Error != "" {
+			apiErr.Message = body.Error
+		}
+	}
+	return apiErr
+}
@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// EventHandler receives each parsed event. Returning an error stops the stream.
+type EventHandler func(event *appschema.EventMessage) error
+
+// Stream follows streamID until a terminal done, batch_done, error or cancelled event, reconnecting with
+// Last-Event-ID and exponential backoff when the connection drops. Backoff
+// starts from the server's retry delay once it has sent one, and never from
+// less than MinBackoff or, when that is unset, defaultMinBackoff. A terminal
+// error or cancelled event is returned as *EventError after it is handed to handler.
+func (c *Client) Stream(ctx context.Context, streamID string, handler EventHandler) error {
+	floor := c.MinBackoff
+	if floor <= 0 {
+		floor = defaultMinBackoff
+	}
+	var (
+		lastEventID string
+		failures    int
+		// the server's retry delay, kept across reconnects
+		retry   time.Duration
+		backoff = floor
+	)
+
+	for {
+		connected, terminal, err := c.streamOnce(ctx, streamID, &lastEventID, &retry, handler)
+		if terminal != nil {
+			if terminal.Event == faceanalyze_events.EventError || terminal.Event == faceanalyze_events.EventCancelled {
+				return &EventError{Event: terminal}
+			}
+			return nil
+		}
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 && apiErr.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+		var handlerErr *handlerError
+		if errors.As(err, &handlerErr) {
+			return handlerErr.err
+		}
+
+		if connected {
+			failures = 0
+			backoff = max(retry, floor)
+		}
+		failures++
+		if c.MaxReconnects > 0 && failures > c.MaxReconnects {
+			if err == nil {
+				err = ErrStreamEnded
+			}
+			return fmt.Errorf("giving up after %d reconnects: %w", c.MaxReconnects, err)
+		}
+
+		log.Printf("[client] Stream %s: reconnecting in %s (%v)", streamID, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+		if c.MaxBackoff > 0 && backoff > c.MaxBackoff {
+			backoff = max(c.MaxBackoff, retry, floor)
+		}
+	}
+}
+
+// defaultMinBackoff keeps a client without MinBackoff from reconnecting in a
+// tight loop
+const defaultMinBackoff = 500 * time.Millisecond
+
+type handlerError struct {
+	err error
+}
+
+func (e *handlerError) Error() string { return e.err.Error() }
+
+// streamOnce runs a single connection. connected reports whether the server
+// accepted it; terminal is set once a terminal event has been handled.
+func (c *Client) streamOnce(ctx context.Context, streamID string, lastEventID *string, retry *time.Duration, handler EventHandler) (connected bool, terminal *appschema.EventMessage, err error) {
+	endpoint := fmt.Sprintf("%s/facelog?stream=%s", c.BaseURL, url.QueryEscape(streamID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if *lastEventID != "" {
+		req.Header.Set("Last-Event-ID", *lastEventID)
+	}
+	c.authorize(req)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return false, nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return false, nil, err
+	}
+
+	reader := stream.NewReader(resp.Body, stream.FormatSSE)
+	defer func() {
+		if reader.Retry() > 0 {
+			*retry = reader.Retry()
+		}
+	}()
+	for {
+		f, err := reader.Next()
+		if err == io.EOF {
+			return true, nil, nil
+		}
+		if err != nil {
+			return true, nil, err
+		}
+
+		if f.ID != "" {
+			*lastEventID = f.ID
+		}
+
+		event, err := decodeEvent(f)
+		if err != nil {
+			log.Printf("[client] Stream %s: skipping undecodable event %q: %v", streamID, f.Event, err)
+			continue
+		}
+		if err := handler(event); err != nil {
+			return true, nil, &handlerError{err: err}
+		}
+		if isTerminal(event.Event) {
+			return true, event, nil
+		}
+	}
+}
+
+// decodeEvent turns a frame into an EventMessage, typing the payload of result
+// carrying events
+func decodeEvent(f *stream.WireEvent) (*appschema.EventMessage, error) {
+	var raw struct {
+		appschema.EventMessage
+		Data json.RawMessage `json:"data,omitempty"`
+	}
+	if err := json.Unmarshal(f.Data, &raw); err != nil {
+		return nil, err
+	}
+
+	event := raw.EventMessage
+	if event.Event == "" {
+		event.Event = f.Event
+	}
+	if len(raw.Data) > 0 {
+		switch event.Event {
+		case faceanalyze_events.EventCompleted, faceanalyze_events.EventImageCompleted, faceanalyze_events.EventPartialResult:
+			var data appschema.FaceScanData
+			if err := json.Unmarshal(raw.Data, &data); err != nil {
+				return nil, err
+			}
+			event.Data = &data
+		case faceanalyze_events.EventBatchCompleted:
+			var data appschema.BatchSummary
+			if err := json.Unmarshal(raw.Data, &data); err != nil {
+				return nil, err
+			}
+			event.Data = &data
+		default:
+			var data any
+			if err := json.Unmarshal(raw.Data, &data); err != nil {
+				return nil, err
+			}
+			event.Data = data
+		}
+	}
+	return &event, nil
+}
+
+func isTerminal(event string) bool {
+	return event == faceanalyze_events.EventCompleted || event == faceanalyze_events.EventError ||
+		event == faceanalyze_events.EventBatchCompleted || event == faceanalyze_events.EventCancelled
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// connection is what the test server saw of one stream request
+type connection struct {
+	at          time.Time
+	lastEventID string
+}
+
+// streamServer answers each connection to /facelog with the next of bodies,
+// repeating the last, and records the connections
+func streamServer(t *testing.T, bodies ...string) (*httptest.Server, func() []connection) {
+	var (
+		mu          sync.Mutex
+		connections []connection
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		n := len(connections)
+		connections = append(connections, connection{at: time.Now(), lastEventID: r.Header.Get("Last-Event-ID")})
+		mu.Unlock()
+
+		if r.URL.Path != "/facelog" || r.URL.Query().Get("stream") != "s1" {
+			t.Errorf("request to %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, bodies[min(n, len(bodies)-1)])
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []connection {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]connection(nil), connections...)
+	}
+}
+
+func TestStreamResumesWithLastEventID(t *testing.T) {
+	srv, connections := streamServer(t,
+		"retry: 1\n\nid: 1\nevent: stage\ndata: {\"event\":\"stage\",\"message\":\"one\"}\n\n"+
+			"id: 2\nevent: stage\ndata: {\"event\":\"stage\",\n"+"data: \"message\":\"two\"}\n\n",
+		": reconnected\n\nid: 3\nevent: done\ndata: {\"event\":\"done\",\"stream_completion\":100}\n\n",
+	)
+	c := New(srv.URL, "tok")
+	c.MinBackoff = time.Millisecond
+
+	var messages []string
+	err := c.Stream(context.Background(), "s1", func(event *appschema.EventMessage) error {
+		messages = append(messages, event.Event+":"+event.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if fmt.Sprint(messages) != "[stage:one stage:two done:]" {
+		t.Fatalf("events = %v", messages)
+	}
+	got := connections()
+	if len(got) != 2 || got[0].lastEventID != "" || got[1].lastEventID != "2" {
+		t.Fatalf("connections = %+v, want a second one resuming after 2", got)
+	}
+}
+
+func TestStreamKeepsServerRetry(t *testing.T) {
+	const retry = 80 * time.Millisecond
+	srv, connections := streamServer(t,
+		"retry: 80\n\nid: 1\ndata: {\"event\":\"stage\"}\n\n",
+		"id: 2\ndata: {\"event\":\"stage\"}\n\n",
+		"id: 3\ndata: {\"event\":\"done\"}\n\n",
+	)
+	c := New(srv.URL, "tok")
+	c.MinBackoff = time.Millisecond
+
+	if err := c.Stream(context.Background(), "s1", func(*appschema.EventMessage) error { return nil }); err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	got := connections()
+	if len(got) != 3 {
+		t.Fatalf("%d connections, want 3", len(got))
+	}
+	// the second connection sends no retry of its own
+	for i := 1; i < len(got); i++ {
+		if gap := got[i].at.Sub(got[i-1].at); gap < retry {
+			t.Errorf("reconnect %d after %s, want at least the server's %s", i, gap, retry)
+		}
+	}
+}
+
+func TestStreamZeroMinBackoff(t *testing.T) {
+	srv, connections := streamServer(t,
+		"id: 1\ndata: {\"event\":\"stage\"}\n\n",
+		"id: 2\ndata: {\"event\":\"done\"}\n\n",
+	)
+	c := New(srv.URL, "tok")
+	c.MinBackoff = 0
+
+	if err := c.Stream(context.Background(), "s1", func(*appschema.EventMessage) error { return nil }); err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	got := connections()
+	if len(got) != 2 {
+		t.Fatalf("%d connections, want 2", len(got))
+	}
+	if gap := got[1].at.Sub(got[0].at); gap < defaultMinBackoff {
+		t.Fatalf("reconnected after %s, want at least %s", gap, defaultMinBackoff)
+	}
+}
+
+func TestStreamTerminalEvents(t *testing.T) {
+	tests := []struct {
+		event   string
+		wantErr bool
+	}{
+		{event: "done"},
+		{event: "batch_done"},
+		{event: "error", wantErr: true},
+		{event: "cancelled", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			srv, _ := streamServer(t, fmt.Sprintf("id: 1\nevent: %s\ndata: {\"code\":400,\"message\":\"m\"}\n\n", tt.event))
+			c := New(srv.URL, "tok")
+
+			var seen string
+			err := c.Stream(context.Background(), "s1", func(event *appschema.EventMessage) error {
+				seen = event.Event
+				return nil
+			})
+			if seen != tt.event {
+				t.Errorf("handler saw %q, want %q", seen, tt.event)
+			}
+			var eventErr *EventError
+			if tt.wantErr != errors.As(err, &eventErr) || (!tt.wantErr && err != nil) {
+				t.Fatalf("Stream = %v, want EventError %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"github.com/muthu-kumar-u/go-sse/client"
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+func runUpload(ctx context.Context, opts *options, args []string) error {
+	var streamID string
+	fs := parseCommand("upload", opts, args, func(fs *flag.FlagSet) {
+		fs.StringVar(&streamID, "stream", "", "stream ID to publish progress on (random when empty)")
+	})
+	if fs.NArg() == 0 {
+		return errors.New("upload needs at least one image path")
+	}
+	if opts.token == "" {
+		return errors.New("a token is required (-token or FACELOG_TOKEN)")
+	}
+	if streamID == "" {
+		streamID = uuid.NewString()
+	}
+
+	images := make([]*client.Image, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		image, file, err := client.ImageFromFile(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		images = append(images, image)
+	}
+
+	c := newClient(opts)
+	out := newRenderer(opts.json)
+	var result *appschema.EventMessage
+	c.OnProgress = func(event *appschema.EventMessage) {
+		if isResult(event) {
+			result = event
+		}
+		out.Event(streamID, event)
+	}
+
+	_, err := c.AnalyzeFacesOn(ctx, streamID, images...)
+	out.Finish()
+	if err != nil {
+		return err
+	}
+	out.Result(result)
+	return nil
+}
+
+func runTail(ctx context.Context, opts *options, args []string) error {
+	fs := parseCommand("tail", opts, args, nil)
+	if fs.NArg() != 1 {
+		return errors.New("tail needs exactly one stream ID")
+	}
+
+	streamID := fs.Arg(0)
+	out := newRenderer(opts.json)
+	var result *appschema.EventMessage
+
+	err := newClient(opts).Stream(ctx, streamID, func(event *appschema.EventMessage) error {
+		if isResult(event) {
+			result = event
+		}
+		out.Event(streamID, event)
+		return nil
+	})
+	out.Finish()
+	if err != nil {
+		return err
+	}
+	out.Result(result)
+	return nil
+}
+
+// isResult reports whether event carries the scan result, a single image
+// finishing with done and a batch with batch_done
+func isResult(event *appschema.EventMessage) bool {
+	return event.Event == faceanalyze_events.EventCompleted || event.Event == faceanalyze_events.EventBatchCompleted
+}
+
+func runCancel(ctx context.Context, opts *options, args []string) error {
+	fs := parseCommand("cancel", opts, args, nil)
+	if fs.NArg() != 1 {
+		return errors.New("cancel needs exactly one job ID")
+	}
+	if opts.token == "" {
+		return errors.New("a token is required (-token or FACELOG_TOKEN)")
+	}
+
+	if err := newClient(opts).CancelJob(ctx, fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Printf("job %s cancelled\n", fs.Arg(0))
+	return nil
+}
+
+func runStreams(ctx context.Context, opts *options, args []string) error {
+	parseCommand("streams", opts, args, nil)
+	if opts.adminKey == "" {
+		return errors.New("an admin key is required (-admin-key or FACELOG_ADMIN_KEY)")
+	}
+
+	streams, err := newClient(opts).ListStreams(ctx)
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return printJSON(streams)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STREAM\tSUBSCRIBERS\tLAST EVENT")
+	for _, s := range streams {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", s.ID, s.Subscribers, s.LastEventID)
+	}
+	return w.Flush()
+}
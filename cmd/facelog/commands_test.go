@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCommand(t *testing.T) {
+	opts := &options{url: "http://api/v1", token: "t1"}
+	var stream string
+	fs := parseCommand("upload", opts, []string{"-json", "-stream", "s1", "-token", "t2", "a.jpg", "b.jpg"}, func(fs *flag.FlagSet) {
+		fs.StringVar(&stream, "stream", "", "")
+	})
+	if !opts.json || opts.token != "t2" || opts.url != "http://api/v1" || stream != "s1" {
+		t.Fatalf("options = %+v, stream %q", opts, stream)
+	}
+	if strings.Join(fs.Args(), " ") != "a.jpg b.jpg" {
+		t.Fatalf("args = %v", fs.Args())
+	}
+}
+
+func TestCommandArguments(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(ctx context.Context, opts *options, args []string) error
+		opts    options
+		args    []string
+		wantErr string
+	}{
+		{name: "upload without images", run: runUpload, opts: options{token: "t"}, wantErr: "at least one image path"},
+		{name: "tail without a stream", run: runTail, wantErr: "exactly one stream ID"},
+		{name: "tail with two streams", run: runTail, args: []string{"s1", "s2"}, wantErr: "exactly one stream ID"},
+		{name: "cancel without a job", run: runCancel, opts: options{token: "t"}, wantErr: "exactly one job ID"},
+		{name: "cancel without a token", run: runCancel, args: []string{"j1"}, wantErr: "token is required"},
+		{name: "streams without an admin key", run: runStreams, wantErr: "admin key is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			err := tt.run(context.Background(), &opts, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// apiServer streams body on /facelog once an upload has arrived, then keeps
+// the connection open so only a terminal event can end the stream
+func apiServer(t *testing.T, body string) *httptest.Server {
+	uploaded := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("stream") != "s1" {
+			t.Errorf("request to %s", r.URL)
+		}
+		switch r.URL.Path {
+		case "/facelog/upload":
+			close(uploaded)
+			w.WriteHeader(http.StatusAccepted)
+		case "/facelog":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "id: 1\nevent: ready\ndata: {\"event\":\"ready\"}\n\n")
+			w.(http.Flusher).Flush()
+			select {
+			case <-uploaded:
+			case <-r.Context().Done():
+				return
+			}
+			fmt.Fprint(w, body)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRunUpload(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "a.jpg")
+	os.WriteFile(image, []byte("jpeg"), 0o644)
+	srv := apiServer(t, "id: 2\nevent: queued\ndata: {\"event\":\"queued\"}\n\n"+
+		"id: 3\nevent: done\ndata: {\"event\":\"done\",\"data\":{\"quantitative\":{\"acne\":{\"percentage\":3}}}}\n\n")
+
+	var err error
+	out := captureStdout(t, func() {
+		opts := &options{url: srv.URL, token: "t"}
+		err = runUpload(context.Background(), opts, []string{"-stream", "s1", image})
+	})
+	if err != nil {
+		t.Fatalf("runUpload: %v", err)
+	}
+	for _, want := range []string{"stream s1 ready", "queued", "done", "acne"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("printed %q, want it to contain %q", out, want)
+		}
+	}
+}
+
+func TestRunTailStopsOnTerminalEvents(t *testing.T) {
+	tests := []struct {
+		event   string
+		want    string
+		wantErr bool
+	}{
+		{event: "done", want: "acne"},
+		{event: "batch_done", want: "0 of 1 images scanned"},
+		{event: "cancelled", wantErr: true},
+		{event: "error", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			data := `{"quantitative":{"acne":{"percentage":3}}}`
+			if tt.event == "batch_done" {
+				data = `{"total":1,"images":[{"index":0,"filename":"a.jpg","status":"failed"}]}`
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprintf(w, "id: 1\nevent: %s\ndata: {\"event\":%q,\"data\":%s}\n\n", tt.event, tt.event, data)
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			}))
+			defer srv.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			var err error
+			out := captureStdout(t, func() {
+				err = runTail(ctx, &options{url: srv.URL}, []string{"s1"})
+			})
+			if ctx.Err() != nil {
+				t.Fatal("tail did not stop on the terminal event")
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runTail = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("printed %q, want it to contain %q", out, tt.want)
+			}
+		})
+	}
+}
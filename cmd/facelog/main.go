@@ -0,0 +1,124 @@
+// Command facelog uploads images to the face log API and follows scan streams.
+//
+//	facelog upload [-stream id] photo.jpg [more.jpg ...]
+//	facelog tail <stream-id>
+//	facelog streams
+//
+// The API base URL, bearer token and admin key come from -url, -token and
+// -admin-key, or from FACELOG_URL, FACELOG_TOKEN and FACELOG_ADMIN_KEY.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/muthu-kumar-u/go-sse/client"
+)
+
+type options struct {
+	url      string
+	token    string
+	adminKey string
+	json     bool
+}
+
+func main() {
+	opts := &options{
+		url:      envOr("FACELOG_URL", "http://localhost:8080/api/v1"),
+		token:    os.Getenv("FACELOG_TOKEN"),
+		adminKey: os.Getenv("FACELOG_ADMIN_KEY"),
+	}
+	global := flag.NewFlagSet("facelog", flag.ExitOnError)
+	bindGlobalFlags(global, opts)
+	global.Usage = usage
+	global.Parse(os.Args[1:])
+
+	if global.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	command, args := global.Arg(0), global.Args()[1:]
+	switch command {
+	case "upload":
+		err = runUpload(ctx, opts, args)
+	case "tail":
+		err = runTail(ctx, opts, args)
+	case "streams":
+		err = runStreams(ctx, opts, args)
+	case "cancel":
+		err = runCancel(ctx, opts, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(130)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// bindGlobalFlags uses the current option values as defaults so a flag set bound
+// after the global one does not reset them
+func bindGlobalFlags(fs *flag.FlagSet, opts *options) {
+	fs.StringVar(&opts.url, "url", opts.url, "API base URL including the version prefix")
+	fs.StringVar(&opts.token, "token", opts.token, "bearer token")
+	fs.StringVar(&opts.adminKey, "admin-key", opts.adminKey, "admin API key")
+	fs.BoolVar(&opts.json, "json", opts.json, "print machine-readable JSON lines")
+}
+
+// parseCommand lets global flags also follow the subcommand name
+func parseCommand(name string, opts *options, args []string, bind func(fs *flag.FlagSet)) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	bindGlobalFlags(fs, opts)
+	if bind != nil {
+		bind(fs)
+	}
+	fs.Parse(args)
+	return fs
+}
+
+func newClient(opts *options) *client.Client {
+	c := client.New(opts.url, opts.token)
+	c.AdminKey = opts.adminKey
+	c.MaxReconnects = 5
+	return c
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `usage: facelog [flags] <command> [args]
+
+commands:
+  upload <image>...   upload one or more images and render scan progress
+  tail <stream-id>    follow an existing stream until it finishes
+  cancel <job-id>     cancel a queued or running scan
+  streams             list active streams (admin)
+
+flags:
+  -url string         API base URL (env FACELOG_URL)
+  -token string       bearer token (env FACELOG_TOKEN)
+  -admin-key string   admin API key (env FACELOG_ADMIN_KEY)
+  -json               print JSON lines instead of a progress display
+`)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+const barWidth = 30
+
+// renderer prints stream events either as a live progress line or as JSON lines
+type renderer struct {
+	json        bool
+	interactive bool
+	drawn       bool
+}
+
+func newRenderer(jsonOutput bool) *renderer {
+	interactive := false
+	if info, err := os.Stdout.Stat(); err == nil {
+		interactive = info.Mode()&os.ModeCharDevice != 0
+	}
+	return &renderer{json: jsonOutput, interactive: interactive}
+}
+
+func (r *renderer) Event(streamID string, event *appschema.EventMessage) {
+	if r.json {
+		if event.StreamID == "" {
+			event.StreamID = streamID
+		}
+		printJSON(event)
+		return
+	}
+
+	line := fmt.Sprintf("%s %3d%% %-16s %s", bar(event.Completion), event.Completion, event.Event, event.Message)
+	if event.Filename != "" {
+		line += " (" + event.Filename + ")"
+	}
+	if event.Cached {
+		line += " [cached]"
+	}
+	if event.Event == faceanalyze_events.EventReady {
+		line = fmt.Sprintf("stream %s ready", streamID)
+	}
+
+	if r.interactive {
+		fmt.Printf("\r\033[K%s", line)
+		r.drawn = true
+		return
+	}
+	fmt.Println(line)
+}
+
+// Finish ends the live progress line
+func (r *renderer) Finish() {
+	if r.drawn {
+		fmt.Println()
+		r.drawn = false
+	}
+}
+
+// Result prints the payload of the done or batch_done event
+func (r *renderer) Result(event *appschema.EventMessage) {
+	if event == nil || event.Data == nil || r.json {
+		return
+	}
+
+	switch data := event.Data.(type) {
+	case *appschema.FaceScanData:
+		printScan(data, "")
+	case *appschema.BatchSummary:
+		fmt.Printf("\n%d of %d images scanned\n", data.Succeeded, data.Total)
+		for _, image := range data.Images {
+			if image.Status != appschema.JobSucceeded {
+				fmt.Printf("\n#%d %s: failed (%s) %s\n", image.Index, image.Filename, image.ErrorCode, image.Message)
+				continue
+			}
+			fmt.Printf("\n#%d %s\n", image.Index, image.Filename)
+			printScan(image.Result, "  ")
+		}
+	default:
+		printJSON(event.Data)
+	}
+}
+
+func printScan(data *appschema.FaceScanData, indent string) {
+	if data == nil {
+		return
+	}
+	fmt.Println(indent + "quantitative")
+	for _, name := range slices.Sorted(maps.Keys(data.Quantitative)) {
+		fmt.Printf("%s  %-24s %6.2f%%\n", indent, name, data.Quantitative[name].Percentage)
+	}
+	fmt.Println(indent + "qualitative")
+	for _, name := range slices.Sorted(maps.Keys(data.Qualitative)) {
+		fmt.Printf("%s  %-24s %v\n", indent, name, data.Qualitative[name].IsPresent)
+	}
+}
+
+func bar(completion int) string {
+	filled := completion * barWidth / 100
+	if filled < 0 {
+		filled = 0
+	}
+	if filled > barWidth {
+		filled = barWidth
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", barWidth-filled) + "]"
+}
+
+func printJSON(v any) error {
+	return json.NewEncoder(os.Stdout).Encode(v)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// captureStdout returns what fn prints
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	fn()
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestBar(t *testing.T) {
+	tests := []struct {
+		completion int
+		filled     int
+	}{
+		{completion: -5, filled: 0},
+		{completion: 0, filled: 0},
+		{completion: 50, filled: 15},
+		{completion: 100, filled: 30},
+		{completion: 150, filled: 30},
+	}
+	for _, tt := range tests {
+		got := bar(tt.completion)
+		want := "[" + strings.Repeat("#", tt.filled) + strings.Repeat("-", barWidth-tt.filled) + "]"
+		if got != want {
+			t.Errorf("bar(%d) = %s, want %s", tt.completion, got, want)
+		}
+	}
+}
+
+func TestRendererEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  bool
+		event *appschema.EventMessage
+		want  []string
+	}{
+		{
+			name:  "progress line",
+			event: &appschema.EventMessage{Event: "analyzing_face", Message: "Analyzing face", Completion: 50, Filename: "a.jpg", Cached: true},
+			want:  []string{" 50% analyzing_face", "Analyzing face (a.jpg) [cached]"},
+		},
+		{
+			name:  "ready",
+			event: &appschema.EventMessage{Event: "ready"},
+			want:  []string{"stream s1 ready"},
+		},
+		{
+			name:  "json fills in the stream",
+			json:  true,
+			event: &appschema.EventMessage{Event: "queued"},
+			want:  []string{`"event":"queued"`, `"stream_id":"s1"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := captureStdout(t, func() {
+				(&renderer{json: tt.json}).Event("s1", tt.event)
+			})
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("printed %q, want it to contain %q", out, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRendererResult(t *testing.T) {
+	scan := &appschema.FaceScanData{
+		Quantitative: map[string]appschema.QuantitativeMetric{"wrinkles": {Percentage: 12.5}, "acne": {Percentage: 3}},
+	}
+	tests := []struct {
+		name  string
+		event *appschema.EventMessage
+		want  []string
+	}{
+		{name: "no event"},
+		{
+			name:  "scan, metrics sorted",
+			event: &appschema.EventMessage{Data: scan},
+			want:  []string{"acne", "3.00%", "wrinkles", "12.50%"},
+		},
+		{
+			name: "batch",
+			event: &appschema.EventMessage{Data: &appschema.BatchSummary{
+				Total: 2, Succeeded: 1, Failed: 1,
+				Images: []appschema.BatchImageResult{
+					{Index: 0, Filename: "a.jpg", Status: appschema.JobSucceeded, Result: scan},
+					{Index: 1, Filename: "b.jpg", Status: appschema.JobFailed, ErrorCode: "timeout", Message: "too slow"},
+				},
+			}},
+			want: []string{"1 of 2 images scanned", "#0 a.jpg", "  quantitative", "#1 b.jpg: failed (timeout) too slow"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := captureStdout(t, func() { (&renderer{}).Result(tt.event) })
+			last := -1
+			for _, want := range tt.want {
+				i := strings.Index(out[max(last, 0):], want)
+				if i < 0 {
+					t.Fatalf("printed %q, want %q next", out, want)
+				}
+				last = max(last, 0) + i + len(want)
+			}
+			if len(tt.want) == 0 && out != "" {
+				t.Fatalf("printed %q for nothing", out)
+			}
+		})
+	}
+}
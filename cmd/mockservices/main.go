@@ -0,0 +1,60 @@
+// Command mockservices serves stand-ins for the face analyzer and user
+// services, so the API runs offline:
+//
+//	mockservices -addr 127.0.0.1:8090 -latency 2s -failure-rate 0.1 -random
+//	FACE_ANALYZE_SERVICE=http://127.0.0.1:8090 USER_SERVICE=http://127.0.0.1:8090 go run .
+//
+// Flags default to the MOCK_* variables the API's in-process MOCK_SERVICES
+// mode reads, from the environment or a .env file. Requests authenticate with
+// the bearer tokens dev-token and dev-token-2 unless MOCK_TOKENS_FILE replaces
+// them.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/muthu-kumar-u/go-sse/mockservice"
+	"github.com/muthu-kumar-u/go-sse/utils"
+)
+
+func main() {
+	godotenv.Load()
+	cfg, err := utils.MockServicesConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+
+	addr := os.Getenv("MOCK_SERVICES_ADDR")
+	if addr == "" {
+		addr = "127.0.0.1:8090"
+	}
+	flag.StringVar(&addr, "addr", addr, "address to listen on")
+	flag.DurationVar(&cfg.Latency, "latency", cfg.Latency, "delay before each analyzer answer")
+	flag.DurationVar(&cfg.Jitter, "jitter", cfg.Jitter, "random extra delay up to this long")
+	flag.Float64Var(&cfg.FailureRate, "failure-rate", cfg.FailureRate, "share of analyzer calls that fail, 0 to 1")
+	flag.BoolVar(&cfg.Random, "random", cfg.Random, "randomise results instead of the canned scan")
+	flag.StringVar(&cfg.Stream, "stream", cfg.Stream, "stream answers as sse or ndjson when the caller accepts it")
+	flag.BoolVar(&cfg.AnyToken, "any-token", cfg.AnyToken, "accept any bearer token as a user of its own")
+	flag.Uint64Var(&cfg.Seed, "seed", cfg.Seed, "seed for repeatable results and failures")
+	flag.Parse()
+
+	switch cfg.Stream {
+	case mockservice.StreamOff, mockservice.StreamSSE, mockservice.StreamNDJSON:
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown -stream %q\n", cfg.Stream)
+		os.Exit(2)
+	}
+	if cfg.FailureRate < 0 || cfg.FailureRate > 1 {
+		fmt.Fprintln(os.Stderr, "error: -failure-rate must be between 0 and 1")
+		os.Exit(2)
+	}
+
+	log.Printf("Mock face analyzer and user services on http://%s", addr)
+	log.Fatal(http.ListenAndServe(addr, mockservice.Handler(cfg)))
+}
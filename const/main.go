@@ -3,6 +3,13 @@ package constants
 var FACE_ANALYZE_PAYLOAD_FIELD_NAME = "file"
 var IMAGE_EXTENSIONS = []string{".png", ".jpeg", ".jpg"} 
 
+// sniffed content type each allowed extension must carry
+var IMAGE_CONTENT_TYPES = map[string]string{
+	".png":  "image/png",
+	".jpeg": "image/jpeg",
+	".jpg":  "image/jpeg",
+}
+
 var FACE_ANALYZE_SERVICE_PATHS = []string{
 	"face/analyzer",
 }
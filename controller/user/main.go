@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -10,10 +11,16 @@ import (
 	constants "github.com/muthu-kumar-u/go-sse/const"
 	"github.com/muthu-kumar-u/go-sse/globals"
 	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/utils"
 )
 
+// ErrInvalidAccount is a user service answer with no stable account ID to own
+// jobs, history and stored images by
+var ErrInvalidAccount = errors.New("user service returned no account id")
+
 type UserController interface {
 	IsUserAuthenticated(ctx context.Context, userId string) (bool, error)
+	GetAuthenticatedUser(ctx context.Context, token string) (*appschema.GetUserData, error)
 }
 
 type userControllerImpl struct {
@@ -25,12 +32,23 @@ func NewUserController(userService appschema.ServiceConnection) UserController {
 }
 
 // user
-func (s *userControllerImpl) IsUserAuthenticated(ctx context.Context, token string) (bool, error){
-	reqUrl := fmt.Sprintf("%s/%s", s.UserService.URL , constants.USER_SERVICE_PATHS[0])
-	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+func (s *userControllerImpl) IsUserAuthenticated(ctx context.Context, token string) (bool, error) {
+	user, err := s.GetAuthenticatedUser(ctx, token)
 	if err != nil {
 		return false, err
 	}
+	return user != nil, nil
+}
+
+// GetAuthenticatedUser resolves the account behind a token. A nil user with a nil
+// error means the user service rejected the token; an account without an ID is
+// ErrInvalidAccount.
+func (s *userControllerImpl) GetAuthenticatedUser(ctx context.Context, token string) (*appschema.GetUserData, error) {
+	reqUrl := fmt.Sprintf("%s/%s", s.UserService.URL, constants.USER_SERVICE_PATHS[0])
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 	req.Header.Set("Content-Type", "application/json")
@@ -38,15 +56,25 @@ func (s *userControllerImpl) IsUserAuthenticated(ctx context.Context, token stri
 	resp, err := globals.FaceAnalyzeService.Client.Do(req)
 	if err != nil {
 		log.Printf("Error making request to user service: %v\n", err)
-		return false, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		log.Printf("user service error %d: %s\n", resp.StatusCode, string(body))
-		return false, err
+		return nil, nil
+	}
+
+	var account appschema.UserAccountResponse
+	if err := utils.BindHttpResponseToStruct(resp, &account); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAccount, err)
+	}
+	// emails and tokens change, so neither can stand in for the ID owning a
+	// user's data
+	if account.Data.ID == "" {
+		return nil, ErrInvalidAccount
 	}
 
-	return true, nil
+	return &account.Data, nil
 }
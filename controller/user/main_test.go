@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muthu-kumar-u/go-sse/globals"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+func TestGetAuthenticatedUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantID  string
+		wantNil bool
+		wantErr error
+	}{
+		{name: "account", status: http.StatusOK, body: `{"data":{"id":"u1","email":"a@b.c"}}`, wantID: "u1"},
+		{name: "rejected token", status: http.StatusUnauthorized, body: `{}`, wantNil: true},
+		{name: "email without id", status: http.StatusOK, body: `{"data":{"email":"a@b.c"}}`, wantErr: ErrInvalidAccount},
+		{name: "empty account", status: http.StatusOK, body: `{"data":{}}`, wantErr: ErrInvalidAccount},
+		{name: "undecodable", status: http.StatusOK, body: `{"data":`, wantErr: ErrInvalidAccount},
+		{name: "empty body", status: http.StatusOK, body: ``, wantErr: ErrInvalidAccount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "Bearer tok" {
+					t.Errorf("Authorization = %q", got)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			globals.FaceAnalyzeService = &appschema.ServiceConnection{Client: srv.Client(), URL: srv.URL}
+
+			c := NewUserController(appschema.ServiceConnection{Client: srv.Client(), URL: srv.URL})
+			user, err := c.GetAuthenticatedUser(context.Background(), "tok")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantNil {
+				if user != nil {
+					t.Fatalf("user = %+v, want nil", user)
+				}
+				return
+			}
+			if user == nil || user.ID != tt.wantID {
+				t.Fatalf("user = %+v, want id %q", user, tt.wantID)
+			}
+		})
+	}
+}
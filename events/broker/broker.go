@@ -0,0 +1,83 @@
+// Package broker persists stream events outside the process so that a subscriber
+// served by one Lambda invocation can follow a scan published by another.
+package broker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+)
+
+// Broker is an append-only per-stream event log
+type Broker interface {
+	// Append stores an event and returns its sequence number within the stream
+	Append(ctx context.Context, streamID, event string, data []byte) (uint64, error)
+	// Read returns the events stored after afterID, oldest first
+	Read(ctx context.Context, streamID string, afterID uint64) ([]stream.Message, error)
+}
+
+// how long Follow waits for a missing seq before giving up on it. A publisher
+// reserves a seq before writing its event, so a later event can land first;
+// one that never lands, say because its publisher failed, is skipped.
+var gapGrace = 10 * time.Second
+
+// Follow polls b for new events on streamID and delivers them on the returned
+// channel until ctx ends, a read fails or isFinal reports a terminal event. The
+// channel is closed when following stops. Events are delivered in seq order:
+// following waits at a gap until the missing event arrives or gapGrace passes.
+func Follow(ctx context.Context, b Broker, streamID string, afterID uint64, interval time.Duration, isFinal func(stream.Message) bool) <-chan stream.Message {
+	out := make(chan stream.Message, 10)
+
+	go func() {
+		defer close(out)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// when the gap after afterID was first seen
+		var gapSince time.Time
+
+		for {
+			messages, err := b.Read(ctx, streamID, afterID)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("[broker] Stream %s: read failed: %v", streamID, err)
+				}
+				return
+			}
+
+			for _, msg := range messages {
+				if msg.ID != afterID+1 {
+					if gapSince.IsZero() {
+						gapSince = time.Now()
+					}
+					if time.Since(gapSince) < gapGrace {
+						break
+					}
+					log.Printf("[broker] Stream %s: events %d to %d never arrived, skipping them", streamID, afterID+1, msg.ID-1)
+				}
+				gapSince = time.Time{}
+
+				select {
+				case out <- msg:
+				case <-ctx.Done():
+					return
+				}
+				afterID = msg.ID
+				if isFinal != nil && isFinal(msg) {
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return out
+}
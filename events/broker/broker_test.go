@@ -0,0 +1,193 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+)
+
+func TestMemoryRead(t *testing.T) {
+	m := NewMemory(time.Hour)
+	ctx := context.Background()
+	for _, event := range []string{"ready", "stage", "done"} {
+		m.Append(ctx, "s1", event, []byte(`{}`))
+	}
+	if id, _ := m.Append(ctx, "s2", "ready", nil); id != 1 {
+		t.Fatalf("first seq of another stream = %d, want 1", id)
+	}
+
+	tests := []struct {
+		stream string
+		after  uint64
+		want   []string
+	}{
+		{stream: "s1", after: 0, want: []string{"ready", "stage", "done"}},
+		{stream: "s1", after: 1, want: []string{"stage", "done"}},
+		{stream: "s1", after: 3, want: nil},
+		{stream: "s1", after: 9, want: nil},
+		{stream: "none", after: 0, want: nil},
+	}
+	for _, tt := range tests {
+		messages, err := m.Read(ctx, tt.stream, tt.after)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		var got []string
+		for i, msg := range messages {
+			if msg.ID != tt.after+uint64(i)+1 {
+				t.Errorf("%s after %d: message %d has ID %d", tt.stream, tt.after, i, msg.ID)
+			}
+			got = append(got, msg.Event)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s after %d = %v, want %v", tt.stream, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	m := NewMemory(time.Millisecond)
+	ctx := context.Background()
+	m.Append(ctx, "old", "ready", nil)
+	time.Sleep(5 * time.Millisecond)
+
+	// expired streams go on the next append
+	m.Append(ctx, "new", "ready", nil)
+	if messages, _ := m.Read(ctx, "old", 0); messages != nil {
+		t.Fatalf("expired stream still has %d events", len(messages))
+	}
+}
+
+func TestFollow(t *testing.T) {
+	isDone := func(msg stream.Message) bool { return msg.Event == "done" }
+	tests := []struct {
+		name  string
+		after uint64
+		// late events are appended once following has started
+		early, late []string
+		want        []string
+	}{
+		{name: "stops at the final event", early: []string{"ready", "done", "extra"}, want: []string{"ready", "done"}},
+		{name: "resumes after an ID", after: 1, early: []string{"ready", "stage", "done"}, want: []string{"stage", "done"}},
+		{name: "picks up later events", early: []string{"ready"}, late: []string{"stage", "done"}, want: []string{"ready", "stage", "done"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			m := NewMemory(time.Hour)
+			for _, event := range tt.early {
+				m.Append(ctx, "s1", event, nil)
+			}
+
+			messages := Follow(ctx, m, "s1", tt.after, time.Millisecond, isDone)
+			for _, event := range tt.late {
+				m.Append(ctx, "s1", event, nil)
+			}
+			var got []string
+			for msg := range messages {
+				got = append(got, msg.Event)
+			}
+			if ctx.Err() != nil {
+				t.Fatal("Follow did not stop at the final event")
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("followed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFollowStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := Follow(ctx, NewMemory(time.Hour), "s1", 0, time.Millisecond, nil)
+	cancel()
+	select {
+	case _, open := <-messages:
+		if open {
+			t.Fatal("received an event from an empty stream")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Follow kept going after its context ended")
+	}
+}
+
+// sparseBroker serves whatever seqs it was given, gaps and all, as a shared
+// table does while a publisher is between reserving a seq and writing it
+type sparseBroker struct {
+	mu       sync.Mutex
+	messages map[uint64]stream.Message
+}
+
+func (s *sparseBroker) put(id uint64, event string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[id] = stream.Message{ID: id, Event: event}
+}
+
+func (s *sparseBroker) Append(ctx context.Context, streamID, event string, data []byte) (uint64, error) {
+	return 0, errors.New("not supported")
+}
+
+func (s *sparseBroker) Read(ctx context.Context, streamID string, afterID uint64) ([]stream.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var messages []stream.Message
+	for _, msg := range s.messages {
+		if msg.ID > afterID {
+			messages = append(messages, msg)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, nil
+}
+
+func TestFollowWaitsAtGaps(t *testing.T) {
+	grace := gapGrace
+	defer func() { gapGrace = grace }()
+
+	isDone := func(msg stream.Message) bool { return msg.Event == "done" }
+	tests := []struct {
+		name  string
+		grace time.Duration
+		// stage, the seq 2 event, is written late or never
+		fillGap bool
+		want    []string
+	}{
+		{name: "missing event arrives", grace: time.Minute, fillGap: true, want: []string{"ready", "stage", "done"}},
+		{name: "missing event never arrives", grace: 20 * time.Millisecond, want: []string{"ready", "done"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gapGrace = tt.grace
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			b := &sparseBroker{messages: make(map[uint64]stream.Message)}
+			b.put(1, "ready")
+			b.put(3, "done")
+			messages := Follow(ctx, b, "s1", 0, time.Millisecond, isDone)
+			if tt.fillGap {
+				time.Sleep(20 * time.Millisecond)
+				b.put(2, "stage")
+			}
+
+			var got []string
+			for msg := range messages {
+				got = append(got, msg.Event)
+			}
+			if ctx.Err() != nil {
+				t.Fatal("Follow did not reach the final event")
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("followed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
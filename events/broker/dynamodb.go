@@ -0,0 +1,167 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+)
+
+// DynamoDB stores events in a table keyed by stream_id (partition, string) and
+// seq (sort, number). Items carry an expires_at epoch for the table's TTL setting.
+// Each stream's seq 0 item is the counter its event seqs are allocated from.
+// Allocating and writing are separate calls, so an event can land before one
+// with a lower seq; Follow waits for the missing one.
+type DynamoDB struct {
+	client dynamodbiface.DynamoDBAPI
+	table  string
+	ttl    time.Duration
+}
+
+// counterSeq is the sort key of a stream's counter item; readers only ever ask
+// for seqs after an event ID, so it never shows up as an event
+const counterSeq = "0"
+
+// how many times Append allocates a new seq when its slot is already taken
+const maxAppendAttempts = 5
+
+func NewDynamoDB(sess *session.Session, table string, ttl time.Duration) *DynamoDB {
+	return &DynamoDB{
+		client: dynamodb.New(sess),
+		table:  table,
+		ttl:    ttl,
+	}
+}
+
+// Append allocates the stream's next seq and writes the event under it. Any
+// number of publishers may share a stream; should a slot be taken anyway, say by
+// an item written before the counter, the event moves on to a fresh seq.
+func (d *DynamoDB) Append(ctx context.Context, streamID, event string, data []byte) (uint64, error) {
+	for attempt := 1; ; attempt++ {
+		seq, err := d.nextSeq(ctx, streamID)
+		if err != nil {
+			return 0, err
+		}
+		err = d.put(ctx, streamID, seq, event, data)
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException && attempt < maxAppendAttempts {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return seq, nil
+	}
+}
+
+func (d *DynamoDB) put(ctx context.Context, streamID string, seq uint64, event string, data []byte) error {
+	item := map[string]*dynamodb.AttributeValue{
+		"stream_id": {S: aws.String(streamID)},
+		"seq":       {N: aws.String(strconv.FormatUint(seq, 10))},
+		"event":     {S: aws.String(event)},
+		"data":      {B: data},
+	}
+	if d.ttl > 0 {
+		expiresAt := time.Now().Add(d.ttl).Unix()
+		item["expires_at"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(expiresAt, 10))}
+	}
+
+	_, err := d.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(d.table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(seq)"),
+	})
+	return err
+}
+
+func (d *DynamoDB) Read(ctx context.Context, streamID string, afterID uint64) ([]stream.Message, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(d.table),
+		KeyConditionExpression: aws.String("stream_id = :stream AND seq > :after"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":stream": {S: aws.String(streamID)},
+			":after":  {N: aws.String(strconv.FormatUint(afterID, 10))},
+		},
+		ConsistentRead:   aws.Bool(true),
+		ScanIndexForward: aws.Bool(true),
+	}
+
+	var (
+		messages []stream.Message
+		parseErr error
+	)
+	err := d.client.QueryPagesWithContext(ctx, input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			msg, err := messageFromItem(item)
+			if err != nil {
+				parseErr = err
+				return false
+			}
+			messages = append(messages, msg)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, parseErr
+}
+
+// nextSeq increments the stream's counter item and returns the new value.
+// DynamoDB applies ADD atomically, so concurrent publishers never share a seq.
+func (d *DynamoDB) nextSeq(ctx context.Context, streamID string) (uint64, error) {
+	update := "ADD last_seq :one"
+	values := map[string]*dynamodb.AttributeValue{
+		":one": {N: aws.String("1")},
+	}
+	if d.ttl > 0 {
+		// the counter lives as long as the stream's latest event
+		update += " SET expires_at = :expires"
+		values[":expires"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(time.Now().Add(d.ttl).Unix(), 10))}
+	}
+
+	out, err := d.client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"stream_id": {S: aws.String(streamID)},
+			"seq":       {N: aws.String(counterSeq)},
+		},
+		UpdateExpression:          aws.String(update),
+		ExpressionAttributeValues: values,
+		ReturnValues:              aws.String(dynamodb.ReturnValueUpdatedNew),
+	})
+	if err != nil {
+		return 0, err
+	}
+	attr, ok := out.Attributes["last_seq"]
+	if !ok || attr.N == nil {
+		return 0, fmt.Errorf("counter of stream %s returned no seq", streamID)
+	}
+	return strconv.ParseUint(*attr.N, 10, 64)
+}
+
+func messageFromItem(item map[string]*dynamodb.AttributeValue) (stream.Message, error) {
+	var msg stream.Message
+	if attr, ok := item["seq"]; ok && attr.N != nil {
+		seq, err := strconv.ParseUint(*attr.N, 10, 64)
+		if err != nil {
+			return msg, err
+		}
+		msg.ID = seq
+	}
+	if attr, ok := item["event"]; ok && attr.S != nil {
+		msg.Event = *attr.S
+	}
+	if attr, ok := item["data"]; ok {
+		msg.Data = attr.B
+	}
+	return msg, nil
+}
@@ -0,0 +1,197 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// fakeTable is just enough of DynamoDB for the event store: atomic counter
+// updates, conditional puts and ordered queries
+type fakeTable struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu    sync.Mutex
+	items map[string]map[uint64]map[string]*dynamodb.AttributeValue
+}
+
+func newFakeTable() *fakeTable {
+	return &fakeTable{items: make(map[string]map[uint64]map[string]*dynamodb.AttributeValue)}
+}
+
+func (f *fakeTable) stream(id string) map[uint64]map[string]*dynamodb.AttributeValue {
+	if f.items[id] == nil {
+		f.items[id] = make(map[uint64]map[string]*dynamodb.AttributeValue)
+	}
+	return f.items[id]
+}
+
+func (f *fakeTable) UpdateItemWithContext(ctx aws.Context, in *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	seq, _ := strconv.ParseUint(*in.Key["seq"].N, 10, 64)
+	items := f.stream(*in.Key["stream_id"].S)
+	item := items[seq]
+	if item == nil {
+		item = map[string]*dynamodb.AttributeValue{"stream_id": in.Key["stream_id"], "seq": in.Key["seq"]}
+		items[seq] = item
+	}
+	last := uint64(0)
+	if attr := item["last_seq"]; attr != nil {
+		last, _ = strconv.ParseUint(*attr.N, 10, 64)
+	}
+	add, _ := strconv.ParseUint(*in.ExpressionAttributeValues[":one"].N, 10, 64)
+	item["last_seq"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatUint(last+add, 10))}
+	if expires := in.ExpressionAttributeValues[":expires"]; expires != nil {
+		item["expires_at"] = expires
+	}
+	return &dynamodb.UpdateItemOutput{Attributes: map[string]*dynamodb.AttributeValue{"last_seq": item["last_seq"]}}, nil
+}
+
+func (f *fakeTable) PutItemWithContext(ctx aws.Context, in *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	seq, _ := strconv.ParseUint(*in.Item["seq"].N, 10, 64)
+	items := f.stream(*in.Item["stream_id"].S)
+	if _, taken := items[seq]; taken {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	items[seq] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeTable) QueryPagesWithContext(ctx aws.Context, in *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, _ ...request.Option) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	after, _ := strconv.ParseUint(*in.ExpressionAttributeValues[":after"].N, 10, 64)
+	items := f.stream(*in.ExpressionAttributeValues[":stream"].S)
+	seqs := make([]uint64, 0, len(items))
+	for seq := range items {
+		if seq > after {
+			seqs = append(seqs, seq)
+		}
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	page := &dynamodb.QueryOutput{}
+	for _, seq := range seqs {
+		page.Items = append(page.Items, items[seq])
+	}
+	fn(page, true)
+	return nil
+}
+
+func TestDynamoDBConcurrentPublishers(t *testing.T) {
+	table := newFakeTable()
+	// two instances share the table, as two Lambda invocations would
+	publishers := []*DynamoDB{
+		{client: table, table: "events", ttl: time.Hour},
+		{client: table, table: "events", ttl: time.Hour},
+	}
+
+	const perPublisher = 50
+	var wg sync.WaitGroup
+	for i, publisher := range publishers {
+		for n := range perPublisher {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := publisher.Append(context.Background(), "s1", "stage", []byte(fmt.Sprintf("%d-%d", i, n))); err != nil {
+					t.Errorf("Append: %v", err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	messages, err := publishers[0].Read(context.Background(), "s1", 0)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(messages) != 2*perPublisher {
+		t.Fatalf("read %d events, want %d", len(messages), 2*perPublisher)
+	}
+	for i, msg := range messages {
+		if msg.ID != uint64(i+1) {
+			t.Fatalf("event %d has seq %d, want %d", i, msg.ID, i+1)
+		}
+	}
+	if counter := table.items["s1"][0]; counter["expires_at"] == nil {
+		t.Error("counter item has no expires_at")
+	}
+}
+
+func TestDynamoDBAppend(t *testing.T) {
+	tests := []struct {
+		name string
+		// seqs already in the stream, e.g. written before the counter existed
+		taken   []uint64
+		want    uint64
+		wantErr bool
+	}{
+		{name: "first event", want: 1},
+		{name: "skips a taken seq", taken: []uint64{1, 2}, want: 3},
+		{name: "gives up", taken: []uint64{1, 2, 3, 4, 5}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := newFakeTable()
+			for _, seq := range tt.taken {
+				table.stream("s1")[seq] = map[string]*dynamodb.AttributeValue{}
+			}
+			d := &DynamoDB{client: table, table: "events"}
+
+			seq, err := d.Append(context.Background(), "s1", "done", []byte("{}"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Append = %d, %v; wantErr %v", seq, err, tt.wantErr)
+			}
+			if !tt.wantErr && seq != tt.want {
+				t.Fatalf("Append = %d, want %d", seq, tt.want)
+			}
+		})
+	}
+}
+
+func TestDynamoDBReadAfter(t *testing.T) {
+	d := &DynamoDB{client: newFakeTable(), table: "events"}
+	for _, event := range []string{"ready", "stage", "done"} {
+		if _, err := d.Append(context.Background(), "s1", event, []byte(`{}`)); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	d.Append(context.Background(), "s2", "ready", []byte(`{}`))
+
+	tests := []struct {
+		after uint64
+		want  []string
+	}{
+		{after: 0, want: []string{"ready", "stage", "done"}},
+		{after: 2, want: []string{"done"}},
+		{after: 3, want: nil},
+	}
+	for _, tt := range tests {
+		messages, err := d.Read(context.Background(), "s1", tt.after)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		var got []string
+		for _, msg := range messages {
+			got = append(got, msg.Event)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("Read after %d = %v, want %v", tt.after, got, tt.want)
+		}
+	}
+}
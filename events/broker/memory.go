@@ -0,0 +1,67 @@
+package broker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+)
+
+// Memory is an in-process Broker for local runs. Streams expire ttl after their
+// last append.
+type Memory struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	streams map[string]*memoryStream
+}
+
+type memoryStream struct {
+	messages  []stream.Message
+	updatedAt time.Time
+}
+
+func NewMemory(ttl time.Duration) *Memory {
+	return &Memory{ttl: ttl, streams: make(map[string]*memoryStream)}
+}
+
+func (m *Memory) Append(ctx context.Context, streamID, event string, data []byte) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(time.Now())
+
+	s, ok := m.streams[streamID]
+	if !ok {
+		s = &memoryStream{}
+		m.streams[streamID] = s
+	}
+	id := uint64(len(s.messages) + 1)
+	s.messages = append(s.messages, stream.Message{ID: id, Event: event, Data: data})
+	s.updatedAt = time.Now()
+	return id, nil
+}
+
+func (m *Memory) Read(ctx context.Context, streamID string, afterID uint64) ([]stream.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.streams[streamID]
+	if !ok || afterID >= uint64(len(s.messages)) {
+		return nil, nil
+	}
+	messages := make([]stream.Message, len(s.messages[afterID:]))
+	copy(messages, s.messages[afterID:])
+	return messages, nil
+}
+
+func (m *Memory) expire(now time.Time) {
+	if m.ttl <= 0 {
+		return
+	}
+	for id, s := range m.streams {
+		if now.Sub(s.updatedAt) > m.ttl {
+			delete(m.streams, id)
+		}
+	}
+}
@@ -2,8 +2,17 @@ package faceanalyze_events
 
 var (
 	EventReady 				= "ready"
+	EventQueued 			= "queued"
 	EventProcessingImage 	= "processing_image"
+	EventPreprocessingImage = "preprocessing_image"
+	EventImageStored 		= "image_stored"
 	EventAnalyzingFace 		= "analyzing_face"
+	EventRetrying 			= "retrying"
+	EventPartialResult 		= "partial_result"
 	EventError 			    = "error"
 	EventCompleted 			= "done"
-)
\ No newline at end of file
+	EventImageCompleted 	= "image_done"
+	EventImageError 		= "image_error"
+	EventBatchCompleted 	= "batch_done"
+	EventCancelled 			= "cancelled"
+)
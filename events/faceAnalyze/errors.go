@@ -0,0 +1,28 @@
+package faceanalyze_events
+
+// machine readable codes carried by error events and failed jobs
+var (
+	ErrCodeInvalidForm         = "invalid_form"
+	ErrCodeInvalidCallback     = "invalid_callback_url"
+	ErrCodeMissingImage        = "missing_image"
+	ErrCodeTooManyImages       = "too_many_images"
+	ErrCodeUnsupportedType     = "unsupported_image_type"
+	ErrCodeTypeMismatch        = "image_type_mismatch"
+	ErrCodeFileTooLarge        = "file_too_large"
+	ErrCodeCorruptImage        = "corrupt_image"
+	ErrCodeImageTooSmall       = "image_too_small"
+	ErrCodeImageTooLarge       = "image_too_large"
+	ErrCodeTooManyPixels       = "too_many_pixels"
+	ErrCodeAnimatedImage       = "animated_image"
+	ErrCodePreprocessFailed    = "preprocess_failed"
+	ErrCodeQueueFull           = "queue_full"
+	ErrCodeInternal            = "internal_error"
+	ErrCodeUpstreamUnavailable = "upstream_unavailable"
+	ErrCodeCircuitOpen         = "upstream_circuit_open"
+	ErrCodeUpstreamFailed      = "upstream_failed"
+	ErrCodeInvalidUpstream     = "invalid_upstream_response"
+	ErrCodeTimeout             = "timeout"
+	ErrCodeBatchFailed         = "batch_failed"
+	ErrCodeUploadMissing       = "upload_missing"
+	ErrCodeUploadStarted       = "upload_already_started"
+)
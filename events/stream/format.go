@@ -0,0 +1,72 @@
+package stream
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// Format is the wire framing a subscriber negotiated for its events
+type Format int
+
+const (
+	FormatSSE Format = iota
+	FormatNDJSON
+)
+
+const (
+	ContentTypeSSE    = "text/event-stream"
+	ContentTypeNDJSON = "application/x-ndjson"
+)
+
+// NegotiateFormat picks a framing from an Accept header. ok is false when the
+// header names neither streaming type, leaving the caller to choose a default.
+func NegotiateFormat(accept string) (format Format, ok bool) {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case ContentTypeNDJSON, "application/ndjson", "application/jsonl":
+			return FormatNDJSON, true
+		case ContentTypeSSE:
+			return FormatSSE, true
+		}
+	}
+	return FormatSSE, false
+}
+
+func (f Format) ContentType() string {
+	if f == FormatNDJSON {
+		return ContentTypeNDJSON
+	}
+	return ContentTypeSSE
+}
+
+// Frame wraps a JSON payload for the wire. id is omitted when zero.
+func (f Format) Frame(id uint64, event string, data []byte) []byte {
+	if f == FormatNDJSON {
+		line := make([]byte, 0, len(data)+1)
+		line = append(line, data...)
+		return append(line, '\n')
+	}
+
+	var b strings.Builder
+	if id > 0 {
+		fmt.Fprintf(&b, "id: %d\n", id)
+	}
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	fmt.Fprintf(&b, "data: %s\n\n", data)
+	return []byte(b.String())
+}
+
+// Heartbeat is a keep-alive the client parser ignores
+func (f Format) Heartbeat() []byte {
+	if f == FormatNDJSON {
+		return []byte("\n")
+	}
+	return []byte(": heartbeat\n\n")
+}
@@ -0,0 +1,52 @@
+package stream
+
+import "testing"
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Format
+		wantOK bool
+	}{
+		{accept: "", want: FormatSSE},
+		{accept: "*/*", want: FormatSSE},
+		{accept: "application/json", want: FormatSSE},
+		{accept: "text/event-stream", want: FormatSSE, wantOK: true},
+		{accept: "application/x-ndjson", want: FormatNDJSON, wantOK: true},
+		{accept: "application/ndjson", want: FormatNDJSON, wantOK: true},
+		{accept: "application/jsonl", want: FormatNDJSON, wantOK: true},
+		// the first streaming type listed wins, parameters and all
+		{accept: "application/json, application/x-ndjson;q=0.9, text/event-stream", want: FormatNDJSON, wantOK: true},
+		{accept: "text/event-stream; charset=utf-8, application/x-ndjson", want: FormatSSE, wantOK: true},
+		{accept: ";;, application/x-ndjson", want: FormatNDJSON, wantOK: true},
+	}
+	for _, tt := range tests {
+		got, ok := NegotiateFormat(tt.accept)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("NegotiateFormat(%q) = %v, %v; want %v, %v", tt.accept, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFormatFrame(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        Format
+		id            uint64
+		event         string
+		want          string
+		wantHeartbeat string
+	}{
+		{name: "sse", format: FormatSSE, id: 7, event: "done", want: "id: 7\nevent: done\ndata: {}\n\n", wantHeartbeat: ": heartbeat\n\n"},
+		{name: "sse without id or event", format: FormatSSE, want: "data: {}\n\n", wantHeartbeat: ": heartbeat\n\n"},
+		{name: "ndjson", format: FormatNDJSON, id: 7, event: "done", want: "{}\n", wantHeartbeat: "\n"},
+	}
+	for _, tt := range tests {
+		if got := string(tt.format.Frame(tt.id, tt.event, []byte("{}"))); got != tt.want {
+			t.Errorf("%s: Frame = %q, want %q", tt.name, got, tt.want)
+		}
+		if got := string(tt.format.Heartbeat()); got != tt.wantHeartbeat {
+			t.Errorf("%s: Heartbeat = %q, want %q", tt.name, got, tt.wantHeartbeat)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WireEvent is one event read off a stream. NDJSON lines carry only Data.
+type WireEvent struct {
+	ID    string
+	Event string
+	Data  []byte
+	Retry time.Duration
+}
+
+// Reader parses events in either framing from a response body
+type Reader struct {
+	format  Format
+	scanner *bufio.Scanner
+	retry   time.Duration
+	// the last id field seen, which events without one of their own inherit
+	lastID string
+}
+
+func NewReader(r io.Reader, format Format) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	return &Reader{format: format, scanner: scanner}
+}
+
+// Next returns the next event with a non-empty payload. Heartbeats, comments and
+// blank lines are skipped. It returns io.EOF once the stream ends.
+func (r *Reader) Next() (*WireEvent, error) {
+	if r.format == FormatNDJSON {
+		return r.nextLine()
+	}
+	return r.nextSSE()
+}
+
+// Retry is the reconnection delay the stream last asked for, zero until it
+// sends one. Servers often send it in a frame of its own, which Next skips.
+func (r *Reader) Retry() time.Duration {
+	return r.retry
+}
+
+func (r *Reader) nextLine() (*WireEvent, error) {
+	for r.scanner.Scan() {
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		return &WireEvent{Data: append([]byte(nil), line...)}, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (r *Reader) nextSSE() (*WireEvent, error) {
+	var (
+		current WireEvent
+		data    []string
+		hasData bool
+	)
+
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+
+		if line == "" {
+			if hasData {
+				current.ID = r.lastID
+				current.Data = []byte(strings.Join(data, "\n"))
+				return &current, nil
+			}
+			current, data = WireEvent{}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "id":
+			r.lastID = value
+		case "event":
+			current.Event = value
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				current.Retry = time.Duration(ms) * time.Millisecond
+				r.retry = current.Retry
+			}
+		}
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
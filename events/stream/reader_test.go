@@ -0,0 +1,124 @@
+package stream
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReaderSSE(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      []WireEvent
+		wantRetry time.Duration
+	}{
+		{
+			name:  "single event",
+			input: "id: 1\nevent: stage\ndata: {\"a\":1}\n\n",
+			want:  []WireEvent{{ID: "1", Event: "stage", Data: []byte(`{"a":1}`)}},
+		},
+		{
+			name:  "multi-line data",
+			input: "event: result\ndata: {\"a\":\ndata: 1}\n\n",
+			want:  []WireEvent{{Event: "result", Data: []byte("{\"a\":\n1}")}},
+		},
+		{
+			name:  "id carries over to events without one",
+			input: "id: 7\ndata: a\n\ndata: b\n\nid: 9\ndata: c\n\n",
+			want:  []WireEvent{{ID: "7", Data: []byte("a")}, {ID: "7", Data: []byte("b")}, {ID: "9", Data: []byte("c")}},
+		},
+		{
+			name:  "comments and heartbeats",
+			input: ": connected\n\n:heartbeat\n\nevent: stage\n: in between\ndata: x\n\n",
+			want:  []WireEvent{{Event: "stage", Data: []byte("x")}},
+		},
+		{
+			name:      "retry in a frame of its own",
+			input:     "retry: 3000\n\nid: 1\ndata: x\n\n",
+			want:      []WireEvent{{ID: "1", Data: []byte("x")}},
+			wantRetry: 3 * time.Second,
+		},
+		{
+			name:      "retry with an event",
+			input:     "retry: 250\nid: 2\ndata: x\n\n",
+			want:      []WireEvent{{ID: "2", Data: []byte("x"), Retry: 250 * time.Millisecond}},
+			wantRetry: 250 * time.Millisecond,
+		},
+		{
+			name:  "invalid retry is ignored",
+			input: "retry: soon\ndata: x\n\nretry: -5\ndata: y\n\n",
+			want:  []WireEvent{{Data: []byte("x")}, {Data: []byte("y")}},
+		},
+		{
+			name:  "value without a space",
+			input: "event:done\ndata:{}\n\n",
+			want:  []WireEvent{{Event: "done", Data: []byte("{}")}},
+		},
+		{
+			name:  "empty data line",
+			input: "data:\n\n",
+			want:  []WireEvent{{Data: []byte("")}},
+		},
+		{
+			name:  "unterminated event is dropped",
+			input: "data: a\n\ndata: b\n",
+			want:  []WireEvent{{Data: []byte("a")}},
+		},
+		{
+			name:  "unknown fields",
+			input: "foo: bar\ndata: x\n\n",
+			want:  []WireEvent{{Data: []byte("x")}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.input), FormatSSE)
+			for i, want := range tt.want {
+				got, err := r.Next()
+				if err != nil {
+					t.Fatalf("event %d: %v", i, err)
+				}
+				if got.ID != want.ID || got.Event != want.Event || string(got.Data) != string(want.Data) || got.Retry != want.Retry {
+					t.Fatalf("event %d = %+v (data %q), want %+v (data %q)", i, got, got.Data, want, want.Data)
+				}
+			}
+			if got, err := r.Next(); err != io.EOF {
+				t.Fatalf("after the last event: %+v, %v; want io.EOF", got, err)
+			}
+			if r.Retry() != tt.wantRetry {
+				t.Fatalf("Retry = %s, want %s", r.Retry(), tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestReaderNDJSON(t *testing.T) {
+	r := NewReader(strings.NewReader("{\"event\":\"stage\"}\n\n  \n{\"event\":\"done\"}"), FormatNDJSON)
+	for _, want := range []string{`{"event":"stage"}`, `{"event":"done"}`} {
+		got, err := r.Next()
+		if err != nil || string(got.Data) != want {
+			t.Fatalf("Next = %+v, %v; want %s", got, err, want)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Fatalf("Next = %v, want io.EOF", err)
+	}
+}
+
+func TestFormatFrameRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatSSE, FormatNDJSON} {
+		frame := format.Frame(42, "stage", []byte(`{"event":"stage"}`))
+		got, err := NewReader(strings.NewReader(string(frame)), format).Next()
+		if err != nil {
+			t.Fatalf("%s: %v", format.ContentType(), err)
+		}
+		if string(got.Data) != `{"event":"stage"}` {
+			t.Errorf("%s: data = %q", format.ContentType(), got.Data)
+		}
+		if format == FormatSSE && (got.ID != "42" || got.Event != "stage") {
+			t.Errorf("%s: frame = %+v", format.ContentType(), got)
+		}
+	}
+}
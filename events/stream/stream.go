@@ -7,6 +7,12 @@ import (
 	"time"
 )
 
+// number of recent messages kept per stream for Last-Event-ID replay
+const historySize = 64
+
+// how long a stream outlives its last subscriber, so a client can reconnect
+var inactiveTTL = 2 * time.Minute
+
 type StreamHub struct {
 	mu      sync.RWMutex
 	streams map[string]*Stream
@@ -14,7 +20,17 @@ type StreamHub struct {
 }
 
 type Stream struct {
-	subscribers map[chan []byte]struct{}
+	subscribers map[chan Message]struct{}
+	history     []Message
+	lastID      uint64
+}
+
+// Message is a published event tagged with its per-stream sequence number.
+// Data is the JSON payload; framing is left to the subscriber.
+type Message struct {
+	ID    uint64
+	Event string
+	Data  []byte
 }
 
 func NewStreamHub() *StreamHub {
@@ -24,20 +40,39 @@ func NewStreamHub() *StreamHub {
 	}
 }
 
+func newStream() *Stream {
+	return &Stream{
+		subscribers: make(map[chan Message]struct{}),
+	}
+}
+
 // Subscribe to a stream
-func (b *StreamHub) Subscribe(ctx context.Context, streamID string) (chan []byte, error) {
-	ch := make(chan []byte, 10)
+func (b *StreamHub) Subscribe(ctx context.Context, streamID string) (chan Message, error) {
+	ch, _, err := b.SubscribeFrom(ctx, streamID, 0)
+	return ch, err
+}
+
+// SubscribeFrom subscribes to a stream and also returns the retained messages
+// published after lastEventID, so a client that connects late or reconnects
+// sees the whole scan without gaps.
+func (b *StreamHub) SubscribeFrom(ctx context.Context, streamID string, lastEventID uint64) (chan Message, []Message, error) {
+	ch := make(chan Message, 10)
 
 	b.mu.Lock()
 	stream, exists := b.streams[streamID]
 	if !exists {
-		stream = &Stream{
-			subscribers: make(map[chan []byte]struct{}),
-		}
+		stream = newStream()
 		b.streams[streamID] = stream
 	}
 	stream.subscribers[ch] = struct{}{}
 
+	var backlog []Message
+	for _, msg := range stream.history {
+		if msg.ID > lastEventID {
+			backlog = append(backlog, msg)
+		}
+	}
+
 	// Cancel pending deletion if stream is re-used
 	if timer, exists := b.timers[streamID]; exists {
 		timer.Stop()
@@ -46,30 +81,47 @@ func (b *StreamHub) Subscribe(ctx context.Context, streamID string) (chan []byte
 	b.mu.Unlock()
 
 	log.Printf("[📥] Subscribed to stream: %s", streamID)
-	return ch, nil
+	return ch, backlog, nil
 }
 
-// Publish a message to all subscribers of a stream
-func (b *StreamHub) Publish(streamID string, data []byte) {
-	b.mu.RLock()
-	defer b.mu.RUnlock()
+// Publish an event to all subscribers of a stream
+func (b *StreamHub) Publish(streamID string, event string, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
 	stream, exists := b.streams[streamID]
 	if !exists {
 		return
 	}
 
+	stream.lastID++
+	msg := Message{ID: stream.lastID, Event: event, Data: data}
+	stream.history = append(stream.history, msg)
+	if len(stream.history) > historySize {
+		stream.history = stream.history[len(stream.history)-historySize:]
+	}
+
+	dropped := false
 	for ch := range stream.subscribers {
 		select {
-		case ch <- data:
+		case ch <- msg:
 		default:
-			// Drop if buffer is full
+			// A subscriber this far behind is closed rather than left to miss
+			// the message, which may be the one ending the scan. It can
+			// reconnect and replay from its Last-Event-ID.
+			close(ch)
+			delete(stream.subscribers, ch)
+			dropped = true
+			log.Printf("[⚠️] Stream %s: closed a subscriber that fell behind", streamID)
 		}
 	}
+	if dropped && len(stream.subscribers) == 0 {
+		b.expire(streamID, inactiveTTL)
+	}
 }
 
 // Unsubscribe a channel from a stream
-func (b *StreamHub) Unsubscribe(streamID string, target chan []byte) {
+func (b *StreamHub) Unsubscribe(streamID string, target chan Message) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
@@ -85,14 +137,32 @@ func (b *StreamHub) Unsubscribe(streamID string, target chan []byte) {
 
 	// Clean up stream if no subscribers remain
 	if len(stream.subscribers) == 0 {
-		b.timers[streamID] = time.AfterFunc(2*time.Minute, func() {
-			b.mu.Lock()
-			defer b.mu.Unlock()
+		b.expire(streamID, inactiveTTL)
+	}
+}
+
+// expire schedules the deletion of a stream, replacing any deletion already
+// scheduled. The stream survives if it has subscribers by then. The caller
+// holds b.mu.
+func (b *StreamHub) expire(streamID string, after time.Duration) {
+	if timer, exists := b.timers[streamID]; exists {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(after, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		// a timer stopped after it fired still runs; only the current one counts
+		if b.timers[streamID] != timer {
+			return
+		}
+		delete(b.timers, streamID)
+		if stream, exists := b.streams[streamID]; exists && len(stream.subscribers) == 0 {
 			delete(b.streams, streamID)
-			delete(b.timers, streamID)
 			log.Printf("[🗑️] Deleted inactive stream: %s", streamID)
-		})
-	}
+		}
+	})
+	b.timers[streamID] = timer
 }
 
 // ListStreams returns all currently active stream IDs
@@ -107,6 +177,29 @@ func (b *StreamHub) ListStreams() []string {
 	return ids
 }
 
+// StreamInfo describes an active stream for the admin API
+type StreamInfo struct {
+	ID          string `json:"id"`
+	Subscribers int    `json:"subscribers"`
+	LastEventID uint64 `json:"last_event_id"`
+}
+
+// Stats returns a snapshot of every active stream
+func (b *StreamHub) Stats() []StreamInfo {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	infos := make([]StreamInfo, 0, len(b.streams))
+	for streamID, stream := range b.streams {
+		infos = append(infos, StreamInfo{
+			ID:          streamID,
+			Subscribers: len(stream.subscribers),
+			LastEventID: stream.lastID,
+		})
+	}
+	return infos
+}
+
 // Exists checks if a stream exists
 func (b *StreamHub) Exists(streamID string) bool {
 	b.mu.RLock()
@@ -124,20 +217,10 @@ func (b *StreamHub) CreateTemporaryStream(streamID string, ttl time.Duration) {
 		return
 	}
 
-	b.streams[streamID] = &Stream{
-		subscribers: make(map[chan []byte]struct{}),
-	}
+	b.streams[streamID] = newStream()
+	// live subscribers keep the stream; Unsubscribe schedules its cleanup
+	b.expire(streamID, ttl)
 	b.mu.Unlock()
 
 	log.Printf("[🆕] Created temporary stream: %s (expires in %s)", streamID, ttl)
-
-	b.timers[streamID] = time.AfterFunc(ttl, func() {
-		b.mu.Lock()
-		defer b.mu.Unlock()
-		if _, exists := b.streams[streamID]; exists {
-			delete(b.streams, streamID)
-			delete(b.timers, streamID)
-			log.Printf("[🗑️] Automatically deleted expired stream: %s", streamID)
-		}
-	})
 }
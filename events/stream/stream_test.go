@@ -0,0 +1,89 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExpiryKeepsResubscribedStream(t *testing.T) {
+	ttl := inactiveTTL
+	inactiveTTL = 20 * time.Millisecond
+	defer func() { inactiveTTL = ttl }()
+
+	tests := []struct {
+		name  string
+		setup func(hub *StreamHub)
+	}{
+		{
+			name: "unsubscribed twice",
+			setup: func(hub *StreamHub) {
+				ch, _ := hub.Subscribe(context.Background(), "s1")
+				hub.Unsubscribe("s1", ch)
+				hub.Unsubscribe("s1", ch)
+			},
+		},
+		{
+			name: "temporary stream unsubscribed",
+			setup: func(hub *StreamHub) {
+				hub.CreateTemporaryStream("s1", inactiveTTL)
+				hub.Unsubscribe("s1", make(chan Message))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewStreamHub()
+			tt.setup(hub)
+			ch, _ := hub.Subscribe(context.Background(), "s1")
+
+			time.Sleep(5 * inactiveTTL)
+			if !hub.Exists("s1") {
+				t.Fatal("an earlier deletion removed a stream with a subscriber")
+			}
+			hub.Unsubscribe("s1", ch)
+			time.Sleep(5 * inactiveTTL)
+			if hub.Exists("s1") {
+				t.Fatal("stream kept after its last subscriber left")
+			}
+		})
+	}
+}
+
+func TestPublishClosesLaggingSubscriber(t *testing.T) {
+	hub := NewStreamHub()
+	slow, _ := hub.Subscribe(context.Background(), "s1")
+	fast, _ := hub.Subscribe(context.Background(), "s1")
+
+	const published = 12
+	received := 0
+	for i := 0; i < published; i++ {
+		event := "stage"
+		if i == published-1 {
+			event = "done"
+		}
+		hub.Publish("s1", event, []byte("{}"))
+		<-fast
+		received++
+	}
+	if received != published {
+		t.Fatalf("fast subscriber got %d of %d", received, published)
+	}
+
+	// the slow one gets what fit in its buffer, then a close instead of gaps
+	var last Message
+	n := 0
+	for msg := range slow {
+		last = msg
+		n++
+	}
+	if n == 0 || last.ID != uint64(n) {
+		t.Fatalf("slow subscriber got %d messages ending at %d, want an unbroken run", n, last.ID)
+	}
+
+	// and resumes where it stopped, terminal event included
+	_, backlog, _ := hub.SubscribeFrom(context.Background(), "s1", last.ID)
+	if len(backlog) != published-n || backlog[len(backlog)-1].Event != "done" {
+		t.Fatalf("backlog after %d = %d messages, want the remaining %d ending with done", last.ID, len(backlog), published-n)
+	}
+}
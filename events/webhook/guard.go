@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrDisallowedHost is a callback pointing into a private, loopback or
+// link-local network, which would let partners probe the service's own network
+var ErrDisallowedHost = errors.New("callback host is a private or local address")
+
+// shared address space (RFC 6598) and "this network", which the netip
+// predicates do not cover
+var disallowedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("0.0.0.0/8"),
+}
+
+const resolveTimeout = 5 * time.Second
+
+// publicAddr reports whether ip may receive callbacks
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range disallowedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateURL accepts absolute http(s) callback URLs, https only when the
+// dispatcher requires it, whose host resolves to public addresses only
+func (d *Dispatcher) ValidateURL(callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("callback url must be an absolute http(s) url")
+	}
+	if d.config.RequireHTTPS && u.Scheme != "https" {
+		return errors.New("callback url must use https")
+	}
+	if d.config.AllowPrivateHosts {
+		return nil
+	}
+
+	host := u.Hostname()
+	addrs := []netip.Addr{}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		addrs = append(addrs, ip)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+		defer cancel()
+		if addrs, err = d.lookup(ctx, host); err != nil || len(addrs) == 0 {
+			return fmt.Errorf("callback host %s cannot be resolved", host)
+		}
+	}
+	for _, ip := range addrs {
+		if !publicAddr(ip) {
+			return ErrDisallowedHost
+		}
+	}
+	return nil
+}
+
+// guardedTransport refuses connections to non-public addresses when they are
+// dialled, so a host that re-resolves after validation, or a redirect, cannot
+// reach them either
+func guardedTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !publicAddr(ip) {
+				return ErrDisallowedHost
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+func lookupHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+}
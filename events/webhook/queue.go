@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// Queue holds deliveries that have not been acknowledged yet. When dir is set every
+// pending delivery is mirrored to disk so retries survive a restart.
+type Queue struct {
+	mu      sync.Mutex
+	dir     string
+	pending map[string]*appschema.WebhookDelivery
+}
+
+func NewQueue(dir string) (*Queue, error) {
+	q := &Queue{
+		dir:     dir,
+		pending: make(map[string]*appschema.WebhookDelivery),
+	}
+	if dir == "" {
+		return q, nil
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "dead"), 0o755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			log.Printf("[webhook] Skipping unreadable queue entry %s: %v", entry.Name(), err)
+			continue
+		}
+		var delivery appschema.WebhookDelivery
+		if err := json.Unmarshal(data, &delivery); err != nil {
+			log.Printf("[webhook] Skipping corrupt queue entry %s: %v", entry.Name(), err)
+			continue
+		}
+		q.pending[delivery.ID] = &delivery
+	}
+	if len(q.pending) > 0 {
+		log.Printf("[webhook] Restored %d pending deliveries", len(q.pending))
+	}
+	return q, nil
+}
+
+// Put stores or updates a pending delivery
+func (q *Queue) Put(delivery *appschema.WebhookDelivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending[delivery.ID] = delivery
+	return q.write(delivery.ID, delivery)
+}
+
+// Remove drops an acknowledged delivery
+func (q *Queue) Remove(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.pending, id)
+	if q.dir != "" {
+		os.Remove(q.path(id))
+	}
+}
+
+// Bury moves a delivery that exhausted its attempts out of the active queue
+func (q *Queue) Bury(delivery *appschema.WebhookDelivery) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.pending, delivery.ID)
+	if q.dir == "" {
+		return
+	}
+	data, err := json.MarshalIndent(delivery, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(q.dir, "dead", delivery.ID+".json"), data, 0o644)
+	}
+	if err != nil {
+		log.Printf("[webhook] Failed to persist dead delivery %s: %v", delivery.ID, err)
+	}
+	os.Remove(q.path(delivery.ID))
+}
+
+// Due returns copies of the deliveries whose next attempt is at or before now
+func (q *Queue) Due(now time.Time) []*appschema.WebhookDelivery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	due := make([]*appschema.WebhookDelivery, 0)
+	for _, delivery := range q.pending {
+		if !delivery.NextAttemptAt.After(now) {
+			copied := *delivery
+			due = append(due, &copied)
+		}
+	}
+	return due
+}
+
+// Len returns the number of pending deliveries
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+func (q *Queue) path(id string) string {
+	return filepath.Join(q.dir, id+".json")
+}
+
+func (q *Queue) write(id string, delivery *appschema.WebhookDelivery) error {
+	if q.dir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(delivery, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := q.path(id) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path(id))
+}
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+func TestQueueSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().Truncate(time.Second)
+
+	queue, err := NewQueue(dir)
+	if err != nil {
+		t.Fatalf("NewQueue: %v", err)
+	}
+	deliveries := []*appschema.WebhookDelivery{
+		{ID: "due", URL: "https://partner.example/hook", StreamID: "s1", Attempts: 2, NextAttemptAt: now.Add(-time.Minute)},
+		{ID: "later", URL: "https://partner.example/hook", StreamID: "s2", NextAttemptAt: now.Add(time.Hour)},
+		{ID: "acked", URL: "https://partner.example/hook", StreamID: "s3", NextAttemptAt: now},
+		{ID: "dead", URL: "https://partner.example/hook", StreamID: "s4", Attempts: 6, NextAttemptAt: now},
+	}
+	for _, delivery := range deliveries {
+		if err := queue.Put(delivery); err != nil {
+			t.Fatalf("Put(%s): %v", delivery.ID, err)
+		}
+	}
+	queue.Remove("acked")
+	queue.Bury(deliveries[3])
+	os.WriteFile(filepath.Join(dir, "corrupt.json"), []byte("{"), 0o644)
+
+	restored, err := NewQueue(dir)
+	if err != nil {
+		t.Fatalf("NewQueue after restart: %v", err)
+	}
+	if restored.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", restored.Len())
+	}
+
+	tests := []struct {
+		at   time.Time
+		want []string
+	}{
+		{at: now, want: []string{"due"}},
+		{at: now.Add(2 * time.Hour), want: []string{"due", "later"}},
+		{at: now.Add(-2 * time.Minute), want: nil},
+	}
+	for _, tt := range tests {
+		due := restored.Due(tt.at)
+		got := map[string]*appschema.WebhookDelivery{}
+		for _, delivery := range due {
+			got[delivery.ID] = delivery
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Due(%s) returned %d deliveries, want %v", tt.at, len(got), tt.want)
+		}
+		for _, id := range tt.want {
+			if got[id] == nil {
+				t.Errorf("Due(%s) is missing %s", tt.at, id)
+			}
+		}
+	}
+
+	due := restored.Due(now)[0]
+	if due.Attempts != 2 || due.StreamID != "s1" || !due.NextAttemptAt.Equal(now.Add(-time.Minute)) {
+		t.Errorf("restored delivery = %+v", due)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dead", "dead.json")); err != nil {
+		t.Errorf("buried delivery not in dead/: %v", err)
+	}
+}
+
+func TestQueueDueReturnsCopies(t *testing.T) {
+	queue, _ := NewQueue("")
+	queue.Put(&appschema.WebhookDelivery{ID: "d1", NextAttemptAt: time.Now().Add(-time.Second)})
+
+	queue.Due(time.Now())[0].Attempts = 5
+	if got := queue.Due(time.Now())[0].Attempts; got != 0 {
+		t.Fatalf("Attempts = %d after mutating a copy, want 0", got)
+	}
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Registry keeps the default callback URL registered by each API client. When
+// path is set the registrations are mirrored to that file so they survive a
+// restart. They are never shared between instances, so a registry only suits
+// a single long-running instance.
+type Registry struct {
+	mu   sync.RWMutex
+	path string
+	urls map[string]string
+}
+
+func NewRegistry(path string) (*Registry, error) {
+	r := &Registry{path: path, urls: make(map[string]string)}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.urls); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Registry) Set(clientID, callbackURL string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, had := r.urls[clientID]
+	r.urls[clientID] = callbackURL
+	if err := r.write(); err != nil {
+		if had {
+			r.urls[clientID] = previous
+		} else {
+			delete(r.urls, clientID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *Registry) Get(clientID string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	callbackURL, ok := r.urls[clientID]
+	return callbackURL, ok
+}
+
+func (r *Registry) Delete(clientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, had := r.urls[clientID]
+	if !had {
+		return nil
+	}
+	delete(r.urls, clientID)
+	if err := r.write(); err != nil {
+		r.urls[clientID] = previous
+		return err
+	}
+	return nil
+}
+
+func (r *Registry) write() error {
+	if r.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(r.urls, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
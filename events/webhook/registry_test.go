@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistrySurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry", "clients.json")
+
+	registry, err := NewRegistry(path)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	for client, url := range map[string]string{"a": "https://a.example/hook", "b": "https://b.example/hook"} {
+		if err := registry.Set(client, url); err != nil {
+			t.Fatalf("Set(%s): %v", client, err)
+		}
+	}
+	if err := registry.Delete("b"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	restored, err := NewRegistry(path)
+	if err != nil {
+		t.Fatalf("NewRegistry after restart: %v", err)
+	}
+	tests := []struct {
+		client string
+		want   string
+		wantOK bool
+	}{
+		{client: "a", want: "https://a.example/hook", wantOK: true},
+		{client: "b"},
+	}
+	for _, tt := range tests {
+		if got, ok := restored.Get(tt.client); got != tt.want || ok != tt.wantOK {
+			t.Errorf("Get(%s) = %q, %v; want %q, %v", tt.client, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRegistryKeepsStateOnWriteError(t *testing.T) {
+	dir := t.TempDir()
+	registry, err := NewRegistry(filepath.Join(dir, "clients.json"))
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	if err := registry.Set("a", "https://a.example/hook"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// a directory where the temp file goes makes every write fail
+	if err := os.Mkdir(filepath.Join(dir, "clients.json.tmp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Set("a", "https://other.example/hook"); err == nil {
+		t.Fatal("Set succeeded")
+	}
+	if err := registry.Set("b", "https://b.example/hook"); err == nil {
+		t.Fatal("Set succeeded")
+	}
+	if err := registry.Delete("a"); err == nil {
+		t.Fatal("Delete succeeded")
+	}
+	if got, _ := registry.Get("a"); got != "https://a.example/hook" {
+		t.Errorf("Get(a) = %q after failed writes", got)
+	}
+	if _, ok := registry.Get("b"); ok {
+		t.Error("failed Set kept b")
+	}
+}
+
+func TestRegistryRejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clients.json")
+	os.WriteFile(path, []byte("{"), 0o644)
+	if _, err := NewRegistry(path); err == nil {
+		t.Fatal("NewRegistry loaded a corrupt file")
+	}
+}
@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+const (
+	HeaderSignature  = "X-Webhook-Signature"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	HeaderDeliveryID = "X-Webhook-Delivery-Id"
+	HeaderEvent      = "X-Webhook-Event"
+)
+
+type Config struct {
+	Secret       string
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	PollInterval time.Duration
+	// RequireHTTPS refuses plain http callbacks
+	RequireHTTPS bool
+	// AllowPrivateHosts lets callbacks reach private and local addresses, for
+	// development receivers only
+	AllowPrivateHosts bool
+}
+
+// Dispatcher posts terminal scan events to partner callbacks and retries failed
+// deliveries with exponential backoff from the queue.
+type Dispatcher struct {
+	config   Config
+	client   *http.Client
+	queue    *Queue
+	inflight sync.Map
+	lookup   func(ctx context.Context, host string) ([]netip.Addr, error)
+}
+
+// NewDispatcher delivers through client when set. The default client refuses to
+// connect to private and local addresses unless config allows them.
+func NewDispatcher(config Config, client *http.Client, queue *Queue) *Dispatcher {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 6
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = 2 * time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 5 * time.Minute
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+		if !config.AllowPrivateHosts {
+			client.Transport = guardedTransport()
+		}
+	}
+	return &Dispatcher{config: config, client: client, queue: queue, lookup: lookupHost}
+}
+
+// Start runs the retry loop until ctx is cancelled
+func (d *Dispatcher) Start(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, delivery := range d.queue.Due(now) {
+				go d.attempt(ctx, delivery)
+			}
+		}
+	}
+}
+
+// Dispatch queues a delivery of event to callbackURL and makes the first attempt
+// right away. It returns the delivery ID.
+func (d *Dispatcher) Dispatch(callbackURL, streamID string, event appschema.EventMessage) (string, error) {
+	if err := d.ValidateURL(callbackURL); err != nil {
+		return "", err
+	}
+
+	event.StreamID = streamID
+	now := time.Now()
+	delivery := &appschema.WebhookDelivery{
+		ID:       uuid.NewString(),
+		URL:      callbackURL,
+		StreamID: streamID,
+		Event:    event,
+		// the poller only picks this up if the immediate attempt below never finishes
+		NextAttemptAt: now.Add(d.config.BaseBackoff),
+		CreatedAt:     now,
+	}
+	if err := d.queue.Put(delivery); err != nil {
+		return "", fmt.Errorf("failed to queue webhook delivery: %w", err)
+	}
+
+	copied := *delivery
+	go d.attempt(context.Background(), &copied)
+	return delivery.ID, nil
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *appschema.WebhookDelivery) {
+	// the poller and Dispatch may both pick up a fresh delivery
+	if _, busy := d.inflight.LoadOrStore(delivery.ID, struct{}{}); busy {
+		return
+	}
+	defer d.inflight.Delete(delivery.ID)
+
+	delivery.Attempts++
+	err := d.send(ctx, delivery)
+	if err == nil {
+		d.queue.Remove(delivery.ID)
+		log.Printf("[webhook] Delivered %s for stream %s (attempt %d)", delivery.ID, delivery.StreamID, delivery.Attempts)
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.config.MaxAttempts {
+		log.Printf("[webhook] Giving up on %s after %d attempts: %v", delivery.ID, delivery.Attempts, err)
+		d.queue.Bury(delivery)
+		return
+	}
+
+	delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempts))
+	log.Printf("[webhook] Delivery %s failed (attempt %d), retrying at %s: %v", delivery.ID, delivery.Attempts, delivery.NextAttemptAt.Format(time.RFC3339), err)
+	if err := d.queue.Put(delivery); err != nil {
+		log.Printf("[webhook] Failed to requeue %s: %v", delivery.ID, err)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *appschema.WebhookDelivery) error {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDeliveryID, delivery.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderEvent, delivery.Event.Event)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(d.config.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver responded with %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.config.BaseBackoff << (attempts - 1)
+	if wait <= 0 || wait > d.config.MaxBackoff {
+		return d.config.MaxBackoff
+	}
+	return wait
+}
+
+// Sign computes the hex HMAC-SHA256 of "<timestamp>.<body>" that receivers compare
+// against the X-Webhook-Signature header.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header value produced by Sign
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	expected := "sha256=" + Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,250 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"event":"done"}`)
+	signature := "sha256=" + Sign("secret", "1700000000", body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{name: "matching", secret: "secret", timestamp: "1700000000", body: body, signature: signature, want: true},
+		{name: "wrong secret", secret: "other", timestamp: "1700000000", body: body, signature: signature},
+		{name: "replayed timestamp", secret: "secret", timestamp: "1700000001", body: body, signature: signature},
+		{name: "tampered body", secret: "secret", timestamp: "1700000000", body: []byte(`{"event":"error"}`), signature: signature},
+		{name: "missing prefix", secret: "secret", timestamp: "1700000000", body: body, signature: Sign("secret", "1700000000", body)},
+		{name: "empty", secret: "secret", timestamp: "1700000000", body: body},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.timestamp, tt.body, tt.signature); got != tt.want {
+				t.Fatalf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatchHeaders(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{header: r.Header.Clone(), body: body}
+	}))
+	defer srv.Close()
+
+	queue, _ := NewQueue("")
+	d := NewDispatcher(Config{Secret: "secret", AllowPrivateHosts: true}, srv.Client(), queue)
+	id, err := d.Dispatch(srv.URL, "stream-1", appschema.EventMessage{Event: "done", Message: "ok"})
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	var r received
+	select {
+	case r = <-got:
+	case <-time.After(5 * time.Second):
+		t.Fatal("receiver was never called")
+	}
+
+	if r.header.Get(HeaderDeliveryID) != id {
+		t.Errorf("%s = %q, want %q", HeaderDeliveryID, r.header.Get(HeaderDeliveryID), id)
+	}
+	if r.header.Get(HeaderEvent) != "done" {
+		t.Errorf("%s = %q, want done", HeaderEvent, r.header.Get(HeaderEvent))
+	}
+	timestamp := r.header.Get(HeaderTimestamp)
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+		t.Errorf("%s = %q, want the current unix time", HeaderTimestamp, timestamp)
+	}
+	if !Verify("secret", timestamp, r.body, r.header.Get(HeaderSignature)) {
+		t.Errorf("%s = %q does not verify", HeaderSignature, r.header.Get(HeaderSignature))
+	}
+
+	var event appschema.EventMessage
+	if err := json.Unmarshal(r.body, &event); err != nil || event.StreamID != "stream-1" {
+		t.Errorf("body = %s, want the event for stream-1", r.body)
+	}
+	waitFor(t, func() bool { return queue.Len() == 0 })
+}
+
+func TestDispatchRetriesUntilLimit(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []time.Time
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, time.Now())
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	queue, err := NewQueue(dir)
+	if err != nil {
+		t.Fatalf("NewQueue: %v", err)
+	}
+	d := NewDispatcher(Config{
+		Secret:            "secret",
+		MaxAttempts:       3,
+		BaseBackoff:       40 * time.Millisecond,
+		MaxBackoff:        time.Second,
+		PollInterval:      5 * time.Millisecond,
+		AllowPrivateHosts: true,
+	}, srv.Client(), queue)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Start(ctx)
+
+	id, err := d.Dispatch(srv.URL, "stream-1", appschema.EventMessage{Event: "error"})
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	waitFor(t, func() bool { return queue.Len() == 0 })
+
+	// give a runaway retry the chance to show up
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 3 {
+		t.Fatalf("receiver called %d times, want 3", len(calls))
+	}
+	for i, want := range []time.Duration{40 * time.Millisecond, 80 * time.Millisecond} {
+		if gap := calls[i+1].Sub(calls[i]); gap < want {
+			t.Errorf("retry %d came after %s, want at least %s", i+1, gap, want)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "dead", id+".json"))
+	if err != nil {
+		t.Fatalf("dead delivery not persisted: %v", err)
+	}
+	var dead appschema.WebhookDelivery
+	if err := json.Unmarshal(data, &dead); err != nil || dead.Attempts != 3 || dead.LastError == "" {
+		t.Fatalf("dead delivery = %s", data)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(Config{BaseBackoff: time.Second, MaxBackoff: 10 * time.Second}, nil, nil)
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 4, want: 8 * time.Second},
+		{attempts: 5, want: 10 * time.Second},
+		{attempts: 80, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	lookup := func(_ context.Context, host string) ([]netip.Addr, error) {
+		switch host {
+		case "partner.example":
+			return []netip.Addr{netip.MustParseAddr("93.184.216.34")}, nil
+		case "internal.example":
+			return []netip.Addr{netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("10.0.0.7")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	tests := []struct {
+		name    string
+		config  Config
+		url     string
+		wantErr bool
+	}{
+		{name: "public ip", url: "http://93.184.216.34/hook"},
+		{name: "public host", url: "https://partner.example/hook"},
+		{name: "relative", url: "/hook", wantErr: true},
+		{name: "other scheme", url: "ftp://partner.example/hook", wantErr: true},
+		{name: "loopback", url: "http://127.0.0.1:8080/hook", wantErr: true},
+		{name: "localhost ipv6", url: "http://[::1]/hook", wantErr: true},
+		{name: "mapped loopback", url: "http://[::ffff:127.0.0.1]/hook", wantErr: true},
+		{name: "rfc1918", url: "http://10.1.2.3/hook", wantErr: true},
+		{name: "rfc1918 172", url: "http://172.16.0.1/hook", wantErr: true},
+		{name: "rfc1918 192", url: "http://192.168.1.1/hook", wantErr: true},
+		{name: "metadata", url: "http://169.254.169.254/latest/meta-data", wantErr: true},
+		{name: "unspecified", url: "http://0.0.0.0/hook", wantErr: true},
+		{name: "carrier nat", url: "http://100.64.0.1/hook", wantErr: true},
+		{name: "host with a private address", url: "https://internal.example/hook", wantErr: true},
+		{name: "unresolvable", url: "https://nowhere.example/hook", wantErr: true},
+		{name: "http when https is required", config: Config{RequireHTTPS: true}, url: "http://partner.example/hook", wantErr: true},
+		{name: "https when required", config: Config{RequireHTTPS: true}, url: "https://partner.example/hook"},
+		{name: "private allowed", config: Config{AllowPrivateHosts: true}, url: "http://127.0.0.1:8080/hook"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDispatcher(tt.config, nil, nil)
+			d.lookup = lookup
+			if err := d.ValidateURL(tt.url); (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateURL(%q) = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGuardedTransportRefusesLoopback(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	queue, _ := NewQueue("")
+	d := NewDispatcher(Config{Secret: "secret"}, nil, queue)
+	err := d.send(context.Background(), &appschema.WebhookDelivery{ID: "d1", URL: srv.URL})
+	if !errors.Is(err, ErrDisallowedHost) {
+		t.Fatalf("send = %v, want %v", err, ErrDisallowedHost)
+	}
+	if called {
+		t.Fatal("receiver on loopback was reached")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -2,15 +2,45 @@ package globals
 
 import (
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/muthu-kumar-u/go-sse/cache"
+	"github.com/muthu-kumar-u/go-sse/events/broker"
 	"github.com/muthu-kumar-u/go-sse/events/stream"
+	"github.com/muthu-kumar-u/go-sse/events/webhook"
+	"github.com/muthu-kumar-u/go-sse/history"
+	"github.com/muthu-kumar-u/go-sse/jobs"
 	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/storage"
+	"github.com/muthu-kumar-u/go-sse/upstream"
 )
 
 var AWSSession *session.Session
 var UserService *appschema.ServiceConnection
 var FaceAnalyzeService *appschema.ServiceConnection
+// FaceAnalyzeUpstream wraps FaceAnalyzeService's client with retries and a breaker
+var FaceAnalyzeUpstream *upstream.Client
 var RequestStore appschema.RequestStore
 
+// webhooks
+var Webhooks *webhook.Dispatcher
+var WebhookRegistry *webhook.Registry
+
+// job records
+var JobTracker *jobs.Tracker
+
+// ImageStore keeps uploaded images; nil when no bucket is configured
+var ImageStore storage.Store
+
+// AnnotationStore keeps annotated result images; nil when annotation is off
+var AnnotationStore storage.Store
+
+// ScanHistory keeps users' completed scans; nil when disabled
+var ScanHistory history.Store
+
+// ResultCache reuses analysis results for repeated images; nil when disabled
+var ResultCache *cache.Cache
+
 // prod
 var Stream *stream.StreamHub
-// var Stream *sse.Server
\ No newline at end of file
+// EventStore mirrors published events outside the process when configured
+var EventStore broker.Broker
+// var Stream *sse.Server
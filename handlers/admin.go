@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/jobs"
+	"github.com/muthu-kumar-u/go-sse/message"
+)
+
+type AdminHandler struct {
+	Jobs *jobs.Pool
+}
+
+func NewAdminHandler(pool *jobs.Pool) *AdminHandler {
+	return &AdminHandler{Jobs: pool}
+}
+
+// ListStreams returns the streams currently held by the hub
+func (h *AdminHandler) ListStreams(c *gin.Context) {
+	streams := globals.Stream.Stats()
+	sort.Slice(streams, func(i, j int) bool { return streams[i].ID < streams[j].ID })
+
+	c.JSON(http.StatusOK, message.ReturnCustomDataWithKey("streams", streams))
+}
+
+// JobStats reports worker pool utilisation
+func (h *AdminHandler) JobStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Jobs.Stats())
+}
+
+// CacheStats reports result cache usage
+func (h *AdminHandler) CacheStats(c *gin.Context) {
+	if globals.ResultCache == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "stats": globals.ResultCache.Stats()})
+}
+
+// UpstreamStats reports retry counters and circuit breaker state for the face
+// analyze service
+func (h *AdminHandler) UpstreamStats(c *gin.Context) {
+	if globals.FaceAnalyzeUpstream == nil {
+		c.JSON(http.StatusOK, gin.H{"face_analyze": "unavailable"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"face_analyze": globals.FaceAnalyzeUpstream.Stats()})
+}
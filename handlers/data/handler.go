@@ -1,14 +1,27 @@
 package handlers
 
 import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	constants "github.com/muthu-kumar-u/go-sse/const"
 	userController "github.com/muthu-kumar-u/go-sse/controller/user"
 	"github.com/muthu-kumar-u/go-sse/globals"
 	"github.com/muthu-kumar-u/go-sse/handlers"
+	"github.com/muthu-kumar-u/go-sse/pipeline"
 	"github.com/muthu-kumar-u/go-sse/services"
+	"github.com/muthu-kumar-u/go-sse/utils"
 )
 
 type AppHandlers struct {
 	StreamHandler   *handlers.StreamHandler
+	WebhookHandler  *handlers.WebhookHandler
+	AdminHandler    *handlers.AdminHandler
+	JobHandler      *handlers.JobHandler
+	HistoryHandler  *handlers.HistoryHandler
+	HealthHandler   *handlers.HealthHandler
 }
 
 func LoadAppHandlers() *AppHandlers {
@@ -17,7 +30,63 @@ func LoadAppHandlers() *AppHandlers {
 	userService := services.NewUserService(userController)
 
 
+	// face scan
+	imageLimits := utils.ImageLimitsConfig()
+	streamHandler := handlers.NewFaceAnalyzeHandler(userService, utils.JobPoolConfig(), imageLimits, loadPipelines(imageLimits))
+
 	return &AppHandlers{
-		StreamHandler:   handlers.NewFaceAnalyzeHandler(userService),
+		StreamHandler:   streamHandler,
+		WebhookHandler:  handlers.NewWebhookHandler(),
+		AdminHandler:    handlers.NewAdminHandler(streamHandler.Jobs),
+		JobHandler:      handlers.NewJobHandler(streamHandler.Jobs),
+		HistoryHandler:  handlers.NewHistoryHandler(),
+		HealthHandler:   handlers.NewHealthHandler(),
+	}
+}
+
+// loadPipelines registers the face analysis plus any configured in ANALYZERS;
+// they all run on the face analyze service. Bumping ANALYZER_VERSION when the
+// service's model changes retires cached results. With an image store and
+// ANALYZER_IMAGE_URL_TTL set, the service gets presigned links instead of bytes.
+// Annotated results are linked through the job API.
+func loadPipelines(imageLimits utils.ImageLimits) *pipeline.Registry {
+	version := os.Getenv("ANALYZER_VERSION")
+	if version == "" {
+		version = "1"
+	}
+	opts := pipeline.Options{
+		Limits:     imageLimits,
+		Preprocess: utils.PreprocessConfigFromEnv(),
+		Weights:    utils.PipelineWeights(),
+		Version:    version,
+		Cache:      globals.ResultCache,
+	}
+	if globals.ImageStore != nil {
+		opts.Storage, opts.StoragePrefix = globals.ImageStore, utils.ImageStorePrefix()
+	}
+	if globals.AnnotationStore != nil {
+		opts.Annotations, opts.StoragePrefix = globals.AnnotationStore, utils.ImageStorePrefix()
+		opts.AnnotationURL = func(jobID string, index int) string {
+			return fmt.Sprintf("%s/jobs/%s/annotated?image=%d", path.Join("/api", os.Getenv("APP_VERSION")), jobID, index)
+		}
+	}
+	urlTTL, _ := time.ParseDuration(os.Getenv("ANALYZER_IMAGE_URL_TTL"))
+	analyzer := func(path string) pipeline.Analyzer {
+		return &pipeline.HTTPAnalyzer{
+			Client:  globals.FaceAnalyzeUpstream,
+			URL:     fmt.Sprintf("%s/%s", globals.FaceAnalyzeService.URL, path),
+			AuthKey: os.Getenv("FACEANALYZE_SERVICE_AUTH_API_KEY"),
+			Field:   constants.FACE_ANALYZE_PAYLOAD_FIELD_NAME,
+			// presigned links need a store to sign against
+			Store:      opts.Storage,
+			PresignTTL: urlTTL,
+		}
+	}
+
+	registry := pipeline.NewRegistry()
+	registry.Register(pipeline.New(pipeline.DefaultAnalysis, analyzer(constants.FACE_ANALYZE_SERVICE_PATHS[0]), opts))
+	for name, path := range utils.AnalyzerPaths() {
+		registry.Register(pipeline.New(name, analyzer(path), opts))
 	}
+	return registry
 }
\ No newline at end of file
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	constants "github.com/muthu-kumar-u/go-sse/const"
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/jobs"
+	"github.com/muthu-kumar-u/go-sse/message"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/pipeline"
+	"github.com/muthu-kumar-u/go-sse/storage"
+	"github.com/muthu-kumar-u/go-sse/utils"
+)
+
+// how long a presigned direct upload URL stays valid
+const directUploadTTL = 15 * time.Minute
+
+// CreateUploadURL reserves a job for one image the client puts straight into
+// object storage, bypassing request body limits. The scan starts once the
+// object lands: on an S3 event in Lambda mode or a call to FinalizeUpload.
+func (h *StreamHandler) CreateUploadURL(c *gin.Context) {
+	if globals.ImageStore == nil {
+		c.JSON(http.StatusNotImplemented, message.ReturnCustomMessage("direct uploads need an image store"))
+		return
+	}
+	ownerId, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+		return
+	}
+
+	var body appschema.DirectUploadRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, message.ReturnInvalidFieldMsg())
+		return
+	}
+	if body.Analysis == "" {
+		body.Analysis = pipeline.DefaultAnalysis
+	}
+	if _, ok := h.Pipelines.Get(body.Analysis); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown analysis"})
+		return
+	}
+	contentType, ok := constants.IMAGE_CONTENT_TYPES[strings.ToLower(filepath.Ext(body.Filename))]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error_code": faceanalyze_events.ErrCodeUnsupportedType, "message": "Only jpg, jpeg, png allowed"})
+		return
+	}
+
+	// completion callback: per request url wins over the client's registered one
+	callbackURL := body.CallbackURL
+	if callbackURL == "" {
+		callbackURL = c.Query("callback_url")
+	}
+	if callbackURL != "" {
+		if globals.Webhooks == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error_code": faceanalyze_events.ErrCodeInvalidCallback, "message": "Webhooks are disabled"})
+			return
+		}
+		if err := globals.Webhooks.ValidateURL(callbackURL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error_code": faceanalyze_events.ErrCodeInvalidCallback, "message": "Invalid callback url"})
+			return
+		}
+	} else {
+		callbackURL = registeredCallback(ownerId)
+	}
+
+	streamId := c.Query("stream")
+	if streamId == "" {
+		streamId = uuid.NewString()
+	}
+	job := &appschema.FaceScanJob{
+		ID:          uuid.NewString(),
+		StreamID:    streamId,
+		OwnerID:     ownerId,
+		CallbackURL: callbackURL,
+		Analysis:    body.Analysis,
+		CreatedAt:   time.Now(),
+	}
+	key := storage.ImageKey(utils.ImageStorePrefix(), ownerId, job.ID, 0, storage.KindOriginal, storage.ImageExt(contentType))
+
+	url, signed, err := globals.ImageStore.PresignPut(key, contentType, directUploadTTL)
+	if err != nil {
+		log.Printf("[direct] Job %s: presign failed: %v", job.ID, err)
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+	if err := globals.JobTracker.Reserve(c.Request.Context(), job, body.Filename); err != nil {
+		log.Printf("[direct] Job %s: failed to record job: %v", job.ID, err)
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+	globals.Stream.CreateTemporaryStream(streamId, streamRetention+directUploadTTL)
+
+	headers := make(map[string]string, len(signed))
+	for name, values := range signed {
+		headers[name] = strings.Join(values, ",")
+	}
+	c.JSON(http.StatusCreated, appschema.DirectUpload{
+		JobID:     job.ID,
+		StreamID:  streamId,
+		Key:       key,
+		URL:       url,
+		Method:    http.MethodPut,
+		Headers:   headers,
+		ExpiresAt: job.CreatedAt.Add(directUploadTTL),
+	})
+}
+
+// FinalizeUpload starts the scan of a direct upload once the client has put
+// the image. Streaming clients, and every client in Lambda mode, get the scan's
+// events in the response, which stays open until it finishes.
+func (h *StreamHandler) FinalizeUpload(c *gin.Context) {
+	ownerId, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+		return
+	}
+
+	record, err := globals.JobTracker.Store.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, jobs.ErrNotFound) || (err == nil && record.OwnerID != ownerId) {
+		c.JSON(http.StatusNotFound, message.ReturnCustomMessage("job not found"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+
+	job, stageErr := h.startDirectUpload(c.Request.Context(), record)
+	if stageErr != nil {
+		c.JSON(stageErr.Status, gin.H{"error_code": stageErr.Code, "message": stageErr.Message})
+		return
+	}
+
+	if format, inlineRequested := stream.NegotiateFormat(c.GetHeader("Accept")); inlineRequested || h.InlineByDefault {
+		h.finalizeInline(c, job, record.Filename, format)
+		return
+	}
+	if err := h.Jobs.Enqueue(job); err != nil {
+		log.Printf("[direct] Job %s: enqueue failed: %v", job.ID, err)
+		h.failDirectUpload(job, record.Filename, http.StatusServiceUnavailable, faceanalyze_events.ErrCodeQueueFull, "Scan queue is full, retry later")
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error_code": faceanalyze_events.ErrCodeQueueFull, "message": "Scan queue is full, retry later"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "face scan queued",
+		"job_id":  job.ID,
+		"stream":  job.StreamID,
+		"images":  1,
+	})
+}
+
+// finalizeInline queues a started direct upload and relays its events in the
+// response until the last one. In Lambda mode nothing runs once the invocation
+// has answered, so the scan must finish within the request.
+func (h *StreamHandler) finalizeInline(c *gin.Context, job *appschema.FaceScanJob, filename string, format stream.Format) {
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	inline := newEventWriter(c.Writer, format)
+	defer inline.Close()
+	stopHeartbeat := inline.KeepAlive(15 * time.Second)
+	defer stopHeartbeat()
+
+	finished := make(chan struct{})
+	var once sync.Once
+	h.inline.Store(job.ID, pipeline.SinkFunc(func(event *appschema.EventMessage) error {
+		err := inline.Emit(event)
+		if isTerminalEvent(event.Event) {
+			once.Do(func() { close(finished) })
+		}
+		return err
+	}))
+	defer h.inline.Delete(job.ID)
+
+	ready, _ := json.Marshal(&appschema.EventMessage{Code: http.StatusOK, Event: faceanalyze_events.EventReady, StreamID: job.StreamID})
+	inline.WriteEvent(0, faceanalyze_events.EventReady, ready)
+	inline.Emit(&appschema.EventMessage{
+		Code:    http.StatusAccepted,
+		Event:   faceanalyze_events.EventQueued,
+		Message: "Scan queued",
+		Data:    gin.H{"job_id": job.ID, "images": 1},
+	})
+
+	if err := h.Jobs.Enqueue(job); err != nil {
+		log.Printf("[direct] Job %s: enqueue failed: %v", job.ID, err)
+		h.failDirectUpload(job, filename, http.StatusServiceUnavailable, faceanalyze_events.ErrCodeQueueFull, "Scan queue is full, retry later")
+		return
+	}
+
+	select {
+	case <-c.Request.Context().Done():
+	case <-finished:
+	}
+}
+
+// HandleObjectCreated starts the scan of the direct upload stored at key and
+// runs it to completion, as a storage event invocation must. Keys that are not
+// a pending direct upload are ignored.
+func (h *StreamHandler) HandleObjectCreated(ctx context.Context, key string) error {
+	parts, ok := storage.ParseImageKey(utils.ImageStorePrefix(), key)
+	if !ok || parts.Kind != storage.KindOriginal {
+		return nil
+	}
+	record, err := globals.JobTracker.Store.Get(ctx, parts.JobID)
+	if errors.Is(err, jobs.ErrNotFound) || (err == nil && record.OwnerID != parts.OwnerID) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if record.Status != appschema.JobAwaitingUpload {
+		return nil
+	}
+
+	job, stageErr := h.startDirectUpload(ctx, record)
+	if stageErr != nil {
+		return stageErr
+	}
+
+	h.Jobs.Run(ctx, job)
+	return nil
+}
+
+// startDirectUpload checks a reserved job's object and, when it holds a valid
+// image, marks the job queued and returns it ready to run. A missing object
+// leaves the job waiting; anything else fails it. The job store decides which
+// of several concurrent callers starts the job.
+func (h *StreamHandler) startDirectUpload(ctx context.Context, record *appschema.JobRecord) (*appschema.FaceScanJob, *pipeline.Error) {
+	if record.Status != appschema.JobAwaitingUpload {
+		return nil, &pipeline.Error{Status: http.StatusConflict, Code: faceanalyze_events.ErrCodeUploadStarted, Message: "Job is not awaiting an upload"}
+	}
+	p, ok := h.Pipelines.Get(record.Analysis)
+	if !ok {
+		return nil, &pipeline.Error{Status: http.StatusInternalServerError, Code: faceanalyze_events.ErrCodeInternal, Message: "Internal error"}
+	}
+	contentType := constants.IMAGE_CONTENT_TYPES[strings.ToLower(filepath.Ext(record.Filename))]
+	key := storage.ImageKey(utils.ImageStorePrefix(), record.OwnerID, record.ID, 0, storage.KindOriginal, storage.ImageExt(contentType))
+
+	job := &appschema.FaceScanJob{
+		ID:          record.ID,
+		StreamID:    record.StreamID,
+		OwnerID:     record.OwnerID,
+		CallbackURL: record.CallbackURL,
+		Analysis:    record.Analysis,
+		CreatedAt:   record.CreatedAt,
+	}
+
+	size, err := globals.ImageStore.Size(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, &pipeline.Error{Status: http.StatusConflict, Code: faceanalyze_events.ErrCodeUploadMissing, Message: "Image has not been uploaded yet"}
+	}
+	if err != nil {
+		log.Printf("[direct] Job %s: head failed: %v", record.ID, err)
+		return nil, &pipeline.Error{Status: http.StatusBadGateway, Code: faceanalyze_events.ErrCodeInternal, Message: "Failed to check upload"}
+	}
+	// a finalize call may race the storage event, on this instance or another;
+	// whichever moves the job on first starts it
+	err = globals.JobTracker.Store.Transition(ctx, record.ID, appschema.JobAwaitingUpload, appschema.JobQueued)
+	if errors.Is(err, jobs.ErrStatusChanged) {
+		return nil, &pipeline.Error{Status: http.StatusConflict, Code: faceanalyze_events.ErrCodeUploadStarted, Message: "Job is already starting"}
+	}
+	if err != nil {
+		log.Printf("[direct] Job %s: start failed: %v", record.ID, err)
+		return nil, &pipeline.Error{Status: http.StatusInternalServerError, Code: faceanalyze_events.ErrCodeInternal, Message: "Internal error"}
+	}
+	if size > h.ImageLimits.MaxBytes {
+		return nil, h.failDirectUpload(job, record.Filename, http.StatusRequestEntityTooLarge, faceanalyze_events.ErrCodeFileTooLarge,
+			fmt.Sprintf("Image is larger than %d bytes", h.ImageLimits.MaxBytes))
+	}
+
+	data, err := globals.ImageStore.Get(ctx, key, h.ImageLimits.MaxBytes)
+	if err != nil {
+		log.Printf("[direct] Job %s: download failed: %v", record.ID, err)
+		// left for a retry
+		globals.JobTracker.Store.Transition(ctx, record.ID, appschema.JobQueued, appschema.JobAwaitingUpload)
+		return nil, &pipeline.Error{Status: http.StatusBadGateway, Code: faceanalyze_events.ErrCodeInternal, Message: "Failed to read upload"}
+	}
+	image := &pipeline.Image{Filename: record.Filename, Data: data}
+	if err := p.Check(ctx, image); err != nil {
+		var stageErr *pipeline.Error
+		errors.As(err, &stageErr)
+		return nil, h.failDirectUpload(job, record.Filename, stageErr.Status, stageErr.Code, stageErr.Message)
+	}
+	job.Images = []appschema.JobImage{{Filename: record.Filename, ContentType: image.ContentType, Data: data, ObjectKey: key}}
+
+	// queued is recorded before a worker can report progress on the job
+	h.jobSink(job).Emit(&appschema.EventMessage{
+		Code:    http.StatusAccepted,
+		Event:   faceanalyze_events.EventQueued,
+		Message: "Scan queued",
+		Data:    gin.H{"job_id": job.ID, "images": 1},
+	})
+	return job, nil
+}
+
+// failDirectUpload ends a direct upload's job with an error on its record and
+// stream
+func (h *StreamHandler) failDirectUpload(job *appschema.FaceScanJob, filename string, status int, code, msg string) *pipeline.Error {
+	index := 0
+	h.jobSink(job).Emit(&appschema.EventMessage{
+		Code:       status,
+		Event:      faceanalyze_events.EventError,
+		ErrorCode:  code,
+		Message:    msg,
+		ImageIndex: &index,
+		Filename:   filename,
+		Completion: 100,
+	})
+	return &pipeline.Error{Status: status, Code: code, Message: msg}
+}
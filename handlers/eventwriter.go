@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// eventWriter streams framed events straight into a response body. It is safe for
+// the heartbeat goroutine and the pipeline to write concurrently.
+type eventWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format stream.Format
+	closed bool
+}
+
+func newEventWriter(w io.Writer, format stream.Format) *eventWriter {
+	return &eventWriter{w: w, format: format}
+}
+
+func (e *eventWriter) WriteEvent(id uint64, event string, data []byte) error {
+	return e.write(e.format.Frame(id, event, data))
+}
+
+// Emit makes the writer a pipeline sink
+func (e *eventWriter) Emit(event *appschema.EventMessage) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return e.WriteEvent(0, event.Event, data)
+}
+
+func (e *eventWriter) Heartbeat() error {
+	return e.write(e.format.Heartbeat())
+}
+
+// Close stops further writes; the underlying writer is left to its owner
+func (e *eventWriter) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closed = true
+}
+
+// KeepAlive writes heartbeats every interval until the returned stop is called
+func (e *eventWriter) KeepAlive(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := e.Heartbeat(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func (e *eventWriter) write(frame []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return io.ErrClosedPipe
+	}
+	if _, err := e.w.Write(frame); err != nil {
+		return err
+	}
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+func TestEventWriterEmit(t *testing.T) {
+	tests := []struct {
+		name   string
+		format stream.Format
+		want   string
+	}{
+		{name: "sse", format: stream.FormatSSE, want: "event: done\ndata: {\"code\":200,\"event\":\"done\""},
+		{name: "ndjson", format: stream.FormatNDJSON, want: "{\"code\":200,\"event\":\"done\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := newEventWriter(&buf, tt.format)
+			if err := w.Emit(&appschema.EventMessage{Code: 200, Event: "done"}); err != nil {
+				t.Fatalf("Emit: %v", err)
+			}
+			if !strings.HasPrefix(buf.String(), tt.want) {
+				t.Fatalf("wrote %q, want it to start with %q", buf.String(), tt.want)
+			}
+
+			// nothing reaches the body once closed
+			w.Close()
+			written := buf.Len()
+			if err := w.Emit(&appschema.EventMessage{Event: "late"}); err != io.ErrClosedPipe {
+				t.Fatalf("Emit after Close = %v, want io.ErrClosedPipe", err)
+			}
+			if buf.Len() != written {
+				t.Fatalf("wrote %q after Close", buf.String()[written:])
+			}
+		})
+	}
+}
+
+// chanWriter hands each write to the test, which the heartbeat makes from its own goroutine
+type chanWriter struct {
+	writes chan string
+}
+
+func (b *chanWriter) Write(p []byte) (int, error) {
+	b.writes <- string(p)
+	return len(p), nil
+}
+
+func TestEventWriterKeepAlive(t *testing.T) {
+	buf := &chanWriter{writes: make(chan string, 16)}
+	w := newEventWriter(buf, stream.FormatNDJSON)
+	stop := w.KeepAlive(time.Millisecond)
+
+	select {
+	case got := <-buf.writes:
+		if got != "\n" {
+			t.Fatalf("heartbeat = %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no heartbeat")
+	}
+	stop()
+	// stopping twice is harmless
+	stop()
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	"github.com/muthu-kumar-u/go-sse/jobs"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/pipeline"
+)
+
+// cancelQueuedJob ends a job cancelled before any worker picked it up
+func (h *StreamHandler) cancelQueuedJob(job *appschema.FaceScanJob) {
+	log.Printf("[jobs] Job %s: cancelled while queued", job.ID)
+	h.jobSink(job).Emit(cancelledEvent())
+}
+
+func cancelledEvent() *appschema.EventMessage {
+	return &appschema.EventMessage{
+		Code:       http.StatusOK,
+		Event:      faceanalyze_events.EventCancelled,
+		Message:    "Scan cancelled",
+		Completion: 100,
+	}
+}
+
+// RunFaceScanJob is the worker half of an upload. It runs each queued image
+// through the job's analysis pipeline, a few at a time, and publishes progress
+// on the job's stream. Batches end with a batch_done summary.
+func (h *StreamHandler) RunFaceScanJob(ctx context.Context, job *appschema.FaceScanJob) {
+	// images report concurrently, but the sinks see one event at a time
+	var mu sync.Mutex
+	sink := h.jobSink(job)
+	sendEvent := func(event *appschema.EventMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+		sink.Emit(event)
+	}
+
+	p, ok := h.Pipelines.Get(job.Analysis)
+	if !ok {
+		log.Printf("[jobs] Job %s: no pipeline for analysis %q", job.ID, job.Analysis)
+		sendEvent(&appschema.EventMessage{
+			Code:       http.StatusInternalServerError,
+			Event:      faceanalyze_events.EventError,
+			ErrorCode:  faceanalyze_events.ErrCodeInternal,
+			Message:    "Internal error",
+			Completion: 100,
+		})
+		return
+	}
+
+	progress := make([]int, len(job.Images))
+	results := make([]appschema.BatchImageResult, len(job.Images))
+
+	// imageSink swaps an image's own completion for the job's overall one
+	imageSink := pipeline.SinkFunc(func(event *appschema.EventMessage) error {
+		mu.Lock()
+		progress[*event.ImageIndex] = event.Completion
+		total := 0
+		for _, p := range progress {
+			total += p
+		}
+		mu.Unlock()
+		event.Completion = total / len(progress)
+
+		if job.IsBatch() {
+			switch event.Event {
+			case faceanalyze_events.EventCompleted:
+				event.Event = faceanalyze_events.EventImageCompleted
+			case faceanalyze_events.EventError:
+				event.Event = faceanalyze_events.EventImageError
+			}
+		}
+		sendEvent(event)
+		return nil
+	})
+
+	limit := make(chan struct{}, h.Jobs.Config().ImageParallelism)
+	var wg sync.WaitGroup
+	for _, image := range job.Images {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case limit <- struct{}{}:
+				defer func() { <-limit }()
+			case <-ctx.Done():
+				// never started, and never without a slot
+				results[image.Index] = appschema.BatchImageResult{Index: image.Index, Filename: image.Filename, Status: appschema.JobCancelled}
+				return
+			}
+
+			result := appschema.BatchImageResult{Index: image.Index, Filename: image.Filename, Status: appschema.JobSucceeded}
+			data, err := p.Run(ctx, &pipeline.Image{
+				Index:       image.Index,
+				Filename:    image.Filename,
+				OwnerID:     job.OwnerID,
+				JobID:       job.ID,
+				ContentType: image.ContentType,
+				Data:        image.Data,
+				ObjectKey:   image.ObjectKey,
+			}, imageSink)
+			if err != nil {
+				result.Status = appschema.JobFailed
+				var stageErr *pipeline.Error
+				if errors.As(err, &stageErr) {
+					result.ErrorCode, result.Message = stageErr.Code, stageErr.Message
+				}
+			}
+			result.Result = data
+			results[image.Index] = result
+		}()
+	}
+	wg.Wait()
+
+	// the images stopped without reporting; the job ends with a single event
+	if errors.Is(ctx.Err(), context.Canceled) {
+		event := cancelledEvent()
+		if !errors.Is(context.Cause(ctx), jobs.ErrCancelled) {
+			// the pool is shutting down
+			event = &appschema.EventMessage{
+				Code:       http.StatusServiceUnavailable,
+				Event:      faceanalyze_events.EventError,
+				ErrorCode:  faceanalyze_events.ErrCodeInternal,
+				Message:    "Face scan cancelled",
+				Completion: 100,
+			}
+		}
+		log.Printf("[jobs] Job %s: %s", job.ID, context.Cause(ctx))
+		sendEvent(event)
+		return
+	}
+
+	if !job.IsBatch() {
+		return
+	}
+
+	summary := &appschema.BatchSummary{Total: len(results), Images: results}
+	for _, result := range results {
+		if result.Status == appschema.JobSucceeded {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+	}
+	sendEvent(&appschema.EventMessage{
+		Code:       http.StatusOK,
+		Event:      faceanalyze_events.EventBatchCompleted,
+		Data:       summary,
+		Message:    fmt.Sprintf("Scanned %d of %d images", summary.Succeeded, summary.Total),
+		Completion: 100,
+	})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/jobs"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/pipeline"
+)
+
+func TestRunFaceScanJobCancelledWhileWaiting(t *testing.T) {
+	globals.JobTracker = &jobs.Tracker{Store: jobs.NewMemoryStore(), Retention: time.Hour}
+	globals.Stream = stream.NewStreamHub()
+
+	// every image blocks in its analysis until the job is cancelled
+	var calls, running, most atomic.Int32
+	started := make(chan struct{}, 3)
+	registry := pipeline.NewRegistry()
+	registry.Register(&pipeline.Pipeline{Name: "slow", Stages: []pipeline.Stage{{
+		Name:   pipeline.StageAnalyze,
+		Weight: 1,
+		Run: func(ctx context.Context, image *pipeline.Image, report pipeline.Reporter) error {
+			calls.Add(1)
+			if n := running.Add(1); n > most.Load() {
+				most.Store(n)
+			}
+			defer running.Add(-1)
+			started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}}})
+	h := &StreamHandler{Jobs: jobs.NewPool(jobs.Config{ImageParallelism: 1}, nil), Pipelines: registry}
+
+	job := &appschema.FaceScanJob{ID: "j1", StreamID: "s1", Analysis: "slow", Images: []appschema.JobImage{
+		{Index: 0, Filename: "a.jpg"}, {Index: 1, Filename: "b.jpg"}, {Index: 2, Filename: "c.jpg"},
+	}}
+	globals.JobTracker.Create(context.Background(), job)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan struct{})
+	go func() {
+		h.RunFaceScanJob(ctx, job)
+		close(done)
+	}()
+	<-started
+	cancel(jobs.ErrCancelled)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job kept running after it was cancelled")
+	}
+	if calls.Load() != 1 || most.Load() != 1 {
+		t.Fatalf("%d images analysed, %d at once; want only the one holding the slot", calls.Load(), most.Load())
+	}
+	record, err := globals.JobTracker.Store.Get(context.Background(), job.ID)
+	if err != nil || record.Status != appschema.JobCancelled {
+		t.Fatalf("job = %+v, %v; want it cancelled", record, err)
+	}
+}
+
+func TestCancelQueuedJob(t *testing.T) {
+	globals.JobTracker = &jobs.Tracker{Store: jobs.NewMemoryStore(), Retention: time.Hour}
+	globals.Stream = stream.NewStreamHub()
+
+	ran := make(chan struct{}, 1)
+	h := &StreamHandler{}
+	h.Jobs = jobs.NewPool(jobs.Config{Workers: 1}, func(ctx context.Context, job *appschema.FaceScanJob) {
+		ran <- struct{}{}
+	})
+	h.Jobs.OnCancelQueued = h.cancelQueuedJob
+
+	job := &appschema.FaceScanJob{ID: "queued", StreamID: "s1", Images: []appschema.JobImage{{Index: 0, Filename: "a.jpg"}}}
+	globals.JobTracker.Create(context.Background(), job)
+	if err := h.Jobs.Enqueue(job); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if !h.Jobs.Cancel(job.ID) {
+		t.Fatal("Cancel = false for a queued job")
+	}
+
+	// settled before any worker has looked at the queue
+	record, err := globals.JobTracker.Store.Get(context.Background(), job.ID)
+	if err != nil || record.Status != appschema.JobCancelled {
+		t.Fatalf("job = %+v, %v; want it cancelled", record, err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	h.Jobs.Start(ctx)
+	select {
+	case <-ran:
+		t.Fatal("worker ran a job cancelled while queued")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
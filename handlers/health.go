@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/upstream"
+)
+
+type HealthHandler struct{}
+
+func NewHealthHandler() *HealthHandler {
+	return &HealthHandler{}
+}
+
+// Health reports whether the service can currently complete scans. An open
+// breaker degrades the status but still answers 200: the instance itself is
+// fine and replacing it would not bring the upstream back. Without an upstream
+// client at all the instance is unusable and reports 503.
+func (h *HealthHandler) Health(c *gin.Context) {
+	if globals.FaceAnalyzeUpstream == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "unavailable",
+			"upstream": gin.H{
+				"face_analyze": "unavailable",
+			},
+		})
+		return
+	}
+	state := globals.FaceAnalyzeUpstream.Breaker().State()
+
+	status := "ok"
+	if state != upstream.BreakerClosed {
+		status = "degraded"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": status,
+		"upstream": gin.H{
+			"face_analyze": state,
+		},
+	})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/upstream"
+)
+
+func TestHealth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer func() { globals.FaceAnalyzeUpstream = nil }()
+
+	open := upstream.NewClient(http.DefaultClient, upstream.Config{}, upstream.NewBreaker(1, time.Hour))
+	open.Breaker().Failure()
+
+	tests := []struct {
+		name       string
+		client     *upstream.Client
+		wantStatus int
+		want       string
+	}{
+		{name: "closed breaker", client: upstream.NewClient(http.DefaultClient, upstream.Config{}, upstream.NewBreaker(1, time.Hour)), wantStatus: http.StatusOK, want: `"status":"ok"`},
+		{name: "open breaker", client: open, wantStatus: http.StatusOK, want: `"status":"degraded"`},
+		{name: "no upstream client", wantStatus: http.StatusServiceUnavailable, want: `"face_analyze":"unavailable"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			globals.FaceAnalyzeUpstream = tt.client
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			NewHealthHandler().Health(c)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.want) {
+				t.Fatalf("Health = %d %s, want %d with %s", w.Code, w.Body, tt.wantStatus, tt.want)
+			}
+		})
+	}
+}
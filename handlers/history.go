@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"image"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muthu-kumar-u/go-sse/annotate"
+	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/history"
+	"github.com/muthu-kumar-u/go-sse/jobs"
+	"github.com/muthu-kumar-u/go-sse/message"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/storage"
+	"github.com/muthu-kumar-u/go-sse/utils"
+)
+
+// trends are computed over at most this many of the range's newest scans
+const maxTrendScans = 1000
+
+type HistoryHandler struct{}
+
+func NewHistoryHandler() *HistoryHandler {
+	return &HistoryHandler{}
+}
+
+// ListScans pages through the caller's completed scans, newest first,
+// optionally narrowed by ?analysis= and a ?from=&to= date range
+func (h *HistoryHandler) ListScans(c *gin.Context) {
+	ownerId, query, ok := h.query(c)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pagination(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, message.ReturnInvalidFieldMsg())
+		return
+	}
+
+	// one extra row tells us whether another page exists
+	query.Limit, query.Offset = limit+1, offset
+	records, err := globals.ScanHistory.List(c.Request.Context(), ownerId, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+
+	list := appschema.ScanList{Scans: records}
+	if len(records) > limit {
+		list.Scans = records[:limit]
+		list.NextOffset = offset + limit
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetScan returns one of the caller's scans
+func (h *HistoryHandler) GetScan(c *gin.Context) {
+	ownerId, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+		return
+	}
+	if globals.ScanHistory == nil {
+		c.JSON(http.StatusNotImplemented, message.ReturnCustomMessage("scan history is disabled"))
+		return
+	}
+
+	record, err := globals.ScanHistory.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, history.ErrNotFound) || (err == nil && record.OwnerID != ownerId) {
+		c.JSON(http.StatusNotFound, message.ReturnCustomMessage("scan not found"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+// Trends reports how each of the caller's metrics changed across the scans in
+// the ?from=&to= range, optionally for one ?analysis=
+func (h *HistoryHandler) Trends(c *gin.Context) {
+	ownerId, query, ok := h.query(c)
+	if !ok {
+		return
+	}
+
+	query.Limit = maxTrendScans
+	records, err := globals.ScanHistory.List(c.Request.Context(), ownerId, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+
+	trends := history.Trends(records)
+	if !query.From.IsZero() {
+		trends.From = &query.From
+	}
+	if !query.To.IsZero() {
+		trends.To = &query.To
+	}
+	c.JSON(http.StatusOK, trends)
+}
+
+// query reads the caller and the filters shared by the history endpoints,
+// answering the request itself when they are unusable
+func (h *HistoryHandler) query(c *gin.Context) (string, history.Query, bool) {
+	ownerId, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+		return "", history.Query{}, false
+	}
+	if globals.ScanHistory == nil {
+		c.JSON(http.StatusNotImplemented, message.ReturnCustomMessage("scan history is disabled"))
+		return "", history.Query{}, false
+	}
+
+	query := history.Query{Analysis: c.Query("analysis")}
+	var fromOk, toOk bool
+	query.From, fromOk = parseDate(c.Query("from"), false)
+	query.To, toOk = parseDate(c.Query("to"), true)
+	if !fromOk || !toOk || (!query.From.IsZero() && !query.To.IsZero() && query.To.Before(query.From)) {
+		c.JSON(http.StatusBadRequest, message.ReturnCustomMessage("from and to must be RFC 3339 times or YYYY-MM-DD dates, from first"))
+		return "", history.Query{}, false
+	}
+	return ownerId, query, true
+}
+
+// parseDate reads an RFC 3339 time or a YYYY-MM-DD date, which as the end of a
+// range covers the whole day. Empty is the zero time.
+func parseDate(raw string, end bool) (time.Time, bool) {
+	if raw == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, true
+	}
+	day, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if end {
+		return day.Add(24*time.Hour - time.Nanosecond), true
+	}
+	return day, true
+}
+
+// CompareScans diffs two of the caller's scans, ?before= and ?after=, each a
+// scan id or the id of a single-image job. Both images must still be stored
+// for the response to link the side by side rendering.
+func (h *HistoryHandler) CompareScans(c *gin.Context) {
+	before, after, ok := h.comparedPair(c)
+	if !ok {
+		return
+	}
+
+	comparison := history.Compare(before, after)
+	if _, err := scanImageKey(c.Request.Context(), before); err == nil {
+		if _, err := scanImageKey(c.Request.Context(), after); err == nil {
+			query := url.Values{"before": {c.Query("before")}, "after": {c.Query("after")}}
+			comparison.ImageURL = path.Join("/api", os.Getenv("APP_VERSION"), "history/compare/image") + "?" + query.Encode()
+		}
+	}
+	c.JSON(http.StatusOK, comparison)
+}
+
+// CompareImage renders the ?before= and ?after= scans' annotated images side
+// by side as a PNG
+func (h *HistoryHandler) CompareImage(c *gin.Context) {
+	before, after, ok := h.comparedPair(c)
+	if !ok {
+		return
+	}
+
+	panels := make([]annotate.Panel, 0, 2)
+	for _, side := range []struct {
+		label  string
+		record *appschema.ScanRecord
+	}{{"before", before}, {"after", after}} {
+		img, err := scanImage(c.Request.Context(), side.record)
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, message.ReturnCustomMessage("scan image not found"))
+			return
+		}
+		if err != nil {
+			log.Printf("[history] Comparing %s scan %s: %v", side.label, side.record.JobID, err)
+			c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+			return
+		}
+		panels = append(panels, annotate.Panel{Label: side.label, Image: img})
+	}
+
+	data, err := annotate.SideBySide(panels...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// comparedPair resolves the two scans of a comparison, answering the request
+// itself when either is missing or not the caller's
+func (h *HistoryHandler) comparedPair(c *gin.Context) (before, after *appschema.ScanRecord, ok bool) {
+	ownerId, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+		return nil, nil, false
+	}
+	if c.Query("before") == "" || c.Query("after") == "" {
+		c.JSON(http.StatusBadRequest, message.ReturnCustomMessage("before and after are required"))
+		return nil, nil, false
+	}
+
+	records := make([]*appschema.ScanRecord, 2)
+	for i, id := range []string{c.Query("before"), c.Query("after")} {
+		record, status, msg := resolveScan(c.Request.Context(), ownerId, id)
+		if status != http.StatusOK {
+			c.JSON(status, message.ReturnCustomMessage(msg))
+			return nil, nil, false
+		}
+		records[i] = record
+	}
+	return records[0], records[1], true
+}
+
+// resolveScan finds id among the owner's scans, falling back to their jobs so
+// a result can be compared without scan history
+func resolveScan(ctx context.Context, ownerId, id string) (*appschema.ScanRecord, int, string) {
+	if globals.ScanHistory != nil {
+		record, err := globals.ScanHistory.Get(ctx, id)
+		if err == nil && record.OwnerID == ownerId {
+			return record, http.StatusOK, ""
+		}
+		if err != nil && !errors.Is(err, history.ErrNotFound) {
+			return nil, http.StatusInternalServerError, "something went wrong"
+		}
+	}
+
+	job, err := globals.JobTracker.Store.Get(ctx, id)
+	if errors.Is(err, jobs.ErrNotFound) || (err == nil && job.OwnerID != ownerId) {
+		return nil, http.StatusNotFound, "scan not found: " + id
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, "something went wrong"
+	}
+	// batches carry one result per image; those are compared by scan id
+	if job.Status != appschema.JobSucceeded || job.Result == nil {
+		return nil, http.StatusConflict, "job has no single completed result: " + id
+	}
+	createdAt := job.CreatedAt
+	if job.FinishedAt != nil {
+		createdAt = *job.FinishedAt
+	}
+	return &appschema.ScanRecord{
+		OwnerID:   job.OwnerID,
+		JobID:     job.ID,
+		Analysis:  job.Analysis,
+		Filename:  job.Filename,
+		Result:    job.Result,
+		CreatedAt: createdAt,
+	}, http.StatusOK, ""
+}
+
+// scanImage draws a scan's regions over its stored image, or fails with
+// storage.ErrNotFound when the image is not kept
+func scanImage(ctx context.Context, record *appschema.ScanRecord) (image.Image, error) {
+	key, err := scanImageKey(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	data, err := globals.ImageStore.Get(ctx, key, maxAnnotationBytes)
+	if err != nil {
+		return nil, err
+	}
+	img, err := annotate.Draw(data, record.Result)
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// scanImageKey finds the stored image a scan's regions were found in: the
+// preprocessed copy when there is one, else the upload
+func scanImageKey(ctx context.Context, record *appschema.ScanRecord) (string, error) {
+	if globals.ImageStore == nil {
+		return "", storage.ErrNotFound
+	}
+	candidates := []string{storage.KindOriginal + ".jpg", storage.KindOriginal + ".png"}
+	if record.Result.Transform != nil {
+		candidates = []string{storage.KindPreprocessed + ".jpg"}
+	}
+	for _, candidate := range candidates {
+		kind, ext, _ := strings.Cut(candidate, ".")
+		key := storage.ImageKey(utils.ImageStorePrefix(), record.OwnerID, record.JobID, record.ImageIndex, kind, "."+ext)
+		_, err := globals.ImageStore.Size(ctx, key)
+		if err == nil {
+			return key, nil
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return "", err
+		}
+	}
+	return "", storage.ErrNotFound
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/jobs"
+	"github.com/muthu-kumar-u/go-sse/message"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/storage"
+	"github.com/muthu-kumar-u/go-sse/utils"
+)
+
+const (
+	defaultJobPageSize = 20
+	maxJobPageSize     = 100
+
+	// annotated images are redirected to links valid this long
+	annotationURLTTL   = 5 * time.Minute
+	maxAnnotationBytes = 64 << 20
+)
+
+type JobHandler struct {
+	Jobs *jobs.Pool
+}
+
+func NewJobHandler(pool *jobs.Pool) *JobHandler {
+	return &JobHandler{Jobs: pool}
+}
+
+// GetJob returns the status of one of the caller's jobs, with the result once done
+func (h *JobHandler) GetJob(c *gin.Context) {
+	ownerId, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+		return
+	}
+
+	record, err := globals.JobTracker.Store.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, jobs.ErrNotFound) || (err == nil && record.OwnerID != ownerId) {
+		c.JSON(http.StatusNotFound, message.ReturnCustomMessage("job not found"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// GetAnnotatedImage serves the annotated result of one of the job's images,
+// ?image=N (default 0). Stores that can presign redirect to the object.
+func (h *JobHandler) GetAnnotatedImage(c *gin.Context) {
+	ownerId, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+		return
+	}
+	index, err := strconv.Atoi(c.DefaultQuery("image", "0"))
+	if err != nil || index < 0 {
+		c.JSON(http.StatusBadRequest, message.ReturnInvalidFieldMsg())
+		return
+	}
+
+	record, err := globals.JobTracker.Store.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, jobs.ErrNotFound) || (err == nil && record.OwnerID != ownerId) {
+		c.JSON(http.StatusNotFound, message.ReturnCustomMessage("job not found"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+
+	var key string
+	for _, object := range record.Objects {
+		if object.Kind == storage.KindAnnotated && object.ImageIndex == index {
+			key = object.Key
+		}
+	}
+	if key == "" || globals.AnnotationStore == nil {
+		c.JSON(http.StatusNotFound, message.ReturnCustomMessage("annotated image not found"))
+		return
+	}
+
+	if url, err := globals.AnnotationStore.PresignGet(key, annotationURLTTL); err == nil {
+		c.Redirect(http.StatusFound, url)
+		return
+	}
+	data, err := globals.AnnotationStore.Get(c.Request.Context(), key, maxAnnotationBytes)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, message.ReturnCustomMessage("annotated image not found"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// CancelJob stops one of the caller's queued or running jobs, or a direct
+// upload still waiting for its image. The upstream call is abandoned and the
+// job's stream ends with a cancelled event.
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	ownerId, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+		return
+	}
+
+	record, err := globals.JobTracker.Store.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, jobs.ErrNotFound) || (err == nil && record.OwnerID != ownerId) {
+		c.JSON(http.StatusNotFound, message.ReturnCustomMessage("job not found"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+
+	switch record.Status {
+	case appschema.JobSucceeded, appschema.JobFailed, appschema.JobCancelled:
+		c.JSON(http.StatusConflict, message.ReturnCustomMessage("job already finished"))
+		return
+	}
+	if record.Status == appschema.JobAwaitingUpload {
+		// nothing runs yet; the upload is simply never started, unless another
+		// instance got to start it first
+		err := globals.JobTracker.Store.Transition(c.Request.Context(), record.ID, appschema.JobAwaitingUpload, appschema.JobCancelled)
+		if errors.Is(err, jobs.ErrStatusChanged) {
+			c.JSON(http.StatusConflict, message.ReturnCustomMessage("job cannot be cancelled"))
+			return
+		}
+		if err != nil {
+			log.Printf("[jobs] Job %s: cancel failed: %v", record.ID, err)
+			c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+			return
+		}
+		event := cancelledEvent()
+		globals.JobTracker.Record(c.Request.Context(), record.ID, event)
+		streamSink(record.StreamID, record.CallbackURL).Emit(event)
+		c.JSON(http.StatusAccepted, gin.H{"message": "cancellation requested", "job_id": record.ID})
+		return
+	}
+	// the record may trail a job that just finished, or belong to another
+	// instance. A queued job is settled by the time Cancel returns.
+	if !h.Jobs.Cancel(record.ID) {
+		c.JSON(http.StatusConflict, message.ReturnCustomMessage("job cannot be cancelled"))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "cancellation requested", "job_id": record.ID})
+}
+
+// ListJobs pages through the caller's recent jobs, newest first
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	ownerId, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+		return
+	}
+
+	limit, offset, ok := pagination(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, message.ReturnInvalidFieldMsg())
+		return
+	}
+
+	// one extra row tells us whether another page exists
+	records, err := globals.JobTracker.Store.List(c.Request.Context(), ownerId, limit+1, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+
+	list := appschema.JobList{Jobs: records}
+	if len(records) > limit {
+		list.Jobs = records[:limit]
+		list.NextOffset = offset + limit
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func pagination(c *gin.Context) (limit, offset int, ok bool) {
+	limit, offset = defaultJobPageSize, 0
+	var err error
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+			return 0, 0, false
+		}
+	}
+	if raw := c.Query("offset"); raw != "" {
+		if offset, err = strconv.Atoi(raw); err != nil || offset < 0 {
+			return 0, 0, false
+		}
+	}
+	if limit > maxJobPageSize {
+		limit = maxJobPageSize
+	}
+	return limit, offset, true
+}
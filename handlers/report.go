@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/history"
+	"github.com/muthu-kumar-u/go-sse/message"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/report"
+	"github.com/muthu-kumar-u/go-sse/storage"
+	"github.com/muthu-kumar-u/go-sse/utils"
+)
+
+// a range report covers at most this many scans, each with its image
+const maxReportScans = 100
+
+// ScanReport downloads one of the caller's scans, a scan id or the id of a
+// single-image job, as a ?format=pdf (default) or csv report
+func (h *HistoryHandler) ScanReport(c *gin.Context) {
+	ownerId, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+		return
+	}
+	format, ok := reportFormat(c)
+	if !ok {
+		return
+	}
+
+	record, status, msg := resolveScan(c.Request.Context(), ownerId, c.Param("id"))
+	if status != http.StatusOK {
+		c.JSON(status, message.ReturnCustomMessage(msg))
+		return
+	}
+
+	name := "scan-" + record.JobID
+	if record.ID != "" {
+		name = "scan-" + record.ID
+	}
+	writeReport(c, format, name, []appschema.ScanRecord{*record}, &report.Report{Title: "Face scan report"})
+}
+
+// RangeReport downloads the caller's scans in the ?from=&to= range, optionally
+// for one ?analysis=, as a ?format=pdf (default) or csv report. The PDF opens
+// with the range's trends.
+func (h *HistoryHandler) RangeReport(c *gin.Context) {
+	ownerId, query, ok := h.query(c)
+	if !ok {
+		return
+	}
+	format, ok := reportFormat(c)
+	if !ok {
+		return
+	}
+
+	query.Limit = maxReportScans + 1
+	records, err := globals.ScanHistory.List(c.Request.Context(), ownerId, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+	if len(records) > maxReportScans {
+		c.JSON(http.StatusBadRequest, message.ReturnCustomMessage(fmt.Sprintf("a report covers at most %d scans, narrow from and to", maxReportScans)))
+		return
+	}
+	// oldest first, the order trends read in
+	slices.Reverse(records)
+
+	doc := &report.Report{Title: "Face scan history", From: query.From, To: query.To, Trends: history.Trends(records)}
+	writeReport(c, format, "scan-history", records, doc)
+}
+
+func reportFormat(c *gin.Context) (string, bool) {
+	format := c.DefaultQuery("format", "pdf")
+	if format != "pdf" && format != "csv" {
+		c.JSON(http.StatusBadRequest, message.ReturnCustomMessage("format must be pdf or csv"))
+		return "", false
+	}
+	return format, true
+}
+
+// writeReport renders records into doc and sends it as an attachment named
+// name. Scans whose image is no longer stored are reported without one.
+func writeReport(c *gin.Context, format, name string, records []appschema.ScanRecord, doc *report.Report) {
+	var buf bytes.Buffer
+	contentType := "text/csv; charset=utf-8"
+	if format == "csv" {
+		if err := report.CSV(&buf, records); err != nil {
+			c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+			return
+		}
+	} else {
+		contentType = "application/pdf"
+		doc.Generated = time.Now()
+		for i := range records {
+			img, err := scanImage(c.Request.Context(), &records[i])
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				log.Printf("[history] Report of scan %s: image left out: %v", records[i].JobID, err)
+			}
+			doc.Scans = append(doc.Scans, report.Scan{Record: &records[i], Image: img})
+		}
+		if err := report.PDF(&buf, doc); err != nil {
+			log.Printf("[history] Rendering report %s failed: %v", name, err)
+			c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+			return
+		}
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
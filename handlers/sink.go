@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	"github.com/muthu-kumar-u/go-sse/globals"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/pipeline"
+)
+
+// streamSink publishes events on a stream, notifying the completion webhook of
+// terminal ones
+func streamSink(streamId, callbackURL string) pipeline.EventSink {
+	return pipeline.SinkFunc(func(event *appschema.EventMessage) error {
+		emitEvent(streamId, callbackURL, event)
+		return nil
+	})
+}
+
+// jobSink records a job's events and publishes them on its stream. An upload
+// waiting inline on the job gets them written into its response as well.
+func (h *StreamHandler) jobSink(job *appschema.FaceScanJob) pipeline.EventSink {
+	sinks := pipeline.MultiSink{
+		pipeline.SinkFunc(func(event *appschema.EventMessage) error {
+			// recorded even if the job's context has expired so its outcome is never lost
+			globals.JobTracker.Record(context.Background(), job.ID, event)
+			return nil
+		}),
+		streamSink(job.StreamID, job.CallbackURL),
+	}
+	if globals.ScanHistory != nil {
+		sinks = append(sinks, historySink(job))
+	}
+	if inline, ok := h.inline.Load(job.ID); ok {
+		sinks = append(sinks, inline.(pipeline.EventSink))
+	}
+	return sinks
+}
+
+// historySink adds each image the job completes to its owner's scan history
+func historySink(job *appschema.FaceScanJob) pipeline.EventSink {
+	return pipeline.SinkFunc(func(event *appschema.EventMessage) error {
+		if event.Event != faceanalyze_events.EventCompleted && event.Event != faceanalyze_events.EventImageCompleted {
+			return nil
+		}
+		result, ok := event.Data.(*appschema.FaceScanData)
+		if !ok {
+			return nil
+		}
+		record := &appschema.ScanRecord{
+			ID:        uuid.NewString(),
+			OwnerID:   job.OwnerID,
+			JobID:     job.ID,
+			Analysis:  job.Analysis,
+			Filename:  event.Filename,
+			Result:    result,
+			CreatedAt: time.Now(),
+		}
+		if event.ImageIndex != nil {
+			record.ImageIndex = *event.ImageIndex
+		}
+		if err := globals.ScanHistory.Save(context.Background(), record); err != nil {
+			log.Printf("[history] Job %s: saving image %d failed: %v", job.ID, record.ImageIndex, err)
+		}
+		return nil
+	})
+}
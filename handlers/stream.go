@@ -1,148 +1,288 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	constants "github.com/muthu-kumar-u/go-sse/const"
+	"github.com/muthu-kumar-u/go-sse/events/broker"
 	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	"github.com/muthu-kumar-u/go-sse/events/stream"
 	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/jobs"
 	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/pipeline"
 	"github.com/muthu-kumar-u/go-sse/services"
 	"github.com/muthu-kumar-u/go-sse/utils"
 )
 
+// how long an upload's stream is kept around for late subscribers
+const streamRetention = 10 * time.Minute
+
 type StreamHandler struct {
 	UserService    services.UserService
+	Jobs           *jobs.Pool
+	ImageLimits    utils.ImageLimits
+	// Pipelines holds the analyses uploads can ask for
+	Pipelines      *pipeline.Registry
+	// InlineByDefault streams upload events in the response even without a
+	// streaming Accept header. Lambda mode sets it since the hub there is
+	// scoped to a single invocation.
+	InlineByDefault bool
+
+	// sinks of uploads waiting inline on their job, by job ID
+	inline sync.Map
 }
 
-func NewFaceAnalyzeHandler(userService services.UserService) *StreamHandler {
-	return &StreamHandler{
+func NewFaceAnalyzeHandler(userService services.UserService, jobConfig jobs.Config, imageLimits utils.ImageLimits, pipelines *pipeline.Registry) *StreamHandler {
+	h := &StreamHandler{
 		UserService: userService,
-	}	
+		ImageLimits: imageLimits,
+		Pipelines:   pipelines,
+	}
+	h.Jobs = jobs.NewPool(jobConfig, h.RunFaceScanJob)
+	h.Jobs.OnCancelQueued = h.cancelQueuedJob
+	h.Jobs.Start(context.Background())
+	return h
 }
 
+// LogUserFace queues an upload for the default face analysis
 func (h *StreamHandler) LogUserFace(c *gin.Context) {
+	h.upload(c, pipeline.DefaultAnalysis)
+}
+
+// AnalyzeUpload serves uploads for the named analysis, which must be registered
+func (h *StreamHandler) AnalyzeUpload(analysis string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.upload(c, analysis)
+	}
+}
+
+// upload validates the images of a multipart upload, queues them for analysis
+// and either answers with the job or, for streaming clients, relays its events
+func (h *StreamHandler) upload(c *gin.Context, analysis string) {
+	p, ok := h.Pipelines.Get(analysis)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown analysis"})
+		return
+	}
+
+	format, inlineRequested := stream.NegotiateFormat(c.GetHeader("Accept"))
+	inlineRequested = inlineRequested || h.InlineByDefault
+
 	streamId := c.Query("stream")
 	if streamId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "streamId is required"})
-		return
+		if !inlineRequested {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "streamId is required"})
+			return
+		}
+		// the caller reads events from this response, so any fresh ID will do
+		streamId = uuid.NewString()
+	}
+	// make sure the stream exists so the job's events are kept for subscribers
+	// that connect after the upload returns
+	globals.Stream.CreateTemporaryStream(streamId, streamRetention)
+
+	// completion callback: per request url wins over the client's registered one
+	callbackURL := c.Query("callback_url")
+	ownerId, _ := utils.GetUserIdFromHeader(c)
+	if callbackURL == "" && ownerId != "" {
+		callbackURL = registeredCallback(ownerId)
 	}
 
-	sendEvent := func(event *appschema.EventMessage) {
-        data, err := json.Marshal(event)
-        if err != nil {
-            log.Printf("marshal error: %v", err)
-            return
-        }
-        payload := fmt.Sprintf("event: %s\ndata: %s\n\n", event.Event, data)
-        globals.Stream.Publish(streamId, []byte(payload))
-    }
+	// Parse multipart form before any inline output starts the response. The body
+	// may hold a full batch plus form overhead, nothing more.
+	maxBody := int64(h.Jobs.Config().MaxImages)*h.ImageLimits.MaxBytes + 1<<20
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBody)
+	parseErr := c.Request.ParseMultipartForm(10 << 20)
+
+	// Clients asking for a stream get this upload's events inline as well
+	var inline *eventWriter
+	if inlineRequested {
+		c.Header("Content-Type", format.ContentType())
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		inline = newEventWriter(c.Writer, format)
+		defer inline.Close()
+		stopHeartbeat := inline.KeepAlive(15 * time.Second)
+		defer stopHeartbeat()
+
+		ready, _ := json.Marshal(&appschema.EventMessage{Code: http.StatusOK, Event: faceanalyze_events.EventReady, StreamID: streamId})
+		inline.WriteEvent(0, faceanalyze_events.EventReady, ready)
+	}
+
+	// rejectEvent reports a failed upload on the stream and to the uploader
+	rejectEvent := func(event *appschema.EventMessage) {
+		event.Event = faceanalyze_events.EventError
+		if inline != nil {
+			pipeline.MultiSink{streamSink(streamId, callbackURL), inline}.Emit(event)
+			return
+		}
+		streamSink(streamId, callbackURL).Emit(event)
+		c.JSON(event.Code, event)
+	}
+	reject := func(code int, errCode, msg string) {
+		rejectEvent(&appschema.EventMessage{Code: code, ErrorCode: errCode, Message: msg})
+	}
+	// rejectImage points the uploader at the image that failed validation
+	rejectImage := func(index int, filename string, code int, errCode, msg string) {
+		rejectEvent(&appschema.EventMessage{Code: code, ErrorCode: errCode, Message: msg, ImageIndex: &index, Filename: filename})
+	}
 
-	// Parse multipart form
-	if err := c.Request.ParseMultipartForm(10 << 20); err != nil {
-		log.Printf("multipart parse error: %v", err)
-		sendEvent(&appschema.EventMessage{Code: 400, Message: "Invalid form data"})
+	if parseErr != nil {
+		log.Printf("multipart parse error: %v", parseErr)
+		var tooLarge *http.MaxBytesError
+		if errors.As(parseErr, &tooLarge) {
+			reject(http.StatusRequestEntityTooLarge, faceanalyze_events.ErrCodeFileTooLarge, fmt.Sprintf("Upload is larger than %d bytes", maxBody))
+			return
+		}
+		reject(http.StatusBadRequest, faceanalyze_events.ErrCodeInvalidForm, "Invalid form data")
 		return
 	}
 
+	if formCallback := c.Request.FormValue("callback_url"); formCallback != "" {
+		callbackURL = formCallback
+	}
+	if callbackURL != "" {
+		if globals.Webhooks == nil {
+			reject(http.StatusBadRequest, faceanalyze_events.ErrCodeInvalidCallback, "Webhooks are disabled")
+			return
+		}
+		if err := globals.Webhooks.ValidateURL(callbackURL); err != nil {
+			callbackURL = ""
+			reject(http.StatusBadRequest, faceanalyze_events.ErrCodeInvalidCallback, "Invalid callback url")
+			return
+		}
+	}
+
 	files := c.Request.MultipartForm.File["image"]
 	if len(files) == 0 {
-		sendEvent(&appschema.EventMessage{Code: 400, Message: "Missing image file"})
+		reject(http.StatusBadRequest, faceanalyze_events.ErrCodeMissingImage, "Missing image file")
 		return
 	}
-
-	fileHeader := files[0]
-	file, err := fileHeader.Open()
-	if err != nil {
-		sendEvent(&appschema.EventMessage{Code: 400, Message: "Failed to open uploaded file"})
+	if maxImages := h.Jobs.Config().MaxImages; len(files) > maxImages {
+		reject(http.StatusBadRequest, faceanalyze_events.ErrCodeTooManyImages, fmt.Sprintf("At most %d images per upload", maxImages))
 		return
 	}
-	defer file.Close()
 
-	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-	if !slices.Contains(constants.IMAGE_EXTENSIONS, ext) {
-		sendEvent(&appschema.EventMessage{Code: 400, Message: "Only jpg, jpeg, png allowed"})
-		return
-	}
+	// every image is checked before any is queued, so a batch is all or nothing
+	images := make([]appschema.JobImage, 0, len(files))
+	for i, fileHeader := range files {
+		ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+		if !slices.Contains(constants.IMAGE_EXTENSIONS, ext) {
+			rejectImage(i, fileHeader.Filename, http.StatusBadRequest, faceanalyze_events.ErrCodeUnsupportedType, "Only jpg, jpeg, png allowed")
+			return
+		}
 
-	sendEvent(&appschema.EventMessage{
-		Code:       http.StatusAccepted,
-		Event:      faceanalyze_events.EventProcessingImage,
-		Message:    "Processing image",
-		Completion: 25,
-	})
+		if fileHeader.Size > h.ImageLimits.MaxBytes {
+			rejectImage(i, fileHeader.Filename, http.StatusRequestEntityTooLarge, faceanalyze_events.ErrCodeFileTooLarge,
+				fmt.Sprintf("Image is larger than %d bytes", h.ImageLimits.MaxBytes))
+			return
+		}
 
-	imageData, err := utils.PrepareImagePayloadFromBytes(file, fileHeader, constants.FACE_ANALYZE_PAYLOAD_FIELD_NAME)
-	if err != nil {
-		sendEvent(&appschema.EventMessage{Code: 500, Message: "Failed to process image"})
-		return
+		file, err := fileHeader.Open()
+		if err != nil {
+			rejectImage(i, fileHeader.Filename, http.StatusBadRequest, faceanalyze_events.ErrCodeInvalidForm, "Failed to open uploaded file")
+			return
+		}
+		// the multipart temp file goes away with the request, so the job keeps the bytes
+		raw, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			rejectImage(i, fileHeader.Filename, http.StatusBadRequest, faceanalyze_events.ErrCodeInvalidForm, "Failed to read uploaded file")
+			return
+		}
+
+		// the extension only says what the client claims; the pipeline's upfront
+		// stages check the bytes
+		image := &pipeline.Image{Index: i, Filename: fileHeader.Filename, Data: raw}
+		var stageErr *pipeline.Error
+		if err := p.Check(c.Request.Context(), image); errors.As(err, &stageErr) {
+			rejectImage(i, fileHeader.Filename, stageErr.Status, stageErr.Code, stageErr.Message)
+			return
+		}
+		images = append(images, appschema.JobImage{Index: i, Filename: image.Filename, ContentType: image.ContentType, Data: image.Data})
 	}
 
-	sendEvent(&appschema.EventMessage{
-		Code:       http.StatusAccepted,
-		Event:      faceanalyze_events.EventAnalyzingFace,
-		Message:    "Analyzing face",
-		Completion: 50,
-	})
+	job := &appschema.FaceScanJob{
+		ID:          uuid.NewString(),
+		StreamID:    streamId,
+		OwnerID:     ownerId,
+		CallbackURL: callbackURL,
+		Analysis:    analysis,
+		Images:      images,
+		CreatedAt:   time.Now(),
+	}
 
-	// Call FaceAnalyze API
-	reqUrl := fmt.Sprintf("%s/%s", globals.FaceAnalyzeService.URL, constants.FACE_ANALYZE_SERVICE_PATHS[0])
-	faceReq, err := http.NewRequest(http.MethodPost, reqUrl, imageData.MultipartBody)
-	if err != nil {
-		sendEvent(&appschema.EventMessage{Code: 500, Message: "Internal error"})
-		return
+	// an inline upload's sink is in place before a worker can pick the job up;
+	// the job's last event releases the response
+	finished := make(chan struct{})
+	if inline != nil {
+		var once sync.Once
+		h.inline.Store(job.ID, pipeline.SinkFunc(func(event *appschema.EventMessage) error {
+			err := inline.Emit(event)
+			if isTerminalEvent(event.Event) {
+				once.Do(func() { close(finished) })
+			}
+			return err
+		}))
+		defer h.inline.Delete(job.ID)
 	}
-	faceReq.Header.Set("Authorization", os.Getenv("FACEANALYZE_SERVICE_AUTH_API_KEY"))
-	faceReq.Header.Set("Content-Type", imageData.MultipartWriter.FormDataContentType())
 
-	resp, err := globals.FaceAnalyzeService.Client.Do(faceReq)
-	if err != nil {
-		sendEvent(&appschema.EventMessage{Code: 500, Message: "Face analyze failed"})
+	// the record exists before a worker can report progress on it
+	if err := globals.JobTracker.Create(c.Request.Context(), job); err != nil {
+		log.Printf("[jobs] Stream %s: failed to record job: %v", streamId, err)
+		reject(http.StatusInternalServerError, faceanalyze_events.ErrCodeInternal, "Failed to queue scan")
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("FaceAnalyze failed: %s", string(body))
-		sendEvent(&appschema.EventMessage{Code: 500, Message: "Face scan error"})
+	// queued goes out before a worker can report progress on the job
+	h.jobSink(job).Emit(&appschema.EventMessage{
+		Code:    http.StatusAccepted,
+		Event:   faceanalyze_events.EventQueued,
+		Message: "Scan queued",
+		Data:    gin.H{"job_id": job.ID, "images": len(images)},
+	})
+
+	if err := h.Jobs.Enqueue(job); err != nil {
+		log.Printf("[jobs] Stream %s: enqueue failed: %v", streamId, err)
+		globals.JobTracker.Record(context.Background(), job.ID, &appschema.EventMessage{
+			Event: faceanalyze_events.EventError, ErrorCode: faceanalyze_events.ErrCodeQueueFull, Message: err.Error(),
+		})
+		c.Header("Retry-After", "5")
+		reject(http.StatusServiceUnavailable, faceanalyze_events.ErrCodeQueueFull, "Scan queue is full, retry later")
 		return
 	}
 
-	var faResp appschema.FaceScannerResponse
-	if err := utils.BindHttpResponseToStruct(resp, &faResp); err != nil {
-		sendEvent(&appschema.EventMessage{Code: 500, Message: "Invalid face scan response"})
+	if inline == nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "face scan queued",
+			"job_id":  job.ID,
+			"stream":  streamId,
+			"images":  len(images),
+		})
 		return
 	}
 
-	sendEvent(&appschema.EventMessage{
-		Code:       200,
-		Event:      faceanalyze_events.EventCompleted,
-		Data:       &appschema.FaceScanData{Quantitative: faResp.Data.Quantitative, Qualitative: faResp.Data.Qualitative},
-		Message:    "Scan complete",
-		Completion: 100,
-	})
-
-	c.JSON(http.StatusOK, gin.H{
-		"message": "face scan complete",
-		"stream":  streamId,
-	})
+	select {
+	case <-c.Request.Context().Done():
+	case <-finished:
+	}
 }
 
 func (h *StreamHandler) FaceLogStream(c *gin.Context) {
@@ -152,8 +292,10 @@ func (h *StreamHandler) FaceLogStream(c *gin.Context) {
         return
     }
 
-    // Set SSE headers
-    c.Header("Content-Type", "text/event-stream")
+    format, _ := stream.NegotiateFormat(c.GetHeader("Accept"))
+
+    // Set streaming headers
+    c.Header("Content-Type", format.ContentType())
     c.Header("Cache-Control", "no-cache")
     c.Header("Connection", "keep-alive")
     c.Header("Access-Control-Allow-Origin", "*")
@@ -171,15 +313,34 @@ func (h *StreamHandler) FaceLogStream(c *gin.Context) {
     ctx, cancel := context.WithCancel(c.Request.Context())
     defer cancel()
 
-    // Subscribe to the stream (note the receive-only channel)
-    recvCh, _ := globals.Stream.Subscribe(ctx, streamId)
-    defer func() {
-        // Convert receive-only channel to bidirectional for unsubscribe
-        if ch, ok := (interface{}(recvCh)).(chan []byte); ok {
-            globals.Stream.Unsubscribe(streamId, ch)
-        }
-        log.Printf("[SSE] Stream %s: Unsubscribed", streamId)
-    }()
+    // Resume after the last event the client saw, if it is reconnecting
+    lastEventId, _ := strconv.ParseUint(c.GetHeader("Last-Event-ID"), 10, 64)
+
+    // NDJSON readers have no event framing to tell them a scan is over, and an
+    // event store follower would otherwise poll forever, so both end with the
+    // terminal event
+    closeOnTerminal := format == stream.FormatNDJSON || globals.EventStore != nil
+    isFinal := func(msg stream.Message) bool {
+        return closeOnTerminal && isTerminalEvent(msg.Event)
+    }
+
+    var (
+        recvCh  <-chan stream.Message
+        backlog []stream.Message
+    )
+    if globals.EventStore != nil {
+        // Follow the external store so scans published by another instance or
+        // Lambda invocation can be observed
+        recvCh = broker.Follow(ctx, globals.EventStore, streamId, lastEventId, time.Second, isFinal)
+    } else {
+        // Subscribe to the stream
+        hubCh, hubBacklog, _ := globals.Stream.SubscribeFrom(ctx, streamId, lastEventId)
+        recvCh, backlog = hubCh, hubBacklog
+        defer func() {
+            globals.Stream.Unsubscribe(streamId, hubCh)
+            log.Printf("[SSE] Stream %s: Unsubscribed", streamId)
+        }()
+    }
 
     // Helper function to safely marshal JSON
     mustJSON := func(v interface{}) string {
@@ -192,15 +353,16 @@ func (h *StreamHandler) FaceLogStream(c *gin.Context) {
     }
 
     // Send initial handshake
-    handshake := fmt.Sprintf("event: ready\ndata: %s\n\n", 
+    handshake := format.Frame(0, faceanalyze_events.EventReady, []byte(
         mustJSON(map[string]interface{}{
             "code":      200,
+            "event":     faceanalyze_events.EventReady,
             "stream_id": streamId,
             "ts":        time.Now().Unix(),
         }),
-    )
+    ))
 
-    if _, err := c.Writer.Write([]byte(handshake)); err != nil {
+    if _, err := c.Writer.Write(handshake); err != nil {
         log.Printf("[SSE] Stream %s: Initial write failed: %v", streamId, err)
         return
     }
@@ -208,6 +370,23 @@ func (h *StreamHandler) FaceLogStream(c *gin.Context) {
 
     log.Printf("[SSE] Stream %s: Connection established", streamId)
 
+    writeMessage := func(msg stream.Message) error {
+        _, err := c.Writer.Write(format.Frame(msg.ID, msg.Event, msg.Data))
+        return err
+    }
+
+    for _, msg := range backlog {
+        if err := writeMessage(msg); err != nil {
+            log.Printf("[SSE] Stream %s: Replay failed: %v", streamId, err)
+            return
+        }
+        if isFinal(msg) {
+            flusher.Flush()
+            return
+        }
+    }
+    flusher.Flush()
+
     // Heartbeat ticker
     heartbeat := time.NewTicker(15 * time.Second)
     defer heartbeat.Stop()
@@ -220,8 +399,8 @@ func (h *StreamHandler) FaceLogStream(c *gin.Context) {
             return
 
         case <-heartbeat.C:
-            // Send keep-alive comment
-            if _, err := c.Writer.Write([]byte(": heartbeat\n\n")); err != nil {
+            // Send keep-alive
+            if _, err := c.Writer.Write(format.Heartbeat()); err != nil {
                 log.Printf("[SSE] Stream %s: Heartbeat failed: %v", streamId, err)
                 return
             }
@@ -234,199 +413,61 @@ func (h *StreamHandler) FaceLogStream(c *gin.Context) {
             }
 
             // Write the message
-            if _, err := c.Writer.Write(msg); err != nil {
+            if err := writeMessage(msg); err != nil {
                 log.Printf("[SSE] Stream %s: Write failed: %v", streamId, err)
                 return
             }
             flusher.Flush()
+
+            if isFinal(msg) {
+                log.Printf("[SSE] Stream %s: Terminal event delivered, closing", streamId)
+                return
+            }
         }
     }
 }
 
-func (h *StreamHandler) LogUserFaceLambda(ctx context.Context, req events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
-	streamId := uuid.NewString()
-	go globals.Stream.CreateTemporaryStream(streamId, 2*time.Minute) // create temporary stream
-
-	reader, writer := io.Pipe()
-	done := make(chan struct{})
-
-	go func ()  {
-		defer func() {
-			writer.Close()
-			close(done)
-			log.Printf("Stream completed or client disconnected for: %s", streamId)
-		}()
-
-		sendEvent := func(event *appschema.EventMessage) {
-			data, err := json.Marshal(event)
-			if err != nil {
-				log.Printf("Marshal error: %v", err)
-				return
-			}
-			payload := fmt.Sprintf("event: %s\ndata: %s\n\n", event.Event, data)
-			writer.Write([]byte(payload))
-			globals.Stream.Publish(streamId, []byte(payload))
-		}
-
-		authHeader := req.Headers["Authorization"]
-		if authHeader == "" {
-			authHeader = req.Headers["authorization"]
-		}
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			sendEvent(&appschema.EventMessage{Code: http.StatusUnauthorized,Event: faceanalyze_events.EventError,Message: "Missing or invalid Authorization header"})
-			return
-		}
-
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		verified, err := h.UserService.IsUserAuthenticated(ctx, tokenString)
-		if err != nil {
-			sendEvent(&appschema.EventMessage{Code: http.StatusInternalServerError,Event: faceanalyze_events.EventError,Message: "Authentication failed"})
-			return
-		}
-		if !verified {
-			sendEvent(&appschema.EventMessage{Code: http.StatusUnauthorized,Event: faceanalyze_events.EventError, Message: "User not allowed"})
-			return
-		}
-
-		sendEvent(&appschema.EventMessage{Code: http.StatusOK,Event: faceanalyze_events.EventReady,Message: "Stream initialized", StreamID: streamId,Completion: 0,})
-		sendEvent(&appschema.EventMessage{Code: http.StatusAccepted,Event: faceanalyze_events.EventProcessingImage, Message: "Starting processing", Completion: 10})
-
-		bodyBytes := []byte(req.Body)
-		if req.IsBase64Encoded {
-			decoded, err := base64.StdEncoding.DecodeString(req.Body)
-			if err != nil {
-				sendEvent(&appschema.EventMessage{Code: http.StatusBadRequest, Event: faceanalyze_events.EventError, Message: "Invalid base64"})
-				return
-			}
-			bodyBytes = decoded
-		}
-
-		contentType := req.Headers["content-type"]
-		if contentType == "" {
-			contentType = req.Headers["Content-Type"]
-		}
-		boundary := extractBoundary(contentType)
-		if boundary == "" {
-			sendEvent(&appschema.EventMessage{Code: http.StatusBadRequest, Event: faceanalyze_events.EventError, Message: "Missing multipart boundary"})
-			return
-		}
-
-		mr := multipart.NewReader(bytes.NewReader(bodyBytes), boundary)
-		var fileData []byte
-		var fileName string
-
-		for {
-			part, err := mr.NextPart()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				sendEvent(&appschema.EventMessage{Code: http.StatusBadRequest, Event: faceanalyze_events.EventError, Message: "Read error in multipart"})
-				return
-			}
-			if part.FormName() == "image" {
-				fileName = part.FileName()
-				fileData, err = io.ReadAll(part)
-				if err != nil {
-					sendEvent(&appschema.EventMessage{Code: http.StatusBadRequest, Event: faceanalyze_events.EventError, Message: "Failed to read image data"})
-					return
-				}
-				break
-			}
-		}
-
-		if len(fileData) == 0 {
-			sendEvent(&appschema.EventMessage{Code: http.StatusBadRequest, Event: faceanalyze_events.EventError, Message: "No image found"})
-			return
-		}
-
-		ext := strings.ToLower(filepath.Ext(fileName))
-		if !slices.Contains(constants.IMAGE_EXTENSIONS, ext) {
-			sendEvent(&appschema.EventMessage{Code: http.StatusBadRequest, Event: faceanalyze_events.EventError, Message: "Unsupported file extension"})
-			return
-		}
-
-		sendEvent(&appschema.EventMessage{Code: http.StatusAccepted,Event: faceanalyze_events.EventAnalyzingFace,Message: "Analyzing face",Completion: 50})
-
-		body := &bytes.Buffer{}
-		mpWriter := multipart.NewWriter(body)
-		part, err := mpWriter.CreateFormFile(constants.FACE_ANALYZE_PAYLOAD_FIELD_NAME, fileName)
-		if err != nil {
-			sendEvent(&appschema.EventMessage{Code: http.StatusInternalServerError, Event: faceanalyze_events.EventError, Message: "Failed to prepare image for scan"})
-			return
-		}
-		part.Write(fileData)
-		mpWriter.Close()
-
-		reqUrl := fmt.Sprintf("%s/%s", globals.FaceAnalyzeService.URL, constants.FACE_ANALYZE_SERVICE_PATHS[0])
-		faceReq, err := http.NewRequest(http.MethodPost, reqUrl, body)
-		if err != nil {
-			sendEvent(&appschema.EventMessage{Code: http.StatusInternalServerError, Event: faceanalyze_events.EventError, Message: "Request creation failed"})
-			return
-		}
-		faceReq.Header.Set("Authorization", os.Getenv("FACEANALYZE_SERVICE_AUTH_API_KEY"))
-		faceReq.Header.Set("Content-Type", mpWriter.FormDataContentType())
-
-		resp, err := globals.FaceAnalyzeService.Client.Do(faceReq)
-		if err != nil {
-			sendEvent(&appschema.EventMessage{Code: http.StatusInternalServerError, Event: faceanalyze_events.EventError, Message: "Face analyze call failed"})
-			return
-		}
-		defer resp.Body.Close()
+// publishEvent fans an event out to local subscribers and, when configured, the
+// external event store
+func publishEvent(streamId, event string, data []byte) {
+	globals.Stream.Publish(streamId, event, data)
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			log.Printf("Scan failed: %s", string(body))
-			sendEvent(&appschema.EventMessage{Code: http.StatusInternalServerError, Event: faceanalyze_events.EventError, Message: "Face scan error"})
-			return
+	if globals.EventStore != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := globals.EventStore.Append(ctx, streamId, event, data); err != nil {
+			log.Printf("[broker] Stream %s: append failed: %v", streamId, err)
 		}
+	}
+}
 
-		var faResp appschema.FaceScannerResponse
-		if err := utils.BindHttpResponseToStruct(resp, &faResp); err != nil {
-			sendEvent(&appschema.EventMessage{Code: http.StatusInternalServerError, Event: faceanalyze_events.EventError, Message: "Failed to parse scan response"})
-			return
-		}
+// emitEvent publishes an event on a stream and notifies the completion webhook.
+// It returns the encoded event.
+func emitEvent(streamId, callbackURL string, event *appschema.EventMessage) []byte {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("marshal error: %v", err)
+		return nil
+	}
+	publishEvent(streamId, event.Event, data)
+	notifyWebhook(callbackURL, streamId, event)
+	return data
+}
 
-		sendEvent(&appschema.EventMessage{Code: http.StatusOK,Event: faceanalyze_events.EventCompleted,Message: "Scan complete",Completion: 100,Data: &appschema.FaceScanData{Quantitative: faResp.Data.Quantitative, Qualitative: faResp.Data.Qualitative},})
-	}()
-
-	// heartbeat loop & client disconnect watch
-	go func() {
-		ticker := time.NewTicker(5 * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				log.Printf("[⚠️] Client manually disconnected from stream: %s", streamId)
-				return
-			case <-done:
-				return
-			case <-ticker.C:
-				writer.Write([]byte(": heartbeat\n\n"))
-			}
-		}
-	}()
-
-	return &events.LambdaFunctionURLStreamingResponse{
-		StatusCode: http.StatusOK,
-		Headers: map[string]string{
-			"Content-Type":                "text/event-stream",
-			"Cache-Control":               "no-cache",
-			"Connection":                  "keep-alive",
-			"Access-Control-Allow-Origin": "*",
-		},
-		Body: reader,
-	}, nil
+func isTerminalEvent(event string) bool {
+	return event == faceanalyze_events.EventCompleted || event == faceanalyze_events.EventError ||
+		event == faceanalyze_events.EventBatchCompleted || event == faceanalyze_events.EventCancelled
 }
 
-func extractBoundary(contentType string) string {
-    parts := strings.Split(contentType, ";")
-    for _, part := range parts {
-        part = strings.TrimSpace(part)
-        if strings.HasPrefix(part, "boundary=") {
-            return strings.Trim(strings.TrimPrefix(part, "boundary="), `"`)
-        }
-    }
-    return ""
+// notifyWebhook hands terminal events to the completion webhook dispatcher
+func notifyWebhook(callbackURL, streamId string, event *appschema.EventMessage) {
+	if callbackURL == "" || globals.Webhooks == nil {
+		return
+	}
+	if !isTerminalEvent(event.Event) {
+		return
+	}
+	if _, err := globals.Webhooks.Dispatch(callbackURL, streamId, *event); err != nil {
+		log.Printf("[webhook] Stream %s: dispatch failed: %v", streamId, err)
+	}
 }
\ No newline at end of file
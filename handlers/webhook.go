@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/message"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/utils"
+)
+
+// registrationOff answers the registration routes when instances cannot share
+// registrations
+const registrationOff = "webhook registration is off, pass callback_url with each upload"
+
+type WebhookHandler struct{}
+
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{}
+}
+
+// RegisterWebhook sets the default completion callback for the calling client
+func (h *WebhookHandler) RegisterWebhook(c *gin.Context) {
+	clientId, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+		return
+	}
+
+	if globals.Webhooks == nil {
+		c.JSON(http.StatusNotImplemented, message.ReturnCustomMessage("webhooks are disabled"))
+		return
+	}
+	if globals.WebhookRegistry == nil {
+		c.JSON(http.StatusNotImplemented, message.ReturnCustomMessage(registrationOff))
+		return
+	}
+
+	var body appschema.WebhookRegistration
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, message.ReturnInvalidFieldMsg())
+		return
+	}
+	if err := globals.Webhooks.ValidateURL(body.URL); err != nil {
+		c.JSON(http.StatusBadRequest, message.ReturnCustomMessage(err.Error()))
+		return
+	}
+
+	if err := globals.WebhookRegistry.Set(clientId, body.URL); err != nil {
+		log.Printf("[webhook] Client %s: saving registration failed: %v", clientId, err)
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+	c.JSON(http.StatusOK, message.ReturnCustomDataWithKey("url", body.URL))
+}
+
+// GetWebhook returns the callback registered for the calling client
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	clientId, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+		return
+	}
+
+	if globals.WebhookRegistry == nil {
+		c.JSON(http.StatusNotImplemented, message.ReturnCustomMessage(registrationOff))
+		return
+	}
+	callbackURL, ok := globals.WebhookRegistry.Get(clientId)
+	if !ok {
+		c.JSON(http.StatusNotFound, message.ReturnCustomMessage("no webhook registered"))
+		return
+	}
+	c.JSON(http.StatusOK, message.ReturnCustomDataWithKey("url", callbackURL))
+}
+
+// DeleteWebhook removes the callback registered for the calling client
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	clientId, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+		return
+	}
+
+	if globals.WebhookRegistry == nil {
+		c.JSON(http.StatusNotImplemented, message.ReturnCustomMessage(registrationOff))
+		return
+	}
+	if err := globals.WebhookRegistry.Delete(clientId); err != nil {
+		log.Printf("[webhook] Client %s: removing registration failed: %v", clientId, err)
+		c.JSON(http.StatusInternalServerError, message.ReturnSomethingWentWrongMsg())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// registeredCallback is the client's registered default callback, if
+// registration is on
+func registeredCallback(clientId string) string {
+	if globals.WebhookRegistry == nil {
+		return ""
+	}
+	callbackURL, _ := globals.WebhookRegistry.Get(clientId)
+	return callbackURL
+}
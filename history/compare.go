@@ -0,0 +1,110 @@
+package history
+
+import (
+	"slices"
+
+	"github.com/muthu-kumar-u/go-sse/annotate"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// Compare diffs two scans' results. Regions are compared in each upload's
+// original space, so scans preprocessed at different sizes still line up.
+func Compare(before, after *appschema.ScanRecord) *appschema.ScanComparison {
+	comparison := &appschema.ScanComparison{
+		Before:       compared(before),
+		After:        compared(after),
+		Quantitative: make(map[string]appschema.QuantitativeDiff),
+		Qualitative:  make(map[string]appschema.QualitativeDiff),
+		Appeared:     []string{},
+		Disappeared:  []string{},
+	}
+	b, a := before.Result, after.Result
+
+	for _, name := range union(keys(b.Quantitative), keys(a.Quantitative)) {
+		var diff appschema.QuantitativeDiff
+		bm, inBefore := b.Quantitative[name]
+		am, inAfter := a.Quantitative[name]
+		if inBefore {
+			diff.Before = &bm.Percentage
+		}
+		if inAfter {
+			diff.After = &am.Percentage
+		}
+		if inBefore && inAfter {
+			delta := am.Percentage - bm.Percentage
+			diff.Delta = &delta
+		}
+		diff.RegionOverlap = overlap(b, bm.Regions, a, am.Regions)
+		comparison.Quantitative[name] = diff
+	}
+
+	for _, name := range union(keys(b.Qualitative), keys(a.Qualitative)) {
+		diff := appschema.QualitativeDiff{Change: appschema.ChangeUnavailable}
+		bm, inBefore := b.Qualitative[name]
+		am, inAfter := a.Qualitative[name]
+		if inBefore {
+			diff.Before = &bm.IsPresent
+		}
+		if inAfter {
+			diff.After = &am.IsPresent
+		}
+		if inBefore && inAfter {
+			switch {
+			case !bm.IsPresent && am.IsPresent:
+				diff.Change = appschema.ChangeAppeared
+				comparison.Appeared = append(comparison.Appeared, name)
+			case bm.IsPresent && !am.IsPresent:
+				diff.Change = appschema.ChangeDisappeared
+				comparison.Disappeared = append(comparison.Disappeared, name)
+			default:
+				diff.Change = appschema.ChangeUnchanged
+			}
+		}
+		diff.RegionOverlap = overlap(b, bm.Regions, a, am.Regions)
+		comparison.Qualitative[name] = diff
+	}
+	return comparison
+}
+
+func compared(record *appschema.ScanRecord) appschema.ComparedScan {
+	return appschema.ComparedScan{ID: record.ID, JobID: record.JobID, ImageIndex: record.ImageIndex, CreatedAt: record.CreatedAt}
+}
+
+func overlap(before *appschema.FaceScanData, b []appschema.Region, after *appschema.FaceScanData, a []appschema.Region) *float64 {
+	iou, ok := annotate.Overlap(toOriginal(before, b), toOriginal(after, a))
+	if !ok {
+		return nil
+	}
+	return &iou
+}
+
+// toOriginal maps regions found in a preprocessed image back onto the upload
+func toOriginal(result *appschema.FaceScanData, regions []appschema.Region) []appschema.Region {
+	if result.Transform == nil {
+		return regions
+	}
+	mapped := make([]appschema.Region, len(regions))
+	for i, region := range regions {
+		points := make([]appschema.Point, len(region.Points))
+		for j, p := range region.Points {
+			points[j].X, points[j].Y = result.Transform.ToOriginal(p.X, p.Y)
+		}
+		mapped[i] = appschema.Region{Type: region.Type, Points: points}
+	}
+	return mapped
+}
+
+func keys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for key := range m {
+		out = append(out, key)
+	}
+	return out
+}
+
+// union is the sorted, distinct names of a and b
+func union(a, b []string) []string {
+	names := append(a, b...)
+	slices.Sort(names)
+	return slices.Compact(names)
+}
@@ -0,0 +1,131 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+func boxAt(x, y, size float64) []appschema.Region {
+	return []appschema.Region{{Type: appschema.RegionBox, Points: []appschema.Point{{X: x, Y: y}, {X: x + size, Y: y + size}}}}
+}
+
+func TestCompareQuantitative(t *testing.T) {
+	before := &appschema.ScanRecord{ID: "s1", CreatedAt: time.Now(), Result: &appschema.FaceScanData{
+		Quantitative: map[string]appschema.QuantitativeMetric{
+			"acne":     {Percentage: 10, Regions: boxAt(0, 0, 100)},
+			"wrinkles": {Percentage: 30},
+		},
+	}}
+	after := &appschema.ScanRecord{ID: "s2", CreatedAt: time.Now(), Result: &appschema.FaceScanData{
+		Quantitative: map[string]appschema.QuantitativeMetric{
+			"acne":  {Percentage: 4, Regions: boxAt(0, 0, 100)},
+			"pores": {Percentage: 12},
+		},
+	}}
+	comparison := Compare(before, after)
+
+	ptr := func(v float64) *float64 { return &v }
+	tests := []struct {
+		metric               string
+		before, after, delta *float64
+		wantOverlap          bool
+	}{
+		{metric: "acne", before: ptr(10), after: ptr(4), delta: ptr(-6), wantOverlap: true},
+		{metric: "wrinkles", before: ptr(30)},
+		{metric: "pores", after: ptr(12)},
+	}
+	show := func(v *float64) string {
+		if v == nil {
+			return "nil"
+		}
+		return fmt.Sprint(*v)
+	}
+	for _, tt := range tests {
+		diff, ok := comparison.Quantitative[tt.metric]
+		if !ok {
+			t.Errorf("%s missing from the comparison", tt.metric)
+			continue
+		}
+		if show(diff.Before) != show(tt.before) || show(diff.After) != show(tt.after) || show(diff.Delta) != show(tt.delta) {
+			t.Errorf("%s = before %s after %s delta %s, want %s %s %s", tt.metric,
+				show(diff.Before), show(diff.After), show(diff.Delta), show(tt.before), show(tt.after), show(tt.delta))
+		}
+		if (diff.RegionOverlap != nil) != tt.wantOverlap {
+			t.Errorf("%s overlap = %s, want set %v", tt.metric, show(diff.RegionOverlap), tt.wantOverlap)
+		}
+	}
+	if iou := *comparison.Quantitative["acne"].RegionOverlap; math.Abs(iou-1) > 0.01 {
+		t.Errorf("acne overlap = %v, want 1 for the same box", iou)
+	}
+	if comparison.Before.ID != "s1" || comparison.After.ID != "s2" {
+		t.Errorf("compared %s to %s", comparison.Before.ID, comparison.After.ID)
+	}
+}
+
+func TestCompareQualitative(t *testing.T) {
+	before := &appschema.ScanRecord{Result: &appschema.FaceScanData{Qualitative: map[string]appschema.QualitativeMetric{
+		"redness": {IsPresent: false},
+		"spots":   {IsPresent: true},
+		"moles":   {IsPresent: true},
+		"scars":   {IsPresent: true},
+	}}}
+	after := &appschema.ScanRecord{Result: &appschema.FaceScanData{Qualitative: map[string]appschema.QualitativeMetric{
+		"redness":  {IsPresent: true},
+		"spots":    {IsPresent: false},
+		"moles":    {IsPresent: true},
+		"freckles": {IsPresent: true},
+	}}}
+	comparison := Compare(before, after)
+
+	want := map[string]string{
+		"redness":  appschema.ChangeAppeared,
+		"spots":    appschema.ChangeDisappeared,
+		"moles":    appschema.ChangeUnchanged,
+		"scars":    appschema.ChangeUnavailable,
+		"freckles": appschema.ChangeUnavailable,
+	}
+	for metric, change := range want {
+		if got := comparison.Qualitative[metric].Change; got != change {
+			t.Errorf("%s = %q, want %q", metric, got, change)
+		}
+	}
+	if fmt.Sprint(comparison.Appeared) != "[redness]" || fmt.Sprint(comparison.Disappeared) != "[spots]" {
+		t.Errorf("appeared %v, disappeared %v", comparison.Appeared, comparison.Disappeared)
+	}
+}
+
+func TestCompareOverlapInOriginalSpace(t *testing.T) {
+	tests := []struct {
+		name  string
+		scale float64
+		want  float64
+	}{
+		// the after scan was downscaled by half, so its box maps back onto the same area
+		{name: "preprocessed at half size", scale: 0.5, want: 1},
+		// without the transform the half-size box covers a quarter of the other
+		{name: "no transform", want: 0.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := &appschema.ScanRecord{Result: &appschema.FaceScanData{Quantitative: map[string]appschema.QuantitativeMetric{
+				"acne": {Regions: boxAt(0, 0, 200)},
+			}}}
+			afterResult := &appschema.FaceScanData{Quantitative: map[string]appschema.QuantitativeMetric{
+				"acne": {Regions: boxAt(0, 0, 100)},
+			}}
+			if tt.scale != 0 {
+				afterResult.Transform = &appschema.ImageTransform{Scale: tt.scale}
+			}
+			comparison := Compare(before, &appschema.ScanRecord{Result: afterResult})
+
+			got := comparison.Quantitative["acne"].RegionOverlap
+			if got == nil || math.Abs(*got-tt.want) > 0.02 {
+				t.Fatalf("overlap = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// MemoryStore keeps scan records in process memory, up to a number per owner
+type MemoryStore struct {
+	mu          sync.RWMutex
+	records     map[string][]byte
+	owned       map[string][]scanRef
+	maxPerOwner int
+}
+
+// scanRef places one of an owner's records for eviction
+type scanRef struct {
+	id        string
+	createdAt time.Time
+}
+
+// NewMemoryStore keeps each owner's newest maxPerOwner scans; zero keeps them
+// all
+func NewMemoryStore(maxPerOwner int) *MemoryStore {
+	return &MemoryStore{
+		records:     make(map[string][]byte),
+		owned:       make(map[string][]scanRef),
+		maxPerOwner: maxPerOwner,
+	}
+}
+
+// records are stored encoded so callers never share mutable state with the store
+func (s *MemoryStore) Save(ctx context.Context, record *appschema.ScanRecord) error {
+	data, err := json.Marshal(storedRecord{record, record.OwnerID})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[record.ID]; !ok {
+		s.owned[record.OwnerID] = append(s.owned[record.OwnerID], scanRef{record.ID, record.CreatedAt})
+	}
+	s.records[record.ID] = data
+	s.evict(record.OwnerID)
+	return nil
+}
+
+// evict drops the owner's oldest scans beyond the cap
+func (s *MemoryStore) evict(ownerID string) {
+	refs := s.owned[ownerID]
+	for s.maxPerOwner > 0 && len(refs) > s.maxPerOwner {
+		oldest := 0
+		for i, ref := range refs {
+			if ref.createdAt.Before(refs[oldest].createdAt) {
+				oldest = i
+			}
+		}
+		delete(s.records, refs[oldest].id)
+		refs = append(refs[:oldest], refs[oldest+1:]...)
+	}
+	s.owned[ownerID] = refs
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*appschema.ScanRecord, error) {
+	s.mu.RLock()
+	data, ok := s.records[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return decodeRecord(data)
+}
+
+func (s *MemoryStore) List(ctx context.Context, ownerID string, query Query) ([]appschema.ScanRecord, error) {
+	s.mu.RLock()
+	var owned []appschema.ScanRecord
+	for _, data := range s.records {
+		record, err := decodeRecord(data)
+		if err != nil {
+			s.mu.RUnlock()
+			return nil, err
+		}
+		if record.OwnerID == ownerID && query.matches(record) {
+			owned = append(owned, *record)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(owned, func(i, j int) bool { return owned[i].CreatedAt.After(owned[j].CreatedAt) })
+	if query.Offset >= len(owned) {
+		return []appschema.ScanRecord{}, nil
+	}
+	owned = owned[query.Offset:]
+	if query.Limit > 0 && len(owned) > query.Limit {
+		owned = owned[:query.Limit]
+	}
+	return owned, nil
+}
+
+// storedRecord keeps the owner, which the API representation hides
+type storedRecord struct {
+	*appschema.ScanRecord
+	Owner string `json:"owner_id"`
+}
+
+func decodeRecord(data []byte) (*appschema.ScanRecord, error) {
+	var stored storedRecord
+	stored.ScanRecord = &appschema.ScanRecord{}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	stored.ScanRecord.OwnerID = stored.Owner
+	return stored.ScanRecord, nil
+}
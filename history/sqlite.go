@@ -0,0 +1,131 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS scans (
+	id          TEXT PRIMARY KEY,
+	owner_id    TEXT NOT NULL,
+	job_id      TEXT NOT NULL,
+	image_index INTEGER NOT NULL DEFAULT 0,
+	analysis    TEXT NOT NULL,
+	filename    TEXT NOT NULL DEFAULT '',
+	result      TEXT NOT NULL,
+	created_at  INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS scans_owner_created ON scans (owner_id, created_at DESC);
+`
+
+// SQLiteStore persists scan records in a SQLite database file
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	// one writer avoids SQLITE_BUSY between workers
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) Save(ctx context.Context, record *appschema.ScanRecord) error {
+	result, err := json.Marshal(record.Result)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO scans (id, owner_id, job_id, image_index, analysis, filename, result, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET result = excluded.result`,
+		record.ID, record.OwnerID, record.JobID, record.ImageIndex, record.Analysis, record.Filename, string(result), record.CreatedAt.UnixMilli(),
+	)
+	return err
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, id string) (*appschema.ScanRecord, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+sqliteColumns+` FROM scans WHERE id = ?`, id)
+	record, err := scanRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return record, err
+}
+
+func (s *SQLiteStore) List(ctx context.Context, ownerID string, query Query) ([]appschema.ScanRecord, error) {
+	where, args := `owner_id = ?`, []any{ownerID}
+	if query.Analysis != "" {
+		where, args = where+` AND analysis = ?`, append(args, query.Analysis)
+	}
+	if !query.From.IsZero() {
+		where, args = where+` AND created_at >= ?`, append(args, query.From.UnixMilli())
+	}
+	if !query.To.IsZero() {
+		where, args = where+` AND created_at <= ?`, append(args, query.To.UnixMilli())
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+sqliteColumns+` FROM scans WHERE `+where+` ORDER BY created_at DESC LIMIT ? OFFSET ?`,
+		append(args, limit, query.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []appschema.ScanRecord{}
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *record)
+	}
+	return records, rows.Err()
+}
+
+const sqliteColumns = `id, owner_id, job_id, image_index, analysis, filename, result, created_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRecord(row rowScanner) (*appschema.ScanRecord, error) {
+	var (
+		record    appschema.ScanRecord
+		result    string
+		createdAt int64
+	)
+	err := row.Scan(&record.ID, &record.OwnerID, &record.JobID, &record.ImageIndex, &record.Analysis, &record.Filename, &result, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	record.Result = &appschema.FaceScanData{}
+	if err := json.Unmarshal([]byte(result), record.Result); err != nil {
+		return nil, err
+	}
+	record.CreatedAt = time.UnixMilli(createdAt)
+	return &record, nil
+}
@@ -0,0 +1,37 @@
+// Package history keeps each user's completed scans so progress can be followed
+// over time.
+package history
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+var ErrNotFound = errors.New("scan not found")
+
+// Query narrows a listing. Zero values leave a field unfiltered; a
+// non-positive Limit returns every match.
+type Query struct {
+	Analysis string
+	// From and To bound CreatedAt, both inclusive
+	From, To time.Time
+	Limit    int
+	Offset   int
+}
+
+func (q Query) matches(record *appschema.ScanRecord) bool {
+	return (q.Analysis == "" || record.Analysis == q.Analysis) &&
+		(q.From.IsZero() || !record.CreatedAt.Before(q.From)) &&
+		(q.To.IsZero() || !record.CreatedAt.After(q.To))
+}
+
+// Store persists scan records
+type Store interface {
+	Save(ctx context.Context, record *appschema.ScanRecord) error
+	Get(ctx context.Context, id string) (*appschema.ScanRecord, error)
+	// List returns the owner's scans matching query, newest first
+	List(ctx context.Context, ownerID string, query Query) ([]appschema.ScanRecord, error)
+}
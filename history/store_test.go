@@ -0,0 +1,138 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// stores returns each backend, empty
+func stores(t *testing.T) map[string]Store {
+	sqlite, err := NewSQLiteStore(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+	return map[string]Store{"memory": NewMemoryStore(0), "sqlite": sqlite}
+}
+
+func TestStoreSaveGet(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := store.Get(ctx, "s1"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Get before Save = %v, want ErrNotFound", err)
+			}
+			err := store.Save(ctx, &appschema.ScanRecord{
+				ID: "s1", OwnerID: "u1", JobID: "j1", ImageIndex: 2, Analysis: "face", Filename: "face.jpg",
+				Result:    &appschema.FaceScanData{Quantitative: map[string]appschema.QuantitativeMetric{"acne": {Percentage: 4}}},
+				CreatedAt: now,
+			})
+			if err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			got, err := store.Get(ctx, "s1")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got.OwnerID != "u1" || got.JobID != "j1" || got.ImageIndex != 2 || got.Analysis != "face" ||
+				got.Result.Quantitative["acne"].Percentage != 4 || !got.CreatedAt.Equal(now) {
+				t.Fatalf("Get = %+v", got)
+			}
+		})
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return base.AddDate(0, 0, n) }
+	tests := []struct {
+		name  string
+		owner string
+		query Query
+		want  []string
+	}{
+		{name: "newest first", owner: "u1", want: []string{"s4", "s3", "s2", "s1"}},
+		{name: "by analysis", owner: "u1", query: Query{Analysis: "skin"}, want: []string{"s3"}},
+		{name: "from is inclusive", owner: "u1", query: Query{From: day(2)}, want: []string{"s4", "s3"}},
+		{name: "to is inclusive", owner: "u1", query: Query{To: day(1)}, want: []string{"s2", "s1"}},
+		{name: "range", owner: "u1", query: Query{From: day(1), To: day(2)}, want: []string{"s3", "s2"}},
+		{name: "limit and offset", owner: "u1", query: Query{Limit: 2, Offset: 1}, want: []string{"s3", "s2"}},
+		{name: "offset past the end", owner: "u1", query: Query{Offset: 10}, want: []string{}},
+		{name: "other owner", owner: "u2", want: []string{"s5"}},
+	}
+	for name, store := range stores(t) {
+		ctx := context.Background()
+		for i, scan := range []struct{ owner, analysis string }{
+			{"u1", "face"}, {"u1", "face"}, {"u1", "skin"}, {"u1", "face"}, {"u2", "face"},
+		} {
+			store.Save(ctx, &appschema.ScanRecord{
+				ID:        fmt.Sprintf("s%d", i+1),
+				OwnerID:   scan.owner,
+				Analysis:  scan.analysis,
+				Result:    &appschema.FaceScanData{},
+				CreatedAt: day(i),
+			})
+		}
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				records, err := store.List(ctx, tt.owner, tt.query)
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				got := []string{}
+				for _, record := range records {
+					got = append(got, record.ID)
+				}
+				if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+					t.Fatalf("List = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestMemoryStoreCap(t *testing.T) {
+	ctx := context.Background()
+	base := time.Now().Truncate(time.Millisecond)
+	store := NewMemoryStore(2)
+	// saved out of order; the oldest scans go first whatever the order
+	for _, scan := range []struct {
+		id, owner string
+		at        int
+	}{
+		{"s2", "u1", 2}, {"s1", "u1", 1}, {"s3", "u1", 3}, {"s3", "u1", 3}, {"o1", "u2", 0}, {"s4", "u1", 4},
+	} {
+		store.Save(ctx, &appschema.ScanRecord{ID: scan.id, OwnerID: scan.owner, CreatedAt: base.Add(time.Duration(scan.at) * time.Minute)})
+	}
+
+	tests := []struct {
+		owner string
+		want  []string
+	}{
+		{owner: "u1", want: []string{"s4", "s3"}},
+		{owner: "u2", want: []string{"o1"}},
+	}
+	for _, tt := range tests {
+		records, err := store.List(ctx, tt.owner, Query{})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		got := []string{}
+		for _, record := range records {
+			got = append(got, record.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("List(%s) = %v, want %v", tt.owner, got, tt.want)
+		}
+	}
+	if _, err := store.Get(ctx, "s1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(s1) = %v, want ErrNotFound once evicted", err)
+	}
+}
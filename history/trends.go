@@ -0,0 +1,56 @@
+package history
+
+import (
+	"sort"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// Trends follows each metric across records, in any order. A metric missing
+// from a scan is left out of that scan's samples rather than counted as zero.
+func Trends(records []appschema.ScanRecord) *appschema.ScanTrends {
+	sorted := make([]appschema.ScanRecord, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt.Before(sorted[j].CreatedAt) })
+
+	trends := &appschema.ScanTrends{
+		Scans:        len(sorted),
+		Quantitative: make(map[string]appschema.QuantitativeTrend),
+		Qualitative:  make(map[string]appschema.QualitativeTrend),
+	}
+	for _, record := range sorted {
+		if record.Result == nil {
+			continue
+		}
+		for name, metric := range record.Result.Quantitative {
+			trend := trends.Quantitative[name]
+			if trend.Samples == 0 {
+				trend.First, trend.Min, trend.Max = metric.Percentage, metric.Percentage, metric.Percentage
+			}
+			trend.Samples++
+			trend.Last = metric.Percentage
+			trend.Change = trend.Last - trend.First
+			trend.Min = min(trend.Min, metric.Percentage)
+			trend.Max = max(trend.Max, metric.Percentage)
+			// running mean, so no second pass is needed
+			trend.Mean += (metric.Percentage - trend.Mean) / float64(trend.Samples)
+			trend.Series = append(trend.Series, appschema.TrendPoint{ScanID: record.ID, At: record.CreatedAt, Percentage: metric.Percentage})
+			trends.Quantitative[name] = trend
+		}
+		for name, metric := range record.Result.Qualitative {
+			trend := trends.Qualitative[name]
+			trend.Samples++
+			trend.PresentLatest = metric.IsPresent
+			if metric.IsPresent {
+				at := record.CreatedAt
+				trend.PresentIn++
+				trend.LastSeen = &at
+				if trend.FirstSeen == nil {
+					trend.FirstSeen, trend.FirstSeenScan = &at, record.ID
+				}
+			}
+			trends.Qualitative[name] = trend
+		}
+	}
+	return trends
+}
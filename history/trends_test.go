@@ -0,0 +1,97 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+func scan(id string, at time.Time, quantitative map[string]float64, qualitative map[string]bool) appschema.ScanRecord {
+	result := &appschema.FaceScanData{
+		Quantitative: make(map[string]appschema.QuantitativeMetric),
+		Qualitative:  make(map[string]appschema.QualitativeMetric),
+	}
+	for name, percentage := range quantitative {
+		result.Quantitative[name] = appschema.QuantitativeMetric{Percentage: percentage}
+	}
+	for name, present := range qualitative {
+		result.Qualitative[name] = appschema.QualitativeMetric{IsPresent: present}
+	}
+	return appschema.ScanRecord{ID: id, CreatedAt: at, Result: result}
+}
+
+func TestTrendsQuantitative(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// out of order, with acne missing from the middle scan
+	records := []appschema.ScanRecord{
+		scan("s3", base.AddDate(0, 0, 2), map[string]float64{"acne": 6, "wrinkles": 20}, nil),
+		scan("s1", base, map[string]float64{"acne": 10, "wrinkles": 30}, nil),
+		scan("s2", base.AddDate(0, 0, 1), map[string]float64{"wrinkles": 10}, nil),
+		{ID: "empty", CreatedAt: base.AddDate(0, 0, 3)},
+	}
+	trends := Trends(records)
+	if trends.Scans != 4 {
+		t.Fatalf("Scans = %d, want 4", trends.Scans)
+	}
+
+	tests := []struct {
+		metric string
+		want   appschema.QuantitativeTrend
+		series []string
+	}{
+		{metric: "acne", want: appschema.QuantitativeTrend{Samples: 2, First: 10, Last: 6, Change: -4, Min: 6, Max: 10, Mean: 8}, series: []string{"s1", "s3"}},
+		{metric: "wrinkles", want: appschema.QuantitativeTrend{Samples: 3, First: 30, Last: 20, Change: -10, Min: 10, Max: 30, Mean: 20}, series: []string{"s1", "s2", "s3"}},
+	}
+	for _, tt := range tests {
+		got := trends.Quantitative[tt.metric]
+		series := got.Series
+		if got.Samples != tt.want.Samples || got.First != tt.want.First || got.Last != tt.want.Last || got.Change != tt.want.Change ||
+			got.Min != tt.want.Min || got.Max != tt.want.Max || got.Mean != tt.want.Mean {
+			t.Errorf("%s = %+v, want %+v", tt.metric, got, tt.want)
+		}
+		if len(series) != len(tt.series) {
+			t.Fatalf("%s series has %d points, want %d", tt.metric, len(series), len(tt.series))
+		}
+		for i, id := range tt.series {
+			if series[i].ScanID != id {
+				t.Errorf("%s series[%d] = %s, want %s", tt.metric, i, series[i].ScanID, id)
+			}
+		}
+	}
+}
+
+func TestTrendsQualitative(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []appschema.ScanRecord{
+		scan("s1", base, nil, map[string]bool{"redness": false, "spots": true}),
+		scan("s2", base.AddDate(0, 0, 1), nil, map[string]bool{"redness": true, "spots": true}),
+		scan("s3", base.AddDate(0, 0, 2), nil, map[string]bool{"redness": true, "spots": false}),
+	}
+	trends := Trends(records)
+
+	tests := []struct {
+		metric        string
+		presentIn     int
+		firstSeenScan string
+		lastSeen      time.Time
+		latest        bool
+	}{
+		{metric: "redness", presentIn: 2, firstSeenScan: "s2", lastSeen: base.AddDate(0, 0, 2), latest: true},
+		{metric: "spots", presentIn: 2, firstSeenScan: "s1", lastSeen: base.AddDate(0, 0, 1), latest: false},
+	}
+	for _, tt := range tests {
+		got := trends.Qualitative[tt.metric]
+		if got.Samples != 3 || got.PresentIn != tt.presentIn || got.FirstSeenScan != tt.firstSeenScan ||
+			got.LastSeen == nil || !got.LastSeen.Equal(tt.lastSeen) || got.PresentLatest != tt.latest {
+			t.Errorf("%s = %+v", tt.metric, got)
+		}
+	}
+}
+
+func TestTrendsEmpty(t *testing.T) {
+	trends := Trends(nil)
+	if trends.Scans != 0 || len(trends.Quantitative) != 0 || len(trends.Qualitative) != 0 {
+		t.Fatalf("Trends(nil) = %+v", trends)
+	}
+}
@@ -0,0 +1,160 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// DynamoDBStore keeps job records in a table keyed by id (partition, string),
+// shared by every instance. Listing needs the owner_created index: owner_id
+// (partition, string) and created_at (sort, number). Items carry an expires_at
+// epoch for the table's TTL setting.
+type DynamoDBStore struct {
+	client dynamodbiface.DynamoDBAPI
+	table  string
+}
+
+// ownerIndex is the secondary index List queries
+const ownerIndex = "owner_created"
+
+func NewDynamoDBStore(sess *session.Session, table string) *DynamoDBStore {
+	return &DynamoDBStore{client: dynamodb.New(sess), table: table}
+}
+
+// Save writes the whole record; the status also lives in an attribute of its
+// own so Transition can check it
+func (s *DynamoDBStore) Save(ctx context.Context, record *appschema.JobRecord) error {
+	data, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item: map[string]*dynamodb.AttributeValue{
+			"id":         {S: aws.String(record.ID)},
+			"owner_id":   {S: aws.String(record.OwnerID)},
+			"created_at": {N: aws.String(strconv.FormatInt(record.CreatedAt.UnixMilli(), 10))},
+			"status":     {S: aws.String(string(record.Status))},
+			"expires_at": {N: aws.String(strconv.FormatInt(record.ExpiresAt.Unix(), 10))},
+			"record":     {B: data},
+		},
+	})
+	return err
+}
+
+// Get ignores records past their retention that TTL has not removed yet
+func (s *DynamoDBStore) Get(ctx context.Context, id string) (*appschema.JobRecord, error) {
+	out, err := s.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Item == nil {
+		return nil, ErrNotFound
+	}
+	record, err := recordFromItem(out.Item)
+	if err != nil {
+		return nil, err
+	}
+	if record.ExpiresAt.Before(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return record, nil
+}
+
+func (s *DynamoDBStore) List(ctx context.Context, ownerID string, limit, offset int) ([]appschema.JobRecord, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String(ownerIndex),
+		KeyConditionExpression: aws.String("owner_id = :owner"),
+		FilterExpression:       aws.String("expires_at >= :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {S: aws.String(ownerID)},
+			":now":   {N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}
+
+	var (
+		records   = []appschema.JobRecord{}
+		skipped   int
+		decodeErr error
+	)
+	err := s.client.QueryPagesWithContext(ctx, input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			record, err := recordFromItem(item)
+			if err != nil {
+				decodeErr = err
+				return false
+			}
+			records = append(records, *record)
+			if limit > 0 && len(records) == limit {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, decodeErr
+}
+
+// Purge leaves expired records to the table's TTL setting
+func (s *DynamoDBStore) Purge(ctx context.Context, now time.Time) (int, error) {
+	return 0, nil
+}
+
+// Transition sets the status with a conditional update, so of several
+// instances racing to move a job only one succeeds. The record's stages are
+// left to the event that follows.
+func (s *DynamoDBStore) Transition(ctx context.Context, id string, from, to appschema.JobStatus) error {
+	_, err := s.client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(s.table),
+		Key:                      map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}},
+		UpdateExpression:         aws.String("SET #status = :to"),
+		ConditionExpression:      aws.String("#status = :from"),
+		ExpressionAttributeNames: map[string]*string{"#status": aws.String("status")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":from": {S: aws.String(string(from))},
+			":to":   {S: aws.String(string(to))},
+		},
+	})
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrStatusChanged
+	}
+	return err
+}
+
+func recordFromItem(item map[string]*dynamodb.AttributeValue) (*appschema.JobRecord, error) {
+	attr, ok := item["record"]
+	if !ok {
+		return nil, errors.New("job item has no record")
+	}
+	record, err := decodeRecord(attr.B)
+	if err != nil {
+		return nil, err
+	}
+	// a transition only updates the status attribute
+	if status := item["status"]; status != nil && status.S != nil {
+		record.Status = appschema.JobStatus(*status.S)
+	}
+	return record, nil
+}
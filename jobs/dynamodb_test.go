@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// fakeTable is just enough of DynamoDB for the job store: puts, consistent
+// gets, conditional status updates and owner index queries
+type fakeTable struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu    sync.Mutex
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func newFakeTable() *fakeTable {
+	return &fakeTable{items: make(map[string]map[string]*dynamodb.AttributeValue)}
+}
+
+func (f *fakeTable) PutItemWithContext(ctx aws.Context, in *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.items[*in.Item["id"].S] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeTable) GetItemWithContext(ctx aws.Context, in *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return &dynamodb.GetItemOutput{Item: f.items[*in.Key["id"].S]}, nil
+}
+
+func (f *fakeTable) UpdateItemWithContext(ctx aws.Context, in *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	item := f.items[*in.Key["id"].S]
+	if item == nil || *item["status"].S != *in.ExpressionAttributeValues[":from"].S {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	updated := make(map[string]*dynamodb.AttributeValue, len(item))
+	for name, value := range item {
+		updated[name] = value
+	}
+	updated["status"] = in.ExpressionAttributeValues[":to"]
+	f.items[*in.Key["id"].S] = updated
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (f *fakeTable) QueryPagesWithContext(ctx aws.Context, in *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, _ ...request.Option) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	owner := *in.ExpressionAttributeValues[":owner"].S
+	now, _ := strconv.ParseInt(*in.ExpressionAttributeValues[":now"].N, 10, 64)
+	page := &dynamodb.QueryOutput{}
+	for _, item := range f.items {
+		expiresAt, _ := strconv.ParseInt(*item["expires_at"].N, 10, 64)
+		if *item["owner_id"].S == owner && expiresAt >= now {
+			page.Items = append(page.Items, item)
+		}
+	}
+	createdAt := func(i int) int64 {
+		n, _ := strconv.ParseInt(*page.Items[i]["created_at"].N, 10, 64)
+		return n
+	}
+	sort.Slice(page.Items, func(i, j int) bool { return createdAt(i) > createdAt(j) })
+	fn(page, true)
+	return nil
+}
@@ -0,0 +1,130 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// MemoryStore keeps job records in process memory
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string][]byte)}
+}
+
+// records are stored encoded so callers never share mutable state with the store
+func (s *MemoryStore) Save(ctx context.Context, record *appschema.JobRecord) error {
+	data, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.ID] = data
+	return nil
+}
+
+func (s *MemoryStore) Transition(ctx context.Context, id string, from, to appschema.JobStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.records[id]
+	if !ok {
+		return ErrStatusChanged
+	}
+	record, err := decodeRecord(data)
+	if err != nil {
+		return err
+	}
+	if record.Status != from {
+		return ErrStatusChanged
+	}
+	record.Status = to
+	if data, err = encodeRecord(record); err != nil {
+		return err
+	}
+	s.records[id] = data
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*appschema.JobRecord, error) {
+	s.mu.RLock()
+	data, ok := s.records[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return decodeRecord(data)
+}
+
+func (s *MemoryStore) List(ctx context.Context, ownerID string, limit, offset int) ([]appschema.JobRecord, error) {
+	s.mu.RLock()
+	var owned []appschema.JobRecord
+	for _, data := range s.records {
+		record, err := decodeRecord(data)
+		if err != nil {
+			s.mu.RUnlock()
+			return nil, err
+		}
+		if record.OwnerID == ownerID {
+			owned = append(owned, *record)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(owned, func(i, j int) bool { return owned[i].CreatedAt.After(owned[j].CreatedAt) })
+	if offset >= len(owned) {
+		return []appschema.JobRecord{}, nil
+	}
+	owned = owned[offset:]
+	if limit > 0 && len(owned) > limit {
+		owned = owned[:limit]
+	}
+	return owned, nil
+}
+
+func (s *MemoryStore) Purge(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for id, data := range s.records {
+		record, err := decodeRecord(data)
+		if err != nil || record.ExpiresAt.Before(now) {
+			delete(s.records, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// storedRecord keeps the owner and callback, which the API representation hides
+type storedRecord struct {
+	*appschema.JobRecord
+	Owner    string `json:"owner_id"`
+	Callback string `json:"callback_url,omitempty"`
+}
+
+func encodeRecord(record *appschema.JobRecord) ([]byte, error) {
+	return json.Marshal(storedRecord{record, record.OwnerID, record.CallbackURL})
+}
+
+func decodeRecord(data []byte) (*appschema.JobRecord, error) {
+	var stored storedRecord
+	stored.JobRecord = &appschema.JobRecord{}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	stored.JobRecord.OwnerID = stored.Owner
+	stored.JobRecord.CallbackURL = stored.Callback
+	return stored.JobRecord, nil
+}
@@ -0,0 +1,222 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+var ErrQueueFull = errors.New("job queue is full")
+
+// ErrCancelled is the cause of a job context ended by Cancel
+var ErrCancelled = errors.New("job cancelled")
+
+// Runner processes a single job. ctx is cancelled when the job times out, is
+// cancelled or the pool shuts down; context.Cause tells which.
+type Runner func(ctx context.Context, job *appschema.FaceScanJob)
+
+type Config struct {
+	Workers    int
+	QueueDepth int
+	Timeout    time.Duration
+	// MaxImages caps how many images one upload may carry
+	MaxImages int
+	// ImageParallelism bounds concurrent upstream calls within one job
+	ImageParallelism int
+}
+
+// Pool runs queued jobs on a fixed number of workers
+type Pool struct {
+	config Config
+	run    Runner
+	queue  chan *appschema.FaceScanJob
+	busy   atomic.Int64
+	wg     sync.WaitGroup
+
+	// OnCancelQueued, when set, reports a job cancelled before any worker
+	// picked it up. Such a job never reaches the Runner, so this is the only
+	// word of its outcome. Set it before Start.
+	OnCancelQueued func(job *appschema.FaceScanJob)
+
+	mu sync.Mutex
+	// queued and running jobs by ID
+	active map[string]*activeJob
+}
+
+// activeJob tracks a job until it finishes so it can be cancelled. A job
+// cancelled while still queued is settled there and then, and skipped by the
+// worker that dequeues it.
+type activeJob struct {
+	job       *appschema.FaceScanJob
+	cancel    context.CancelCauseFunc
+	cancelled bool
+}
+
+type Stats struct {
+	Workers    int   `json:"workers"`
+	Busy       int64 `json:"busy"`
+	Queued     int   `json:"queued"`
+	QueueDepth int   `json:"queue_depth"`
+}
+
+func NewPool(config Config, run Runner) *Pool {
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+	if config.QueueDepth <= 0 {
+		config.QueueDepth = 64
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 2 * time.Minute
+	}
+	if config.MaxImages <= 0 {
+		config.MaxImages = 10
+	}
+	if config.ImageParallelism <= 0 {
+		config.ImageParallelism = 2
+	}
+	return &Pool{
+		config: config,
+		run:    run,
+		queue:  make(chan *appschema.FaceScanJob, config.QueueDepth),
+		active: make(map[string]*activeJob),
+	}
+}
+
+// Start launches the workers; they exit once ctx is cancelled
+func (p *Pool) Start(ctx context.Context) {
+	for i := 0; i < p.config.Workers; i++ {
+		p.wg.Add(1)
+		go p.worker(ctx)
+	}
+	log.Printf("[jobs] Started %d workers (queue depth %d)", p.config.Workers, p.config.QueueDepth)
+}
+
+// Wait blocks until every worker has exited
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+// Enqueue adds a job without blocking and fails with ErrQueueFull when the
+// queue is at capacity
+func (p *Pool) Enqueue(job *appschema.FaceScanJob) error {
+	p.mu.Lock()
+	p.active[job.ID] = &activeJob{job: job}
+	p.mu.Unlock()
+
+	select {
+	case p.queue <- job:
+		return nil
+	default:
+		p.mu.Lock()
+		delete(p.active, job.ID)
+		p.mu.Unlock()
+		return ErrQueueFull
+	}
+}
+
+// Run processes job on the calling goroutine, for callers that must see it
+// finish. It skips the queue but is otherwise handled like a queued job: bound
+// by the job timeout and cancellable through Cancel while it runs.
+func (p *Pool) Run(ctx context.Context, job *appschema.FaceScanJob) {
+	p.process(ctx, job)
+}
+
+// Cancel stops a queued or running job. A running job's context ends with
+// ErrCancelled; a queued one is reported to OnCancelQueued right away instead
+// of waiting for a worker. It reports false when the pool has no such job,
+// e.g. because it already finished.
+func (p *Pool) Cancel(jobID string) bool {
+	p.mu.Lock()
+	active, ok := p.active[jobID]
+	if !ok {
+		p.mu.Unlock()
+		return false
+	}
+	alreadyCancelled := active.cancelled
+	active.cancelled = true
+	if active.cancel != nil {
+		active.cancel(ErrCancelled)
+		p.mu.Unlock()
+		return true
+	}
+	p.mu.Unlock()
+
+	if !alreadyCancelled && p.OnCancelQueued != nil {
+		p.OnCancelQueued(active.job)
+	}
+	return true
+}
+
+// Config returns the pool's settings with defaults applied
+func (p *Pool) Config() Config {
+	return p.config
+}
+
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Workers:    p.config.Workers,
+		Busy:       p.busy.Load(),
+		Queued:     len(p.queue),
+		QueueDepth: p.config.QueueDepth,
+	}
+}
+
+func (p *Pool) worker(ctx context.Context) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-p.queue:
+			p.process(ctx, job)
+		}
+	}
+}
+
+func (p *Pool) process(ctx context.Context, job *appschema.FaceScanJob) {
+	p.busy.Add(1)
+	defer p.busy.Add(-1)
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[jobs] Job %s panicked: %v", job.ID, r)
+		}
+	}()
+
+	ctx, cancelJob := context.WithCancelCause(ctx)
+	p.mu.Lock()
+	active, ok := p.active[job.ID]
+	if !ok {
+		active = &activeJob{job: job}
+		p.active[job.ID] = active
+	}
+	if active.cancelled {
+		// cancelled while queued, and already reported as such
+		delete(p.active, job.ID)
+		p.mu.Unlock()
+		cancelJob(nil)
+		return
+	}
+	active.cancel = cancelJob
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.active, job.ID)
+		p.mu.Unlock()
+		cancelJob(nil)
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	started := time.Now()
+	p.run(ctx, job)
+	log.Printf("[jobs] Job %s finished in %s", job.ID, time.Since(started).Round(time.Millisecond))
+}
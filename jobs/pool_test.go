@@ -0,0 +1,168 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+func TestPoolRunCancel(t *testing.T) {
+	started := make(chan struct{})
+	cause := make(chan error, 1)
+	pool := NewPool(Config{Workers: 1}, func(ctx context.Context, job *appschema.FaceScanJob) {
+		close(started)
+		<-ctx.Done()
+		cause <- context.Cause(ctx)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		pool.Run(context.Background(), &appschema.FaceScanJob{ID: "j1"})
+		close(done)
+	}()
+	<-started
+
+	if pool.Stats().Busy != 1 {
+		t.Errorf("busy = %d while running, want 1", pool.Stats().Busy)
+	}
+	if !pool.Cancel("j1") {
+		t.Fatal("Cancel reported no such job while it ran")
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after Cancel")
+	}
+	if err := <-cause; !errors.Is(err, ErrCancelled) {
+		t.Fatalf("cause = %v, want %v", err, ErrCancelled)
+	}
+	if pool.Cancel("j1") {
+		t.Fatal("Cancel found the job after it finished")
+	}
+}
+
+func TestPoolEnqueue(t *testing.T) {
+	tests := []struct {
+		name       string
+		queueDepth int
+		jobs       int
+		wantFull   int
+	}{
+		{name: "fits", queueDepth: 2, jobs: 2},
+		{name: "one over", queueDepth: 2, jobs: 3, wantFull: 1},
+		{name: "default depth", jobs: 65, wantFull: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// no workers are started, so every job stays queued
+			pool := NewPool(Config{QueueDepth: tt.queueDepth}, func(context.Context, *appschema.FaceScanJob) {})
+			full := 0
+			for i := range tt.jobs {
+				err := pool.Enqueue(&appschema.FaceScanJob{ID: fmt.Sprint(i)})
+				if errors.Is(err, ErrQueueFull) {
+					full++
+				} else if err != nil {
+					t.Fatalf("Enqueue: %v", err)
+				}
+			}
+			if full != tt.wantFull {
+				t.Fatalf("%d jobs rejected, want %d", full, tt.wantFull)
+			}
+			if got := pool.Stats().Queued; got != tt.jobs-tt.wantFull {
+				t.Fatalf("Queued = %d, want %d", got, tt.jobs-tt.wantFull)
+			}
+			// a rejected job is not left behind for Cancel to find
+			if tt.wantFull > 0 && pool.Cancel(fmt.Sprint(tt.jobs-1)) {
+				t.Fatal("Cancel found a rejected job")
+			}
+		})
+	}
+}
+
+func TestPoolJobContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    error
+	}{
+		{name: "times out", timeout: 10 * time.Millisecond, want: context.DeadlineExceeded},
+		{name: "pool shuts down", timeout: time.Minute, want: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			cause := make(chan error, 1)
+			pool := NewPool(Config{Workers: 1, Timeout: tt.timeout}, func(ctx context.Context, job *appschema.FaceScanJob) {
+				close(started)
+				<-ctx.Done()
+				cause <- context.Cause(ctx)
+			})
+			if err := pool.Enqueue(&appschema.FaceScanJob{ID: "j1"}); err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+
+			ctx, stop := context.WithCancel(context.Background())
+			defer stop()
+			pool.Start(ctx)
+			<-started
+			if tt.want == context.Canceled {
+				stop()
+			}
+			select {
+			case err := <-cause:
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("cause = %v, want %v", err, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("job did not end")
+			}
+			stop()
+			pool.Wait()
+		})
+	}
+}
+
+func TestPoolCancelQueued(t *testing.T) {
+	ran := make(chan string, 2)
+	pool := NewPool(Config{Workers: 1}, func(ctx context.Context, job *appschema.FaceScanJob) {
+		ran <- job.ID
+	})
+	var reported []string
+	pool.OnCancelQueued = func(job *appschema.FaceScanJob) {
+		reported = append(reported, job.ID)
+	}
+	for _, id := range []string{"j1", "j2"} {
+		if err := pool.Enqueue(&appschema.FaceScanJob{ID: id}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	// reported before any worker runs, and only once
+	if !pool.Cancel("j1") || !pool.Cancel("j1") {
+		t.Fatal("Cancel did not find the queued job")
+	}
+	if fmt.Sprint(reported) != "[j1]" {
+		t.Fatalf("reported %v, want j1 once", reported)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	pool.Start(ctx)
+	select {
+	case id := <-ran:
+		if id != "j2" {
+			t.Fatalf("ran %s, want the cancelled job skipped", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job behind the cancelled one never ran")
+	}
+	stop()
+	pool.Wait()
+	if pool.Cancel("j1") {
+		t.Fatal("Cancel found the skipped job")
+	}
+}
@@ -0,0 +1,245 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id            TEXT PRIMARY KEY,
+	owner_id      TEXT NOT NULL,
+	stream_id     TEXT NOT NULL,
+	status        TEXT NOT NULL,
+	filename      TEXT NOT NULL DEFAULT '',
+	stages        TEXT NOT NULL DEFAULT '[]',
+	result        TEXT,
+	error_code    TEXT NOT NULL DEFAULT '',
+	error_message TEXT NOT NULL DEFAULT '',
+	created_at    INTEGER NOT NULL,
+	started_at    INTEGER,
+	finished_at   INTEGER,
+	expires_at    INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS jobs_owner_created ON jobs (owner_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS jobs_expires ON jobs (expires_at);
+`
+
+// columns added after the initial schema; a duplicate column error means the
+// migration already ran
+var sqliteMigrations = []string{
+	`ALTER TABLE jobs ADD COLUMN batch TEXT`,
+	`ALTER TABLE jobs ADD COLUMN analysis TEXT NOT NULL DEFAULT 'face'`,
+	`ALTER TABLE jobs ADD COLUMN objects TEXT`,
+	`ALTER TABLE jobs ADD COLUMN callback_url TEXT NOT NULL DEFAULT ''`,
+}
+
+// SQLiteStore persists job records in a SQLite database file
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	// one writer avoids SQLITE_BUSY between workers
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	for _, migration := range sqliteMigrations {
+		if _, err := db.Exec(migration); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) Save(ctx context.Context, record *appschema.JobRecord) error {
+	stages, err := json.Marshal(record.Stages)
+	if err != nil {
+		return err
+	}
+	result, err := nullableJSON(record.Result)
+	if err != nil {
+		return err
+	}
+	batch, err := nullableJSON(record.Batch)
+	if err != nil {
+		return err
+	}
+	var objects any
+	if len(record.Objects) > 0 {
+		if objects, err = nullableJSON(&record.Objects); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO jobs (id, owner_id, callback_url, stream_id, analysis, status, filename, stages, result, batch, objects, error_code, error_message, created_at, started_at, finished_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			status = excluded.status,
+			stages = excluded.stages,
+			result = excluded.result,
+			batch = excluded.batch,
+			objects = excluded.objects,
+			error_code = excluded.error_code,
+			error_message = excluded.error_message,
+			started_at = excluded.started_at,
+			finished_at = excluded.finished_at,
+			expires_at = excluded.expires_at`,
+		record.ID, record.OwnerID, record.CallbackURL, record.StreamID, record.Analysis, string(record.Status), record.Filename, string(stages), result, batch, objects,
+		record.ErrorCode, record.ErrorMessage, record.CreatedAt.UnixMilli(), nullableMillis(record.StartedAt),
+		nullableMillis(record.FinishedAt), record.ExpiresAt.UnixMilli(),
+	)
+	return err
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, id string) (*appschema.JobRecord, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+sqliteColumns+` FROM jobs WHERE id = ?`, id)
+	record, err := scanRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return record, err
+}
+
+func (s *SQLiteStore) List(ctx context.Context, ownerID string, limit, offset int) ([]appschema.JobRecord, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT `+sqliteColumns+` FROM jobs WHERE owner_id = ? ORDER BY created_at DESC LIMIT ? OFFSET ?`, ownerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []appschema.JobRecord{}
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *record)
+	}
+	return records, rows.Err()
+}
+
+func (s *SQLiteStore) Purge(ctx context.Context, now time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM jobs WHERE expires_at < ?`, now.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	removed, err := res.RowsAffected()
+	return int(removed), err
+}
+
+func (s *SQLiteStore) Transition(ctx context.Context, id string, from, to appschema.JobStatus) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE jobs SET status = ? WHERE id = ? AND status = ?`, string(to), id, string(from))
+	if err != nil {
+		return err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrStatusChanged
+	}
+	return nil
+}
+
+const sqliteColumns = `id, owner_id, callback_url, stream_id, analysis, status, filename, stages, result, batch, objects, error_code, error_message, created_at, started_at, finished_at, expires_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRecord(row rowScanner) (*appschema.JobRecord, error) {
+	var (
+		record                 appschema.JobRecord
+		status, stages         string
+		result, batch, objects sql.NullString
+		createdAt, expiresAt   int64
+		startedAt, finishedAt  sql.NullInt64
+	)
+	err := row.Scan(&record.ID, &record.OwnerID, &record.CallbackURL, &record.StreamID, &record.Analysis, &status, &record.Filename, &stages, &result, &batch, &objects,
+		&record.ErrorCode, &record.ErrorMessage, &createdAt, &startedAt, &finishedAt, &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	record.Status = appschema.JobStatus(status)
+	if err := json.Unmarshal([]byte(stages), &record.Stages); err != nil {
+		return nil, err
+	}
+	if result.Valid {
+		record.Result = &appschema.FaceScanData{}
+		// results of an older schema are dropped rather than failing the record
+		if err := json.Unmarshal([]byte(result.String), record.Result); err != nil {
+			log.Printf("[jobs] Job %s: dropping undecodable result: %v", record.ID, err)
+			record.Result = nil
+		}
+	}
+	if batch.Valid {
+		record.Batch = &appschema.BatchSummary{}
+		if err := json.Unmarshal([]byte(batch.String), record.Batch); err != nil {
+			return nil, err
+		}
+	}
+	if objects.Valid {
+		if err := json.Unmarshal([]byte(objects.String), &record.Objects); err != nil {
+			return nil, err
+		}
+	}
+	record.CreatedAt = time.UnixMilli(createdAt)
+	record.ExpiresAt = time.UnixMilli(expiresAt)
+	record.StartedAt = timeFromMillis(startedAt)
+	record.FinishedAt = timeFromMillis(finishedAt)
+	return &record, nil
+}
+
+// nullableJSON encodes v for a nullable TEXT column
+func nullableJSON[T any](v *T) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+func nullableMillis(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UnixMilli()
+}
+
+func timeFromMillis(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.UnixMilli(v.Int64)
+	return &t
+}
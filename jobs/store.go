@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+var ErrNotFound = errors.New("job not found")
+
+// ErrStatusChanged reports a transition from a status the job is no longer in
+var ErrStatusChanged = errors.New("job status changed")
+
+// Store persists job records for result retrieval after the stream is gone
+type Store interface {
+	Save(ctx context.Context, record *appschema.JobRecord) error
+	Get(ctx context.Context, id string) (*appschema.JobRecord, error)
+	// List returns the owner's jobs, newest first
+	List(ctx context.Context, ownerID string, limit, offset int) ([]appschema.JobRecord, error)
+	// Purge removes records whose retention ended before now
+	Purge(ctx context.Context, now time.Time) (int, error)
+	// Transition moves the job from one status to another, atomically across
+	// every instance sharing the store, and fails with ErrStatusChanged when
+	// the job is not in from
+	Transition(ctx context.Context, id string, from, to appschema.JobStatus) error
+}
+
+// Tracker applies stream events to job records
+type Tracker struct {
+	Store     Store
+	Retention time.Duration
+}
+
+// Create stores a fresh queued record for job
+func (t *Tracker) Create(ctx context.Context, job *appschema.FaceScanJob) error {
+	return t.Store.Save(ctx, &appschema.JobRecord{
+		ID:          job.ID,
+		OwnerID:     job.OwnerID,
+		CallbackURL: job.CallbackURL,
+		StreamID:    job.StreamID,
+		Analysis:    job.Analysis,
+		Status:      appschema.JobQueued,
+		Filename:    job.Images[0].Filename,
+		Stages:      []appschema.JobStage{{Name: string(appschema.JobQueued), At: job.CreatedAt}},
+		CreatedAt:   job.CreatedAt,
+		ExpiresAt:   job.CreatedAt.Add(t.Retention),
+	})
+}
+
+// Reserve stores a record for a direct upload whose image is still on its way
+// to object storage; recording its queued event moves it on
+func (t *Tracker) Reserve(ctx context.Context, job *appschema.FaceScanJob, filename string) error {
+	return t.Store.Save(ctx, &appschema.JobRecord{
+		ID:          job.ID,
+		OwnerID:     job.OwnerID,
+		CallbackURL: job.CallbackURL,
+		StreamID:    job.StreamID,
+		Analysis:    job.Analysis,
+		Status:      appschema.JobAwaitingUpload,
+		Filename:    filename,
+		Stages:      []appschema.JobStage{{Name: string(appschema.JobAwaitingUpload), At: job.CreatedAt}},
+		CreatedAt:   job.CreatedAt,
+		ExpiresAt:   job.CreatedAt.Add(t.Retention),
+	})
+}
+
+// Record folds an event into the job's record: every event marks a stage, the
+// first one starts the job and terminal events finish it
+func (t *Tracker) Record(ctx context.Context, jobID string, event *appschema.EventMessage) {
+	record, err := t.Store.Get(ctx, jobID)
+	if err != nil {
+		log.Printf("[jobs] Job %s: load for %s failed: %v", jobID, event.Event, err)
+		return
+	}
+
+	now := time.Now()
+	record.Stages = append(record.Stages, appschema.JobStage{Name: event.Event, ImageIndex: event.ImageIndex, At: now})
+
+	switch event.Event {
+	case faceanalyze_events.EventCompleted:
+		record.Status = appschema.JobSucceeded
+		if data, ok := event.Data.(*appschema.FaceScanData); ok {
+			record.Result = data
+		}
+	case faceanalyze_events.EventError:
+		record.Status = appschema.JobFailed
+		record.ErrorCode = event.ErrorCode
+		record.ErrorMessage = event.Message
+	case faceanalyze_events.EventQueued:
+		record.Status = appschema.JobQueued
+	case faceanalyze_events.EventCancelled:
+		record.Status = appschema.JobCancelled
+	case faceanalyze_events.EventBatchCompleted:
+		// a batch only fails as a whole when none of its images could be scanned
+		record.Status = appschema.JobSucceeded
+		if summary, ok := event.Data.(*appschema.BatchSummary); ok {
+			record.Batch = summary
+			if summary.Succeeded == 0 {
+				record.Status = appschema.JobFailed
+				record.ErrorCode = faceanalyze_events.ErrCodeBatchFailed
+				record.ErrorMessage = event.Message
+			}
+		}
+	case faceanalyze_events.EventImageStored:
+		if object, ok := event.Data.(*appschema.StoredImage); ok {
+			record.Objects = append(record.Objects, *object)
+		}
+		fallthrough
+	default:
+		if record.Status == appschema.JobQueued {
+			record.Status = appschema.JobRunning
+			record.StartedAt = &now
+		}
+	}
+	if record.Status == appschema.JobSucceeded || record.Status == appschema.JobFailed || record.Status == appschema.JobCancelled {
+		record.FinishedAt = &now
+		record.ExpiresAt = now.Add(t.Retention)
+	}
+
+	if err := t.Store.Save(ctx, record); err != nil {
+		log.Printf("[jobs] Job %s: save failed: %v", jobID, err)
+	}
+}
+
+// StartJanitor purges expired records every interval until ctx ends
+func (t *Tracker) StartJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			removed, err := t.Store.Purge(ctx, now)
+			if err != nil {
+				log.Printf("[jobs] Purge failed: %v", err)
+			} else if removed > 0 {
+				log.Printf("[jobs] Purged %d expired jobs", removed)
+			}
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// stores returns each backend, empty
+func stores(t *testing.T) map[string]Store {
+	sqlite, err := NewSQLiteStore(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+	return map[string]Store{
+		"memory":   NewMemoryStore(),
+		"sqlite":   sqlite,
+		"dynamodb": &DynamoDBStore{client: newFakeTable(), table: "jobs"},
+	}
+}
+
+func TestStoreSaveGet(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	record := &appschema.JobRecord{
+		ID:          "j1",
+		OwnerID:     "u1",
+		CallbackURL: "https://partner.example/hook",
+		StreamID:    "s1",
+		Analysis:    "face",
+		Status:      appschema.JobSucceeded,
+		Filename:    "face.jpg",
+		Stages:      []appschema.JobStage{{Name: "queued", At: now}},
+		Result:      &appschema.FaceScanData{SchemaVersion: 1},
+		Objects:     []appschema.StoredImage{{Kind: "original", Key: "k"}},
+		CreatedAt:   now,
+		StartedAt:   &now,
+		ExpiresAt:   now.Add(time.Hour),
+	}
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := store.Get(ctx, "j1"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Get before Save = %v, want ErrNotFound", err)
+			}
+			if err := store.Save(ctx, record); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			got, err := store.Get(ctx, "j1")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got.OwnerID != "u1" || got.CallbackURL != record.CallbackURL || got.Status != appschema.JobSucceeded || got.Result == nil || len(got.Objects) != 1 ||
+				!got.CreatedAt.Equal(now) || got.StartedAt == nil || got.FinishedAt != nil {
+				t.Fatalf("Get = %+v", got)
+			}
+
+			// saving again replaces the record
+			update := *record
+			update.Status = appschema.JobFailed
+			store.Save(ctx, &update)
+			if got, _ := store.Get(ctx, "j1"); got.Status != appschema.JobFailed {
+				t.Fatalf("status after update = %s", got.Status)
+			}
+		})
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	base := time.Now().Truncate(time.Millisecond)
+	tests := []struct {
+		name          string
+		owner         string
+		limit, offset int
+		want          []string
+	}{
+		{name: "newest first", owner: "u1", want: []string{"j3", "j2", "j1"}},
+		{name: "limit", owner: "u1", limit: 2, want: []string{"j3", "j2"}},
+		{name: "offset", owner: "u1", limit: 2, offset: 2, want: []string{"j1"}},
+		{name: "past the end", owner: "u1", offset: 5, want: []string{}},
+		{name: "other owner", owner: "u2", want: []string{"j4"}},
+		{name: "no jobs", owner: "u3", want: []string{}},
+	}
+	for name, store := range stores(t) {
+		ctx := context.Background()
+		for i, owner := range []string{"u1", "u1", "u1", "u2"} {
+			store.Save(ctx, &appschema.JobRecord{
+				ID:        fmt.Sprintf("j%d", i+1),
+				OwnerID:   owner,
+				Status:    appschema.JobQueued,
+				CreatedAt: base.Add(time.Duration(i) * time.Second),
+				ExpiresAt: base.Add(time.Hour),
+			})
+		}
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				records, err := store.List(ctx, tt.owner, tt.limit, tt.offset)
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				got := []string{}
+				for _, record := range records {
+					got = append(got, record.ID)
+				}
+				if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+					t.Fatalf("List = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestStorePurge(t *testing.T) {
+	now := time.Now()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store.Save(ctx, &appschema.JobRecord{ID: "old", OwnerID: "u1", CreatedAt: now, ExpiresAt: now.Add(-time.Minute)})
+			store.Save(ctx, &appschema.JobRecord{ID: "new", OwnerID: "u1", CreatedAt: now, ExpiresAt: now.Add(time.Minute)})
+
+			// the table's TTL removes dynamodb records, which are hidden once expired
+			wantRemoved := 1
+			if name == "dynamodb" {
+				wantRemoved = 0
+			}
+			removed, err := store.Purge(ctx, now)
+			if err != nil || removed != wantRemoved {
+				t.Fatalf("Purge = %d, %v; want %d", removed, err, wantRemoved)
+			}
+			if _, err := store.Get(ctx, "old"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Get(old) = %v, want ErrNotFound", err)
+			}
+			if _, err := store.Get(ctx, "new"); err != nil {
+				t.Fatalf("Get(new) = %v", err)
+			}
+		})
+	}
+}
+
+func TestStoreTransition(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		from, to   appschema.JobStatus
+		wantErr    error
+		wantStatus appschema.JobStatus
+	}{
+		{name: "moves on", id: "j1", from: appschema.JobAwaitingUpload, to: appschema.JobQueued, wantStatus: appschema.JobQueued},
+		{name: "already moved", id: "j1", from: appschema.JobAwaitingUpload, to: appschema.JobCancelled, wantErr: ErrStatusChanged, wantStatus: appschema.JobQueued},
+		{name: "unknown job", id: "j2", from: appschema.JobAwaitingUpload, to: appschema.JobQueued, wantErr: ErrStatusChanged},
+	}
+	for name, store := range stores(t) {
+		ctx := context.Background()
+		now := time.Now().Truncate(time.Millisecond)
+		store.Save(ctx, &appschema.JobRecord{
+			ID:        "j1",
+			OwnerID:   "u1",
+			Status:    appschema.JobAwaitingUpload,
+			Filename:  "face.jpg",
+			CreatedAt: now,
+			ExpiresAt: now.Add(time.Hour),
+		})
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				if err := store.Transition(ctx, tt.id, tt.from, tt.to); !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Transition = %v, want %v", err, tt.wantErr)
+				}
+				if tt.wantStatus == "" {
+					return
+				}
+				// only the status changes
+				got, err := store.Get(ctx, tt.id)
+				if err != nil || got.Status != tt.wantStatus || got.Filename != "face.jpg" || got.OwnerID != "u1" {
+					t.Fatalf("Get = %+v, %v; want status %s", got, err, tt.wantStatus)
+				}
+			})
+		}
+	}
+}
+
+func TestStoreTransitionRace(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store.Save(ctx, &appschema.JobRecord{ID: "j1", OwnerID: "u1", Status: appschema.JobAwaitingUpload, CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)})
+
+			// a finalize call, the storage event and a cancel all at once
+			var wg sync.WaitGroup
+			var won atomic.Int32
+			for _, to := range []appschema.JobStatus{appschema.JobQueued, appschema.JobQueued, appschema.JobCancelled, appschema.JobQueued} {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := store.Transition(ctx, "j1", appschema.JobAwaitingUpload, to)
+					if err == nil {
+						won.Add(1)
+					} else if !errors.Is(err, ErrStatusChanged) {
+						t.Errorf("Transition: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+			if won.Load() != 1 {
+				t.Fatalf("%d transitions succeeded, want 1", won.Load())
+			}
+		})
+	}
+}
+
+func TestSQLiteStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	for range 2 {
+		// the migrations run again on an already migrated file
+		store, err := NewSQLiteStore(path)
+		if err != nil {
+			t.Fatalf("NewSQLiteStore: %v", err)
+		}
+		store.Close()
+	}
+}
+
+func TestTrackerRecord(t *testing.T) {
+	index := 0
+	tests := []struct {
+		name        string
+		events      []*appschema.EventMessage
+		wantStatus  appschema.JobStatus
+		wantCode    string
+		wantObjects int
+		finished    bool
+	}{
+		{
+			name:       "starts on the first stage",
+			events:     []*appschema.EventMessage{{Event: faceanalyze_events.EventAnalyzingFace}},
+			wantStatus: appschema.JobRunning,
+		},
+		{
+			name: "succeeds",
+			events: []*appschema.EventMessage{
+				{Event: faceanalyze_events.EventAnalyzingFace},
+				{Event: faceanalyze_events.EventCompleted, Data: &appschema.FaceScanData{SchemaVersion: 1}},
+			},
+			wantStatus: appschema.JobSucceeded,
+			finished:   true,
+		},
+		{
+			name:       "fails",
+			events:     []*appschema.EventMessage{{Event: faceanalyze_events.EventError, ErrorCode: "timeout", Message: "m"}},
+			wantStatus: appschema.JobFailed,
+			wantCode:   "timeout",
+			finished:   true,
+		},
+		{
+			name:       "cancelled",
+			events:     []*appschema.EventMessage{{Event: faceanalyze_events.EventCancelled}},
+			wantStatus: appschema.JobCancelled,
+			finished:   true,
+		},
+		{
+			name: "batch with no successes fails",
+			events: []*appschema.EventMessage{{
+				Event: faceanalyze_events.EventBatchCompleted,
+				Data:  &appschema.BatchSummary{Total: 2, Failed: 2},
+			}},
+			wantStatus: appschema.JobFailed,
+			wantCode:   faceanalyze_events.ErrCodeBatchFailed,
+			finished:   true,
+		},
+		{
+			name: "batch with a success succeeds",
+			events: []*appschema.EventMessage{{
+				Event: faceanalyze_events.EventBatchCompleted,
+				Data:  &appschema.BatchSummary{Total: 2, Succeeded: 1, Failed: 1},
+			}},
+			wantStatus: appschema.JobSucceeded,
+			finished:   true,
+		},
+		{
+			name: "stored images are kept",
+			events: []*appschema.EventMessage{{
+				Event:      faceanalyze_events.EventImageStored,
+				ImageIndex: &index,
+				Data:       &appschema.StoredImage{Kind: "original", Key: "k"},
+			}},
+			wantStatus:  appschema.JobRunning,
+			wantObjects: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tracker := &Tracker{Store: NewMemoryStore(), Retention: time.Hour}
+			job := &appschema.FaceScanJob{ID: "j1", OwnerID: "u1", Images: []appschema.JobImage{{Filename: "face.jpg"}}, CreatedAt: time.Now()}
+			if err := tracker.Create(ctx, job); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			for _, event := range tt.events {
+				tracker.Record(ctx, "j1", event)
+			}
+
+			record, err := tracker.Store.Get(ctx, "j1")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if record.Status != tt.wantStatus || record.ErrorCode != tt.wantCode || (record.FinishedAt != nil) != tt.finished {
+				t.Fatalf("record = %+v, want %s (code %q, finished %v)", record, tt.wantStatus, tt.wantCode, tt.finished)
+			}
+			if len(record.Stages) != len(tt.events)+1 {
+				t.Fatalf("%d stages, want %d", len(record.Stages), len(tt.events)+1)
+			}
+			if len(record.Objects) != tt.wantObjects {
+				t.Fatalf("Objects = %+v", record.Objects)
+			}
+		})
+	}
+}
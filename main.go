@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
-	"strings"
+	"path"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -14,52 +18,154 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/muthu-kumar-u/go-sse/events/stream"
 	"github.com/muthu-kumar-u/go-sse/globals"
-	"github.com/muthu-kumar-u/go-sse/handlers"
 	app "github.com/muthu-kumar-u/go-sse/handlers/data"
 	"github.com/muthu-kumar-u/go-sse/middleware"
+	"github.com/muthu-kumar-u/go-sse/pipeline"
 	"github.com/muthu-kumar-u/go-sse/utils"
 )
 
-var streamHandler *handlers.StreamHandler
+var router *gin.Engine
+
+// objectCreated starts the scan of a direct upload once its image is stored
+var objectCreated func(ctx context.Context, key string) error
+
+var lambdaLocal = flag.Bool("lambda-local", false, "serve the Lambda handler over plain HTTP on APP_PORT")
 
 func Init() error {
 	flag.Parse()
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	utils.AWSSessionConfigure()
 
 	if err := godotenv.Load(); err != nil {
 		log.Printf("Error loading .env file: %v", err)
 	}
 
+	utils.AWSSessionConfigure()
+
+	// mock services in production are a misconfiguration, not a degraded start
+	if _, err := utils.MockServicesEnabled(); err != nil {
+		return err
+	}
+	// nothing can be scanned, or even routed, without the upstream clients
 	if err := utils.CreateHttpClients(); err != nil {
-		log.Printf("Error while creating HTTP client pool: %v", err)
+		return fmt.Errorf("failed to create HTTP client pool: %w", err)
+	}
+
+	if err := utils.ConfigureWebhooks(context.Background()); err != nil {
+		return err
+	}
+
+	if err := utils.ConfigureEventStore(); err != nil {
+		return err
+	}
+
+	if err := utils.ConfigureJobStore(context.Background()); err != nil {
+		return err
+	}
+
+	if err := utils.ConfigureResultCache(context.Background()); err != nil {
+		return err
+	}
+
+	if err := utils.ConfigureImageStore(); err != nil {
+		return err
+	}
+
+	utils.ConfigureAnnotations()
+
+	if err := utils.ConfigureScanHistory(); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// NewRouter registers every route and middleware; both the local server and the
+// Lambda adapter serve this same engine
+func NewRouter(handlers *app.AppHandlers) *gin.Engine {
+	ginApp := gin.New()
+	ginApp.Use(gin.Logger(), gin.Recovery(), utils.GetCorsConfig())
+
+	version := os.Getenv("APP_VERSION")
+	api := ginApp.Group("/api/" + version)
+	{
+		api.GET("/health", handlers.HealthHandler.Health)
+
+		api.GET("/facelog", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.StreamHandler.FaceLogStream)
+		api.POST("/facelog/upload", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.StreamHandler.LogUserFace)
+		api.POST("/facelog/upload-url", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.StreamHandler.CreateUploadURL)
+		// every other analysis gets an upload route of its own
+		for _, analysis := range handlers.StreamHandler.Pipelines.Names() {
+			if analysis == pipeline.DefaultAnalysis {
+				continue
+			}
+			api.POST("/analyze/"+analysis+"/upload", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.StreamHandler.AnalyzeUpload(analysis))
+		}
+
+		api.GET("/jobs", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.JobHandler.ListJobs)
+		api.GET("/jobs/:id", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.JobHandler.GetJob)
+		api.GET("/jobs/:id/annotated", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.JobHandler.GetAnnotatedImage)
+		api.DELETE("/jobs/:id", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.JobHandler.CancelJob)
+		api.POST("/jobs/:id/finalize", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.StreamHandler.FinalizeUpload)
+
+		api.GET("/history", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.HistoryHandler.ListScans)
+		api.GET("/history/trends", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.HistoryHandler.Trends)
+		api.GET("/history/compare", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.HistoryHandler.CompareScans)
+		api.GET("/history/compare/image", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.HistoryHandler.CompareImage)
+		api.GET("/history/report", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.HistoryHandler.RangeReport)
+		api.GET("/history/:id", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.HistoryHandler.GetScan)
+		api.GET("/history/:id/report", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.HistoryHandler.ScanReport)
+
+		api.GET("/webhook", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.WebhookHandler.GetWebhook)
+		api.PUT("/webhook", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.WebhookHandler.RegisterWebhook)
+		api.DELETE("/webhook", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.WebhookHandler.DeleteWebhook)
+
+		api.GET("/admin/streams", middleware.AdminMiddleware(), handlers.AdminHandler.ListStreams)
+		api.GET("/admin/jobs", middleware.AdminMiddleware(), handlers.AdminHandler.JobStats)
+		api.GET("/admin/upstream", middleware.AdminMiddleware(), handlers.AdminHandler.UpstreamStats)
+		api.GET("/admin/cache", middleware.AdminMiddleware(), handlers.AdminHandler.CacheStats)
+	}
+	// with the mock services the test page is served from the API's own origin,
+	// under its prefix so the page can find the versioned routes
+	if mock, _ := utils.MockServicesEnabled(); mock {
+		api.StaticFile("/sse-test.html", "sse-test.html")
+		ginApp.GET("/sse-test.html", func(c *gin.Context) {
+			c.Redirect(http.StatusMovedPermanently, path.Join("/api", version, "sse-test.html"))
+		})
+	}
+	ginApp.NoRoute(middleware.PathNotFound())
+
+	return ginApp
+}
+
 func lambdaHandler(ctx context.Context, req events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
-	switch {
-	case req.RequestContext.HTTP.Method == http.MethodPost && strings.HasPrefix(req.RawPath, "/api/v1/facelog/upload"):
-		return streamHandler.LogUserFaceLambda(ctx, req)
-		
-	case req.RequestContext.HTTP.Method == http.MethodOptions :
-		return &events.LambdaFunctionURLStreamingResponse{
-			StatusCode: http.StatusOK,
-			Headers: map[string]string{
-				"Access-Control-Allow-Origin":  "*",
-				"Access-Control-Allow-Methods": "GET, POST, OPTIONS",
-				"Access-Control-Allow-Headers": "Content-Type, Authorization",
-			},
-			Body: strings.NewReader(""),
-		}, nil
-	
-	default:
-		return &events.LambdaFunctionURLStreamingResponse{
-			StatusCode: http.StatusNotFound,
-			Body:       strings.NewReader("Not found"),
-		}, nil
+	return utils.ServeLambdaFunctionURL(ctx, router, req)
+}
+
+// lambdaEntry serves both invocations the function is wired to: function URL
+// requests and the image bucket's object created notifications
+func lambdaEntry(ctx context.Context, payload json.RawMessage) (any, error) {
+	var s3Event events.S3Event
+	if err := json.Unmarshal(payload, &s3Event); err == nil && len(s3Event.Records) > 0 && s3Event.Records[0].EventSource == "aws:s3" {
+		var errs []error
+		for _, record := range s3Event.Records {
+			// notification keys arrive URL encoded
+			key, err := url.QueryUnescape(record.S3.Object.Key)
+			if err != nil {
+				key = record.S3.Object.Key
+			}
+			if err := objectCreated(ctx, key); err != nil {
+				log.Printf("[direct] Object %s: %v", key, err)
+				errs = append(errs, err)
+			}
+		}
+		return nil, errors.Join(errs...)
 	}
+
+	var req events.LambdaFunctionURLRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return nil, err
+	}
+	return lambdaHandler(ctx, req)
 }
 
 func main() {
@@ -69,29 +175,27 @@ func main() {
 	defer os.Exit(1)
 
 	handlers := app.LoadAppHandlers()
-	streamHandler = handlers.StreamHandler
 	globals.Stream = stream.NewStreamHub()
 
 	production := os.Getenv("PRODUCTION") == "true"
-	if production {
+	emulateLambda := *lambdaLocal || os.Getenv("LAMBDA_LOCAL") == "true"
+	if production || emulateLambda {
+		// the hub does not outlive an invocation, so uploads stream their own events
+		handlers.StreamHandler.InlineByDefault = true
+	}
+	router = NewRouter(handlers)
+	objectCreated = handlers.StreamHandler.HandleObjectCreated
+
+	port := os.Getenv("APP_PORT")
+	switch {
+	case emulateLambda:
+		log.Printf("Emulating Lambda function URL on :%s\n", port)
+		log.Fatal(http.ListenAndServe(":"+port, utils.NewLambdaURLEmulator(lambdaHandler)))
+	case production:
 		log.Println("Running as Lambda function...")
-		lambda.Start(lambdaHandler)
-	} else {
-		port := os.Getenv("APP_PORT")
+		lambda.Start(lambdaEntry)
+	default:
 		log.Printf("Starting local server on :%s\n", port)
-		
-		// Local Gin setup
-		ginApp := gin.New()
-		ginApp.Use(gin.Logger(), gin.Recovery(), utils.GetCorsConfig())
-		
-		version := os.Getenv("APP_VERSION")
-		api := ginApp.Group("/api/" + version)
-		{
-			api.GET("/facelog", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.StreamHandler.FaceLogStream)
-			api.POST("/facelog/upload", middleware.AuthMiddleware(handlers.StreamHandler.UserService), handlers.StreamHandler.LogUserFace)
-		}
-		ginApp.NoRoute(middleware.PathNotFound())
-		
-		log.Fatal(ginApp.Run(":" + port))
+		log.Fatal(router.Run(":" + port))
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+	"github.com/muthu-kumar-u/go-sse/globals"
+	app "github.com/muthu-kumar-u/go-sse/handlers/data"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/pipeline"
+	"github.com/muthu-kumar-u/go-sse/storage"
+	"github.com/muthu-kumar-u/go-sse/utils"
+)
+
+// TestMain brings the app up the way -lambda-local does, against the bundled
+// mock services
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	for key, value := range map[string]string{
+		"MOCK_SERVICES":       "true",
+		"APP_ALLOWED_ORIGINS": "http://localhost",
+		"APP_VERSION":         "v1",
+	} {
+		os.Setenv(key, value)
+	}
+	if err := Init(); err != nil {
+		panic(err)
+	}
+
+	// direct uploads need somewhere to land
+	globals.ImageStore = storage.NewMemory(time.Hour)
+	handlers := app.LoadAppHandlers()
+	globals.Stream = stream.NewStreamHub()
+	handlers.StreamHandler.InlineByDefault = true
+	router = NewRouter(handlers)
+	objectCreated = handlers.StreamHandler.HandleObjectCreated
+
+	os.Exit(m.Run())
+}
+
+func TestLambdaURLEmulatorUpload(t *testing.T) {
+	srv := httptest.NewServer(utils.NewLambdaURLEmulator(lambdaHandler))
+	defer srv.Close()
+
+	face := multipartBody(t, "image", "face.jpg", testJPEG(t, 128, 128))
+	photo := multipartBody(t, "photo", "face.jpg", testJPEG(t, 128, 128))
+	// once the stream has started, request errors arrive as error events
+	tests := []struct {
+		name        string
+		token       string
+		contentType string
+		body        []byte
+		wantStatus  int
+		wantEvents  []string
+	}{
+		{
+			name:        "no token",
+			contentType: face.contentType,
+			body:        face.body,
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "unknown token",
+			token:       "Bearer nobody",
+			contentType: face.contentType,
+			body:        face.body,
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "not multipart",
+			token:       "Bearer dev-token",
+			contentType: "application/json",
+			body:        []byte(`{"image":"face.jpg"}`),
+			wantStatus:  http.StatusOK,
+			wantEvents:  []string{"event: error", `"error_code":"invalid_form"`},
+		},
+		{
+			name:        "truncated multipart",
+			token:       "Bearer dev-token",
+			contentType: face.contentType,
+			body:        face.body[:len(face.body)/2],
+			wantStatus:  http.StatusOK,
+			wantEvents:  []string{"event: error", `"error_code":"invalid_form"`},
+		},
+		{
+			name:        "missing image field",
+			token:       "Bearer dev-token",
+			contentType: photo.contentType,
+			body:        photo.body,
+			wantStatus:  http.StatusOK,
+			wantEvents:  []string{"event: error", `"error_code":"missing_image"`},
+		},
+		{
+			name:        "scan",
+			token:       "Bearer dev-token",
+			contentType: face.contentType,
+			body:        face.body,
+			wantStatus:  http.StatusOK,
+			wantEvents:  []string{"event: done", `"stream_completion":100`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/facelog/upload", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.token != "" {
+				req.Header.Set("Authorization", tt.token)
+			}
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+			for _, event := range tt.wantEvents {
+				if !strings.Contains(string(body), event) {
+					t.Errorf("response is missing %q:\n%s", event, body)
+				}
+			}
+			if tt.name == "scan" && strings.Contains(string(body), "event: error") {
+				t.Errorf("scan reported an error:\n%s", body)
+			}
+		})
+	}
+}
+
+// TestUploadQueuedFirst checks no worker event overtakes queued, whose job ID
+// the uploader needs first
+func TestUploadQueuedFirst(t *testing.T) {
+	srv := httptest.NewServer(utils.NewLambdaURLEmulator(lambdaHandler))
+	defer srv.Close()
+
+	for _, images := range [][][]byte{{testJPEG(t, 128, 128)}, {testJPEG(t, 128, 128), testJPEG(t, 128, 128)}} {
+		upload := multipartBatch(t, images...)
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/facelog/upload", bytes.NewReader(upload.body))
+		req.Header.Set("Content-Type", upload.contentType)
+		req.Header.Set("Authorization", "Bearer dev-token")
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		var events []string
+		reader := stream.NewReader(bytes.NewReader(body), stream.FormatSSE)
+		for {
+			f, err := reader.Next()
+			if err != nil {
+				break
+			}
+			events = append(events, f.Event)
+		}
+		if len(events) < 3 || events[0] != "ready" || events[1] != "queued" {
+			t.Fatalf("%d images: events %v, want ready then queued first", len(images), events)
+		}
+	}
+}
+
+// TestDirectUploadRunsToCompletion checks both ways a direct upload starts in
+// Lambda mode finish the scan before the invocation answers
+func TestDirectUploadRunsToCompletion(t *testing.T) {
+	srv := httptest.NewServer(utils.NewLambdaURLEmulator(lambdaHandler))
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		start func(t *testing.T, job *appschema.FaceScanJob, key string)
+	}{
+		{
+			name: "finalize",
+			start: func(t *testing.T, job *appschema.FaceScanJob, key string) {
+				req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/jobs/"+job.ID+"/finalize", nil)
+				req.Header.Set("Authorization", "Bearer dev-token")
+				resp, err := srv.Client().Do(req)
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				defer resp.Body.Close()
+				body, _ := io.ReadAll(resp.Body)
+				if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "event: done") {
+					t.Fatalf("status %d, want the scan streamed to its end:\n%s", resp.StatusCode, body)
+				}
+			},
+		},
+		{
+			name: "object created",
+			start: func(t *testing.T, job *appschema.FaceScanJob, key string) {
+				if err := objectCreated(context.Background(), key); err != nil {
+					t.Fatalf("objectCreated: %v", err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &appschema.FaceScanJob{
+				ID:        uuid.NewString(),
+				StreamID:  uuid.NewString(),
+				OwnerID:   "dev-user",
+				Analysis:  pipeline.DefaultAnalysis,
+				CreatedAt: time.Now(),
+			}
+			if err := globals.JobTracker.Reserve(context.Background(), job, "face.jpg"); err != nil {
+				t.Fatalf("Reserve: %v", err)
+			}
+			key := storage.ImageKey(utils.ImageStorePrefix(), job.OwnerID, job.ID, 0, storage.KindOriginal, storage.ImageExt("image/jpeg"))
+			globals.ImageStore.Put(context.Background(), key, "image/jpeg", testJPEG(t, 128, 128))
+
+			tt.start(t, job, key)
+
+			record, err := globals.JobTracker.Store.Get(context.Background(), job.ID)
+			if err != nil || record.Status != appschema.JobSucceeded {
+				t.Fatalf("job = %+v, %v; want it succeeded once started", record, err)
+			}
+		})
+	}
+}
+
+// TestBatchUpload checks a batch is validated as a whole and summed up once
+// every image is done
+func TestBatchUpload(t *testing.T) {
+	srv := httptest.NewServer(utils.NewLambdaURLEmulator(lambdaHandler))
+	defer srv.Close()
+
+	face := testJPEG(t, 128, 128)
+	tests := []struct {
+		name       string
+		images     [][]byte
+		wantEvents []string
+		notEvents  []string
+	}{
+		{
+			name:       "every image scanned",
+			images:     [][]byte{face, face},
+			wantEvents: []string{`"images":2`, "event: batch_done", `"total":2,"succeeded":2,"failed":0`},
+			notEvents:  []string{"event: error"},
+		},
+		{
+			name:       "one bad image rejects the batch",
+			images:     [][]byte{face, []byte("not an image")},
+			wantEvents: []string{"event: error", `"error_code":"unsupported_image_type"`, `"image_index":1`, `"filename":"face1.jpg"`},
+			notEvents:  []string{"event: queued", "event: batch_done"},
+		},
+		{
+			name:       "too many images",
+			images:     [][]byte{face, face, face, face, face, face, face, face, face, face, face},
+			wantEvents: []string{"event: error", `"error_code":"too_many_images"`},
+			notEvents:  []string{"event: queued"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upload := multipartBatch(t, tt.images...)
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/facelog/upload", bytes.NewReader(upload.body))
+			req.Header.Set("Content-Type", upload.contentType)
+			req.Header.Set("Authorization", "Bearer dev-token")
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			for _, event := range tt.wantEvents {
+				if !strings.Contains(string(body), event) {
+					t.Errorf("response is missing %q:\n%s", event, body)
+				}
+			}
+			for _, event := range tt.notEvents {
+				if strings.Contains(string(body), event) {
+					t.Errorf("response has %q:\n%s", event, body)
+				}
+			}
+		})
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	ctx := context.Background()
+	reserve := func(t *testing.T) string {
+		job := &appschema.FaceScanJob{ID: uuid.NewString(), StreamID: uuid.NewString(), OwnerID: "dev-user", Analysis: pipeline.DefaultAnalysis, CreatedAt: time.Now()}
+		if err := globals.JobTracker.Reserve(ctx, job, "face.jpg"); err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+		return job.ID
+	}
+	finished := uuid.NewString()
+	globals.JobTracker.Store.Save(ctx, &appschema.JobRecord{
+		ID: finished, OwnerID: "dev-user", Status: appschema.JobSucceeded, CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour),
+	})
+	awaiting, cancelledTwice := reserve(t), reserve(t)
+
+	tests := []struct {
+		name       string
+		id         string
+		token      string
+		wantStatus int
+		wantRecord appschema.JobStatus
+	}{
+		{name: "unknown job", id: uuid.NewString(), token: "Bearer dev-token", wantStatus: http.StatusNotFound},
+		{name: "another owner's job", id: awaiting, token: "Bearer dev-token-2", wantStatus: http.StatusNotFound, wantRecord: appschema.JobAwaitingUpload},
+		{name: "finished job", id: finished, token: "Bearer dev-token", wantStatus: http.StatusConflict, wantRecord: appschema.JobSucceeded},
+		{name: "awaiting upload", id: awaiting, token: "Bearer dev-token", wantStatus: http.StatusAccepted, wantRecord: appschema.JobCancelled},
+		{name: "cancelled once", id: cancelledTwice, token: "Bearer dev-token", wantStatus: http.StatusAccepted, wantRecord: appschema.JobCancelled},
+		{name: "cancelled again", id: cancelledTwice, token: "Bearer dev-token", wantStatus: http.StatusConflict, wantRecord: appschema.JobCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/jobs/"+tt.id, nil)
+			req.Header.Set("Authorization", tt.token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantRecord == "" {
+				return
+			}
+			record, err := globals.JobTracker.Store.Get(ctx, tt.id)
+			if err != nil || record.Status != tt.wantRecord {
+				t.Fatalf("job = %+v, %v; want %s", record, err, tt.wantRecord)
+			}
+		})
+	}
+}
+
+type multipartUpload struct {
+	contentType string
+	body        []byte
+}
+
+func multipartBody(t *testing.T, field, filename string, data []byte) multipartUpload {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreateFormFile(field, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	w.Close()
+	return multipartUpload{contentType: w.FormDataContentType(), body: buf.Bytes()}
+}
+
+// multipartBatch puts each image in its own image field, named face<index>.jpg
+func multipartBatch(t *testing.T, images ...[]byte) multipartUpload {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for i, data := range images {
+		part, err := w.CreateFormFile("image", fmt.Sprintf("face%d.jpg", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	w.Close()
+	return multipartUpload{contentType: w.FormDataContentType(), body: buf.Bytes()}
+}
+
+func testJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 160, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTestPageServedUnderAPIPrefix(t *testing.T) {
+	tests := []struct {
+		path         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{path: "/api/v1/sse-test.html", wantStatus: http.StatusOK},
+		{path: "/sse-test.html", wantStatus: http.StatusMovedPermanently, wantLocation: "/api/v1/sse-test.html"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.wantStatus || w.Header().Get("Location") != tt.wantLocation {
+			t.Errorf("GET %s = %d (Location %q), want %d (Location %q)", tt.path, w.Code, w.Header().Get("Location"), tt.wantStatus, tt.wantLocation)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muthu-kumar-u/go-sse/message"
+)
+
+// AdminMiddleware guards operator routes with the ADMIN_API_KEY shared secret
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminKey := os.Getenv("ADMIN_API_KEY")
+		if adminKey == "" {
+			c.JSON(http.StatusForbidden, message.ReturnCustomMessage("admin api disabled"))
+			c.Abort()
+			return
+		}
+
+		provided := c.GetHeader("X-Admin-Key")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(adminKey)) != 1 {
+			c.JSON(http.StatusUnauthorized, message.ReturnMessage(http.StatusUnauthorized))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		adminKey   string
+		provided   string
+		wantStatus int
+	}{
+		{name: "admin api disabled", provided: "anything", wantStatus: http.StatusForbidden},
+		{name: "no key", adminKey: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "wrong key", adminKey: "secret", provided: "secreT", wantStatus: http.StatusUnauthorized},
+		{name: "prefix of the key", adminKey: "secret", provided: "sec", wantStatus: http.StatusUnauthorized},
+		{name: "right key", adminKey: "secret", provided: "secret", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ADMIN_API_KEY", tt.adminKey)
+			router := gin.New()
+			router.GET("/admin", AdminMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tt.provided != "" {
+				req.Header.Set("X-Admin-Key", tt.provided)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -1,12 +1,13 @@
 package middleware
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	controller "github.com/muthu-kumar-u/go-sse/controller/user"
 	"github.com/muthu-kumar-u/go-sse/globals"
 	"github.com/muthu-kumar-u/go-sse/message"
 	"github.com/muthu-kumar-u/go-sse/services"
@@ -37,7 +38,13 @@ func AuthMiddleware(userService services.UserService) gin.HandlerFunc {
 			return
 		}
 
-		verified, err := userService.IsUserAuthenticated(context.TODO(), tokenString)
+		user, err := userService.GetAuthenticatedUser(c.Request.Context(), tokenString)
+		if errors.Is(err, controller.ErrInvalidAccount) {
+			fmt.Println("error while authenticate user", err.Error())
+			c.JSON(http.StatusBadGateway, message.ReturnMessage(http.StatusBadGateway))
+			c.Abort()
+			return
+		}
 		if err != nil {
 			fmt.Println("error while authenticate user", err.Error())
 			c.JSON(http.StatusInternalServerError, message.ReturnMessage(http.StatusInternalServerError))
@@ -45,12 +52,14 @@ func AuthMiddleware(userService services.UserService) gin.HandlerFunc {
 			return
 		}
 
-		if !verified {
+		if user == nil {
 			c.JSON(http.StatusUnauthorized, message.ReturnCustomMessage("User not allowed"))
 			c.Abort()
 			return
 		}
 
+		c.Set("id", user.ID)
+
 		c.Next()
 	}
 }
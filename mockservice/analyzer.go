@@ -0,0 +1,284 @@
+package mockservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	constants "github.com/muthu-kumar-u/go-sse/const"
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+const maxImageBytes = 64 << 20
+
+// shape is a region laid out in fractions of the image, so the canned result
+// fits any upload
+type shape struct {
+	kind   string
+	points [][2]float64
+}
+
+func box(x, y, w, h float64) shape {
+	return shape{kind: appschema.RegionBox, points: [][2]float64{{x, y}, {w, h}}}
+}
+
+type metric struct {
+	name       string
+	percentage float64
+	present    bool
+	shapes     []shape
+}
+
+// the canned scan: a face roughly centred in the frame
+var (
+	cannedQuantitative = []metric{
+		{name: "wrinkles", percentage: 18.4, shapes: []shape{
+			{kind: appschema.RegionPolygon, points: [][2]float64{{0.30, 0.18}, {0.70, 0.18}, {0.68, 0.28}, {0.32, 0.28}}},
+		}},
+		{name: "pores", percentage: 9.6, shapes: []shape{box(0.22, 0.50, 0.16, 0.12), box(0.62, 0.50, 0.16, 0.12)}},
+		{name: "pigmentation", percentage: 4.2, shapes: []shape{{kind: appschema.RegionPoint, points: [][2]float64{{0.40, 0.62}}}}},
+	}
+	cannedQualitative = []metric{
+		{name: "acne", present: true, shapes: []shape{box(0.55, 0.66, 0.08, 0.06)}},
+		{name: "dark_circles", present: true, shapes: []shape{box(0.30, 0.42, 0.14, 0.05), box(0.56, 0.42, 0.14, 0.05)}},
+		{name: "redness"},
+	}
+)
+
+// analyze answers face/analyzer: the upload comes as a multipart file, or as
+// JSON naming an image_url to fetch
+func (m *mock) analyze(w http.ResponseWriter, r *http.Request) {
+	if m.cfg.APIKey != "" && r.Header.Get("Authorization") != m.cfg.APIKey {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid api key"})
+		return
+	}
+
+	size, err := imageSize(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	failing := m.float64() < m.cfg.FailureRate
+	response := m.result(size)
+
+	format := stream.FormatSSE
+	switch m.cfg.Stream {
+	case StreamNDJSON:
+		format = stream.FormatNDJSON
+		fallthrough
+	case StreamSSE:
+		if strings.Contains(r.Header.Get("Accept"), format.ContentType()) {
+			m.stream(w, r.Context(), format, response, failing)
+			return
+		}
+	}
+
+	if !m.sleep(r.Context(), m.latency()) {
+		return
+	}
+	if failing {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "mock failure"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// stream sends the latency out over stage events before the result, or an
+// error event in its place when failing
+func (m *mock) stream(w http.ResponseWriter, ctx context.Context, format stream.Format, response []byte, failing bool) {
+	w.Header().Set("Content-Type", format.ContentType())
+	flusher, _ := w.(http.Flusher)
+	send := func(msg appschema.FaceScannerStreamEvent) {
+		data, _ := json.Marshal(msg)
+		w.Write(format.Frame(0, msg.Event, data))
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	stages := []appschema.FaceScannerStreamEvent{
+		{Stage: "detecting", Message: "Detecting face", Completion: 15},
+		{Stage: "measuring", Message: "Measuring skin", Completion: 50},
+		{Stage: "classifying", Message: "Classifying conditions", Completion: 80},
+	}
+	step := m.latency() / time.Duration(len(stages)+1)
+	for i, stage := range stages {
+		if !m.sleep(ctx, step) {
+			return
+		}
+		stage.Event = "stage"
+		send(stage)
+		if failing && i == 0 {
+			send(appschema.FaceScannerStreamEvent{Event: "error", Error: "mock failure"})
+			return
+		}
+	}
+	if !m.sleep(ctx, step) {
+		return
+	}
+	send(appschema.FaceScannerStreamEvent{Event: "result", Completion: 100, Data: response})
+}
+
+func (m *mock) latency() time.Duration {
+	latency := m.cfg.Latency
+	if m.cfg.Jitter > 0 {
+		latency += time.Duration(m.float64() * float64(m.cfg.Jitter))
+	}
+	return latency
+}
+
+// sleep waits d unless the caller goes away first
+func (m *mock) sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// result is the analyzer response for an image of size
+func (m *mock) result(size image.Point) []byte {
+	if m.cfg.Result != nil {
+		return m.cfg.Result
+	}
+
+	quantitative, qualitative := cannedQuantitative, cannedQualitative
+	if m.cfg.Random {
+		quantitative, qualitative = m.randomise(cannedQuantitative), m.randomise(cannedQualitative)
+	}
+	data := &appschema.UpstreamScanData{
+		Quantitative: make([]map[string]appschema.Quantitative, 0, len(quantitative)),
+		Qualitative:  make([]map[string]appschema.Qualitative, 0, len(qualitative)),
+	}
+	for _, metric := range quantitative {
+		percentage := metric.percentage
+		data.Quantitative = append(data.Quantitative, map[string]appschema.Quantitative{
+			metric.name: {Percentage: &percentage, Coordinates: coordinates(metric.shapes, size)},
+		})
+	}
+	for _, metric := range qualitative {
+		present := metric.present
+		data.Qualitative = append(data.Qualitative, map[string]appschema.Qualitative{
+			metric.name: {IsPresent: &present, Coordinates: coordinates(metric.shapes, size)},
+		})
+	}
+	response, _ := json.Marshal(appschema.FaceScannerResponse{Data: data})
+	return response
+}
+
+// randomise draws new values for metrics, each with a box or two somewhere
+// on the face when it has any
+func (m *mock) randomise(metrics []metric) []metric {
+	out := make([]metric, len(metrics))
+	for i, metric := range metrics {
+		out[i] = metric
+		out[i].percentage = math.Round(m.float64()*600) / 10
+		out[i].present = m.intN(2) == 1
+		out[i].shapes = nil
+		// absent conditions have nowhere to be
+		if qualitative := metric.percentage == 0; qualitative && !out[i].present {
+			continue
+		}
+		for range 1 + m.intN(2) {
+			w, h := 0.05+m.float64()*0.2, 0.04+m.float64()*0.15
+			out[i].shapes = append(out[i].shapes, box(0.15+m.float64()*(0.7-w), 0.15+m.float64()*(0.7-h), w, h))
+		}
+	}
+	return out
+}
+
+// coordinates writes shapes in the analyzer's own formats: boxes as x, y,
+// width and height objects, points as [x, y] and polygons as lists of them
+func coordinates(shapes []shape, size image.Point) json.RawMessage {
+	scale := func(p [2]float64) [2]int {
+		return [2]int{
+			int(math.Round(p[0] * float64(size.X-1))),
+			int(math.Round(p[1] * float64(size.Y-1))),
+		}
+	}
+	regions := make([]any, 0, len(shapes))
+	for _, s := range shapes {
+		switch s.kind {
+		case appschema.RegionBox:
+			origin := scale(s.points[0])
+			extent := scale(s.points[1])
+			regions = append(regions, map[string]int{"x": origin[0], "y": origin[1], "width": max(1, extent[0]), "height": max(1, extent[1])})
+		case appschema.RegionPoint:
+			regions = append(regions, scale(s.points[0]))
+		default:
+			points := make([][2]int, len(s.points))
+			for i, p := range s.points {
+				points[i] = scale(p)
+			}
+			regions = append(regions, points)
+		}
+	}
+	raw, _ := json.Marshal(regions)
+	return raw
+}
+
+// imageSize reads the upload far enough to learn its dimensions
+func imageSize(r *http.Request) (image.Point, error) {
+	r.Body = http.MaxBytesReader(nil, r.Body, maxImageBytes)
+	var data io.Reader
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body struct {
+			ImageURL string `json:"image_url"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ImageURL == "" {
+			return image.Point{}, errors.New("image_url is required")
+		}
+		fetched, err := fetch(r.Context(), body.ImageURL)
+		if err != nil {
+			return image.Point{}, err
+		}
+		data = bytes.NewReader(fetched)
+	} else {
+		file, _, err := r.FormFile(constants.FACE_ANALYZE_PAYLOAD_FIELD_NAME)
+		if err != nil {
+			return image.Point{}, fmt.Errorf("multipart field %q is required", constants.FACE_ANALYZE_PAYLOAD_FIELD_NAME)
+		}
+		defer file.Close()
+		data = file
+	}
+
+	config, _, err := image.DecodeConfig(data)
+	if err != nil || config.Width < 2 || config.Height < 2 {
+		return image.Point{}, errors.New("unsupported image")
+	}
+	return image.Pt(config.Width, config.Height), nil
+}
+
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("[mock] Fetching image failed: %v", err)
+		return nil, errors.New("image_url could not be fetched")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image_url answered %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxImageBytes))
+}
@@ -0,0 +1,115 @@
+// Package mockservice stands in for the face analyzer and user services during
+// local development. It answers the face/analyzer and auth/account contracts
+// with canned or randomised results, so the API runs without either service.
+package mockservice
+
+import (
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	constants "github.com/muthu-kumar-u/go-sse/const"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// Stream modes of the analyzer
+const (
+	StreamOff    = ""
+	StreamSSE    = "sse"
+	StreamNDJSON = "ndjson"
+)
+
+type Config struct {
+	// Latency delays every analyzer answer, plus up to Jitter more
+	Latency time.Duration
+	Jitter  time.Duration
+	// FailureRate is the share of analyzer calls, 0 to 1, answered 503
+	FailureRate float64
+	// Random answers with fresh metrics and regions each call instead of the
+	// canned result
+	Random bool
+	// Result, when set, is served verbatim as the analyzer's response
+	Result []byte
+	// Stream answers as stage events then the result, when the caller
+	// accepts the format
+	Stream string
+	// APIKey, when set, must be the analyzer request's Authorization
+	APIKey string
+	// Tokens are the bearer tokens the user service knows. With AnyToken,
+	// any other token is a user of its own.
+	Tokens   map[string]appschema.GetUserData
+	AnyToken bool
+	// Seed makes random results and failures repeatable; zero is unseeded
+	Seed uint64
+}
+
+// DefaultTokens are the fixtures used when no others are configured: two users,
+// so ownership checks can be tried out
+var DefaultTokens = map[string]appschema.GetUserData{
+	"dev-token": {
+		ID: "dev-user", FirstName: "Dev", LastName: "User", Email: "dev@example.com",
+		SkinType: "normal", IsVerified: true, CreatedAt: "2024-01-01T00:00:00Z",
+	},
+	"dev-token-2": {
+		ID: "dev-user-2", FirstName: "Second", LastName: "User", Email: "dev2@example.com",
+		SkinType: "oily", IsVerified: true, CreatedAt: "2024-01-01T00:00:00Z",
+	},
+}
+
+type mock struct {
+	cfg Config
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// Handler serves the analyzer on every face/analyzer-style path and the user
+// service on auth/account
+func Handler(cfg Config) http.Handler {
+	if cfg.Tokens == nil {
+		cfg.Tokens = DefaultTokens
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	m := &mock{cfg: cfg, rng: rand.New(rand.NewPCG(seed, seed))}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /"+constants.USER_SERVICE_PATHS[0], m.account)
+	// extra analyses are configured with paths of their own
+	mux.HandleFunc("POST /", m.analyze)
+	return mux
+}
+
+// Listen serves Handler(cfg) on addr in the background and returns its base
+// URL. Port 0 picks a free one.
+func Listen(addr string, cfg Config) (string, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	go func() {
+		if err := http.Serve(listener, Handler(cfg)); err != nil {
+			log.Printf("[mock] Server stopped: %v", err)
+		}
+	}()
+	return fmt.Sprintf("http://%s", listener.Addr()), nil
+}
+
+// float64 and intN guard the shared source, which is not safe for concurrent use
+func (m *mock) float64() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rng.Float64()
+}
+
+func (m *mock) intN(n int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rng.IntN(n)
+}
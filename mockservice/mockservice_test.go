@@ -0,0 +1,264 @@
+package mockservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	constants "github.com/muthu-kumar-u/go-sse/const"
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// upload builds an analyzer request carrying data as the multipart image
+func upload(t *testing.T, field string, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile(field, "face.png")
+	part.Write(data)
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/face/analyzer", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestAccount(t *testing.T) {
+	tests := []struct {
+		name       string
+		auth       string
+		anyToken   bool
+		wantStatus int
+		wantID     string
+	}{
+		{name: "no token", wantStatus: http.StatusUnauthorized},
+		{name: "not bearer", auth: "Basic dev-token", wantStatus: http.StatusUnauthorized},
+		{name: "known", auth: "Bearer dev-token", wantStatus: http.StatusOK, wantID: "dev-user"},
+		{name: "second fixture", auth: "Bearer dev-token-2", wantStatus: http.StatusOK, wantID: "dev-user-2"},
+		{name: "unknown", auth: "Bearer someone", wantStatus: http.StatusUnauthorized},
+		{name: "any token", auth: "Bearer someone", anyToken: true, wantStatus: http.StatusOK, wantID: "user-someone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+constants.USER_SERVICE_PATHS[0], nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			Handler(Config{AnyToken: tt.anyToken}).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			var resp appschema.UserAccountResponse
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp.Data.ID != tt.wantID {
+				t.Fatalf("user = %q, want %q", resp.Data.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	face := testPNG(t, 200, 100)
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/face.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(face)
+	}))
+	defer images.Close()
+
+	jsonRequest := func(body string) func(t *testing.T) *http.Request {
+		return func(t *testing.T) *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/face/analyzer", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			return req
+		}
+	}
+	tests := []struct {
+		name       string
+		cfg        Config
+		request    func(t *testing.T) *http.Request
+		auth       string
+		wantStatus int
+	}{
+		{name: "multipart", request: func(t *testing.T) *http.Request { return upload(t, constants.FACE_ANALYZE_PAYLOAD_FIELD_NAME, face) }, wantStatus: http.StatusOK},
+		{name: "image url", request: jsonRequest(`{"image_url": "` + images.URL + `/face.png"}`), wantStatus: http.StatusOK},
+		{name: "image url missing", request: jsonRequest(`{}`), wantStatus: http.StatusBadRequest},
+		{name: "image url not found", request: jsonRequest(`{"image_url": "` + images.URL + `/none.png"}`), wantStatus: http.StatusBadRequest},
+		{name: "wrong field", request: func(t *testing.T) *http.Request { return upload(t, "photo", face) }, wantStatus: http.StatusBadRequest},
+		{name: "not an image", request: func(t *testing.T) *http.Request {
+			return upload(t, constants.FACE_ANALYZE_PAYLOAD_FIELD_NAME, []byte("nope"))
+		}, wantStatus: http.StatusBadRequest},
+		{
+			name:       "api key missing",
+			cfg:        Config{APIKey: "secret"},
+			request:    func(t *testing.T) *http.Request { return upload(t, constants.FACE_ANALYZE_PAYLOAD_FIELD_NAME, face) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "api key given",
+			cfg:        Config{APIKey: "secret"},
+			request:    func(t *testing.T) *http.Request { return upload(t, constants.FACE_ANALYZE_PAYLOAD_FIELD_NAME, face) },
+			auth:       "secret",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "always failing",
+			cfg:        Config{FailureRate: 1},
+			request:    func(t *testing.T) *http.Request { return upload(t, constants.FACE_ANALYZE_PAYLOAD_FIELD_NAME, face) },
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.request(t)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			Handler(tt.cfg).ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp appschema.FaceScannerResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data == nil {
+				t.Fatalf("response %s: %v", w.Body, err)
+			}
+		})
+	}
+}
+
+// TestResultRegionsFitTheImage checks the canned and random results are ones
+// the API accepts: every region parses and lies inside the uploaded image
+func TestResultRegionsFitTheImage(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		w, h int
+	}{
+		{name: "canned", w: 640, h: 480},
+		{name: "canned, tiny image", w: 2, h: 2},
+		{name: "random", cfg: Config{Random: true, Seed: 1}, w: 300, h: 500},
+		{name: "random, another seed", cfg: Config{Random: true, Seed: 42}, w: 1024, h: 768},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Handler(tt.cfg)
+			for range 5 {
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, upload(t, constants.FACE_ANALYZE_PAYLOAD_FIELD_NAME, testPNG(t, tt.w, tt.h)))
+				var resp appschema.FaceScannerResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("response %s: %v", w.Body, err)
+				}
+
+				var coordinates []json.RawMessage
+				for _, entry := range resp.Data.Quantitative {
+					for _, metric := range entry {
+						if p := *metric.Percentage; p < 0 || p > 100 {
+							t.Errorf("percentage %v out of range", p)
+						}
+						coordinates = append(coordinates, metric.Coordinates)
+					}
+				}
+				for _, entry := range resp.Data.Qualitative {
+					for _, metric := range entry {
+						coordinates = append(coordinates, metric.Coordinates)
+					}
+				}
+				for _, raw := range coordinates {
+					regions, err := appschema.ParseRegions(raw)
+					if err != nil {
+						t.Fatalf("coordinates %s: %v", raw, err)
+					}
+					for _, region := range regions {
+						lo, hi := region.Bounds()
+						if lo.X < 0 || lo.Y < 0 || hi.X > float64(tt.w) || hi.Y > float64(tt.h) {
+							t.Errorf("%s region %v outside %dx%d", region.Type, region.Points, tt.w, tt.h)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        Config
+		accept     string
+		wantType   string
+		wantEvents []string
+	}{
+		{name: "sse", cfg: Config{Stream: StreamSSE}, accept: "text/event-stream", wantType: "text/event-stream", wantEvents: []string{"stage", "stage", "stage", "result"}},
+		{name: "ndjson", cfg: Config{Stream: StreamNDJSON}, accept: "application/x-ndjson", wantType: "application/x-ndjson", wantEvents: []string{"stage", "stage", "stage", "result"}},
+		{name: "failing", cfg: Config{Stream: StreamSSE, FailureRate: 1}, accept: "text/event-stream", wantType: "text/event-stream", wantEvents: []string{"stage", "error"}},
+		{name: "caller does not accept the stream", cfg: Config{Stream: StreamSSE}, accept: "application/json", wantType: "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := upload(t, constants.FACE_ANALYZE_PAYLOAD_FIELD_NAME, testPNG(t, 64, 64))
+			req.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			Handler(tt.cfg).ServeHTTP(w, req)
+
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.wantType) {
+				t.Fatalf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			if tt.wantEvents == nil {
+				return
+			}
+			format := stream.FormatSSE
+			if tt.cfg.Stream == StreamNDJSON {
+				format = stream.FormatNDJSON
+			}
+			reader := stream.NewReader(w.Body, format)
+			var events []string
+			for {
+				frame, err := reader.Next()
+				if err != nil {
+					break
+				}
+				var msg appschema.FaceScannerStreamEvent
+				if err := json.Unmarshal(frame.Data, &msg); err != nil {
+					t.Fatalf("frame %s: %v", frame.Data, err)
+				}
+				events = append(events, msg.Event)
+			}
+			if strings.Join(events, ",") != strings.Join(tt.wantEvents, ",") {
+				t.Fatalf("events = %v, want %v", events, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestResultFileServedVerbatim(t *testing.T) {
+	result := []byte(`{"data":{"quantitative":[],"qualitative":[]}}`)
+	w := httptest.NewRecorder()
+	Handler(Config{Result: result}).ServeHTTP(w, upload(t, constants.FACE_ANALYZE_PAYLOAD_FIELD_NAME, testPNG(t, 8, 8)))
+	if w.Body.String() != string(result) {
+		t.Fatalf("body = %s, want %s", w.Body, result)
+	}
+}
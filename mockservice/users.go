@@ -0,0 +1,35 @@
+package mockservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// account answers auth/account: the user behind the bearer token, or 401
+func (m *mock) account(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	token = strings.TrimSpace(token)
+	if !ok || token == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "missing bearer token"})
+		return
+	}
+
+	user, known := m.cfg.Tokens[token]
+	if !known && !m.cfg.AnyToken {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "unknown token"})
+		return
+	}
+	if !known {
+		user = appschema.GetUserData{ID: "user-" + token, FirstName: "Dev", Email: token + "@example.com", IsVerified: true}
+	}
+	writeJSON(w, http.StatusOK, appschema.UserAccountResponse{Data: user})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,52 @@
+package appschema
+
+import "time"
+
+// Qualitative changes between two scans
+const (
+	ChangeAppeared    = "appeared"
+	ChangeDisappeared = "disappeared"
+	ChangeUnchanged   = "unchanged"
+	// ChangeUnavailable is a metric only one of the scans reported
+	ChangeUnavailable = "unavailable"
+)
+
+// ScanComparison is how a scan differs from an earlier one. Deltas are after
+// minus before.
+type ScanComparison struct {
+	Before       ComparedScan                `json:"before"`
+	After        ComparedScan                `json:"after"`
+	Quantitative map[string]QuantitativeDiff `json:"quantitative"`
+	Qualitative  map[string]QualitativeDiff  `json:"qualitative"`
+	Appeared     []string                    `json:"appeared"`
+	Disappeared  []string                    `json:"disappeared"`
+	// ImageURL serves both annotated images side by side when both are stored
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+// ComparedScan identifies one side of a comparison
+type ComparedScan struct {
+	ID         string    `json:"id"`
+	JobID      string    `json:"job_id"`
+	ImageIndex int       `json:"image_index"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// QuantitativeDiff compares a percentage metric. A side is nil when its scan
+// did not report the metric; Delta needs both. RegionOverlap is the
+// intersection over union of the areas the metric covers, set when either
+// side covers one.
+type QuantitativeDiff struct {
+	Before        *float64 `json:"before"`
+	After         *float64 `json:"after"`
+	Delta         *float64 `json:"delta"`
+	RegionOverlap *float64 `json:"region_overlap,omitempty"`
+}
+
+// QualitativeDiff compares a present-or-not metric
+type QualitativeDiff struct {
+	Before        *bool    `json:"before"`
+	After         *bool    `json:"after"`
+	Change        string   `json:"change"`
+	RegionOverlap *float64 `json:"region_overlap,omitempty"`
+}
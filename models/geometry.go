@@ -0,0 +1,189 @@
+package appschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Region types
+const (
+	RegionPoint   = "point"
+	RegionLine    = "line"
+	RegionBox     = "box"
+	RegionPolygon = "polygon"
+)
+
+// Point is a position in image pixels, [x, y] on the wire
+type Point struct {
+	X, Y float64
+}
+
+func (p Point) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]float64{p.X, p.Y})
+}
+
+func (p *Point) UnmarshalJSON(data []byte) error {
+	var xy []float64
+	if err := json.Unmarshal(data, &xy); err != nil {
+		return err
+	}
+	if len(xy) != 2 {
+		return fmt.Errorf("point has %d values, want 2", len(xy))
+	}
+	p.X, p.Y = xy[0], xy[1]
+	return nil
+}
+
+// Region is one detected area. Points holds a point's position, a line's two
+// ends, a box's top-left and bottom-right corners or a polygon's vertices.
+type Region struct {
+	Type   string  `json:"type"`
+	Points []Point `json:"points"`
+}
+
+// Bounds returns the corners of the smallest box holding the region
+func (r Region) Bounds() (Point, Point) {
+	if len(r.Points) == 0 {
+		return Point{}, Point{}
+	}
+	lo, hi := r.Points[0], r.Points[0]
+	for _, p := range r.Points[1:] {
+		lo.X, lo.Y = math.Min(lo.X, p.X), math.Min(lo.Y, p.Y)
+		hi.X, hi.Y = math.Max(hi.X, p.X), math.Max(hi.Y, p.Y)
+	}
+	return lo, hi
+}
+
+// ParseRegions reads analyzer coordinates into regions. Analyzers are not
+// consistent about the format, so besides Region itself it accepts:
+//
+//	[x, y]                                a point
+//	[x, y, width, height]                 a box
+//	[[x, y], [x, y], ...]                 a line for two points, else a polygon
+//	{"x", "y"[, "width", "height"]}       a point or a box
+//	{"points": [...]}                     any of the above
+//
+// and lists of any of these. Missing or empty coordinates are no regions.
+func ParseRegions(raw json.RawMessage) ([]Region, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	return parseRegions(v)
+}
+
+func parseRegions(v any) ([]Region, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		if len(v) == 0 {
+			return nil, nil
+		}
+		if numbers, ok := numberList(v); ok {
+			switch len(numbers) {
+			case 2:
+				return []Region{{Type: RegionPoint, Points: []Point{{numbers[0], numbers[1]}}}}, nil
+			case 4:
+				return []Region{box(numbers[0], numbers[1], numbers[2], numbers[3])}, nil
+			}
+			return nil, fmt.Errorf("coordinate list of %d numbers is neither a point nor a box", len(numbers))
+		}
+		if points, ok := pointList(v); ok {
+			switch len(points) {
+			case 1:
+				return []Region{{Type: RegionPoint, Points: points}}, nil
+			case 2:
+				return []Region{{Type: RegionLine, Points: points}}, nil
+			}
+			return []Region{{Type: RegionPolygon, Points: points}}, nil
+		}
+		var regions []Region
+		for _, item := range v {
+			parsed, err := parseRegions(item)
+			if err != nil {
+				return nil, err
+			}
+			regions = append(regions, parsed...)
+		}
+		return regions, nil
+	case map[string]any:
+		if _, ok := v["type"]; ok {
+			return typedRegion(v)
+		}
+		if points, ok := v["points"]; ok {
+			return parseRegions(points)
+		}
+		x, okX := v["x"].(float64)
+		y, okY := v["y"].(float64)
+		if !okX || !okY {
+			return nil, errors.New("coordinate object needs numeric x and y")
+		}
+		w, okW := v["width"].(float64)
+		h, okH := v["height"].(float64)
+		if okW && okH {
+			return []Region{box(x, y, w, h)}, nil
+		}
+		return []Region{{Type: RegionPoint, Points: []Point{{x, y}}}}, nil
+	}
+	return nil, fmt.Errorf("unexpected coordinate value %v", v)
+}
+
+// typedRegion reads a region already in Region's own form
+func typedRegion(v map[string]any) ([]Region, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var region Region
+	if err := json.Unmarshal(encoded, &region); err != nil {
+		return nil, err
+	}
+	want := map[string]int{RegionPoint: 1, RegionLine: 2, RegionBox: 2}[region.Type]
+	switch {
+	case region.Type == RegionPolygon && len(region.Points) < 3:
+		return nil, fmt.Errorf("polygon has %d points, want at least 3", len(region.Points))
+	case region.Type != RegionPolygon && want == 0:
+		return nil, fmt.Errorf("unknown region type %q", region.Type)
+	case want != 0 && len(region.Points) != want:
+		return nil, fmt.Errorf("%s has %d points, want %d", region.Type, len(region.Points), want)
+	}
+	return []Region{region}, nil
+}
+
+func numberList(list []any) ([]float64, bool) {
+	out := make([]float64, len(list))
+	for i, item := range list {
+		n, ok := item.(float64)
+		if !ok {
+			return nil, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
+
+func pointList(list []any) ([]Point, bool) {
+	points := make([]Point, len(list))
+	for i, item := range list {
+		pair, ok := item.([]any)
+		if !ok {
+			return nil, false
+		}
+		xy, ok := numberList(pair)
+		if !ok || len(xy) != 2 {
+			return nil, false
+		}
+		points[i] = Point{xy[0], xy[1]}
+	}
+	return points, true
+}
+
+func box(x, y, w, h float64) Region {
+	return Region{Type: RegionBox, Points: []Point{{x, y}, {x + w, y + h}}}
+}
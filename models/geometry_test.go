@@ -0,0 +1,84 @@
+package appschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestParseRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{name: "missing", raw: "", want: "[]"},
+		{name: "null", raw: "null", want: "[]"},
+		{name: "empty list", raw: "[]", want: "[]"},
+		{name: "point", raw: "[10, 20]", want: `[{"type":"point","points":[[10,20]]}]`},
+		{name: "box from x, y, width, height", raw: "[10, 20, 30, 40]", want: `[{"type":"box","points":[[10,20],[40,60]]}]`},
+		{name: "line", raw: "[[0, 0], [5, 5]]", want: `[{"type":"line","points":[[0,0],[5,5]]}]`},
+		{name: "polygon", raw: "[[0, 0], [5, 0], [5, 5]]", want: `[{"type":"polygon","points":[[0,0],[5,0],[5,5]]}]`},
+		{name: "single pair list", raw: "[[1, 2]]", want: `[{"type":"point","points":[[1,2]]}]`},
+		{name: "object point", raw: `{"x": 1, "y": 2}`, want: `[{"type":"point","points":[[1,2]]}]`},
+		{name: "object box", raw: `{"x": 1, "y": 2, "width": 3, "height": 4}`, want: `[{"type":"box","points":[[1,2],[4,6]]}]`},
+		{name: "points wrapper", raw: `{"points": [[0, 0], [5, 5]]}`, want: `[{"type":"line","points":[[0,0],[5,5]]}]`},
+		{name: "typed region", raw: `{"type": "box", "points": [[0, 0], [5, 5]]}`, want: `[{"type":"box","points":[[0,0],[5,5]]}]`},
+		{name: "list of mixed", raw: `[[1, 2], {"x": 3, "y": 4}, [0, 0, 1, 1]]`, want: `[{"type":"point","points":[[1,2]]},{"type":"point","points":[[3,4]]},{"type":"box","points":[[0,0],[1,1]]}]`},
+		{name: "three numbers", raw: "[1, 2, 3]", wantErr: true},
+		{name: "object without y", raw: `{"x": 1}`, wantErr: true},
+		{name: "unknown type", raw: `{"type": "circle", "points": [[0, 0]]}`, wantErr: true},
+		{name: "short polygon", raw: `{"type": "polygon", "points": [[0, 0], [1, 1]]}`, wantErr: true},
+		{name: "box with one corner", raw: `{"type": "box", "points": [[0, 0]]}`, wantErr: true},
+		{name: "string", raw: `"here"`, wantErr: true},
+		{name: "not json", raw: `[1,`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regions, err := ParseRegions(json.RawMessage(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRegions = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if regions == nil {
+				regions = []Region{}
+			}
+			got, _ := json.Marshal(regions)
+			if string(got) != tt.want {
+				t.Fatalf("ParseRegions = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegionBounds(t *testing.T) {
+	tests := []struct {
+		region Region
+		lo, hi Point
+	}{
+		{region: Region{}, lo: Point{}, hi: Point{}},
+		{region: Region{Type: RegionPoint, Points: []Point{{3, 4}}}, lo: Point{3, 4}, hi: Point{3, 4}},
+		{region: Region{Type: RegionPolygon, Points: []Point{{5, 1}, {0, 9}, {2, -3}}}, lo: Point{0, -3}, hi: Point{5, 9}},
+	}
+	for _, tt := range tests {
+		lo, hi := tt.region.Bounds()
+		if lo != tt.lo || hi != tt.hi {
+			t.Errorf("Bounds(%v) = %v, %v; want %v, %v", tt.region, lo, hi, tt.lo, tt.hi)
+		}
+	}
+}
+
+func TestPointJSON(t *testing.T) {
+	var p Point
+	for _, raw := range []string{`[1]`, `[1, 2, 3]`, `{"x": 1}`} {
+		if err := json.Unmarshal([]byte(raw), &p); err == nil {
+			t.Errorf("Unmarshal(%s) = %v, want an error", raw, p)
+		}
+	}
+	if err := json.Unmarshal([]byte(`[1.5, 2]`), &p); err != nil || fmt.Sprint(p) != "{1.5 2}" {
+		t.Fatalf("Unmarshal = %v, %v", p, err)
+	}
+}
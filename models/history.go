@@ -0,0 +1,59 @@
+package appschema
+
+import "time"
+
+// ScanRecord is one completed scan in a user's history
+type ScanRecord struct {
+	ID         string        `json:"id"`
+	OwnerID    string        `json:"-"`
+	JobID      string        `json:"job_id"`
+	ImageIndex int           `json:"image_index"`
+	Analysis   string        `json:"analysis"`
+	Filename   string        `json:"filename,omitempty"`
+	Result     *FaceScanData `json:"result"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type ScanList struct {
+	Scans      []ScanRecord `json:"scans"`
+	NextOffset int          `json:"next_offset,omitempty"`
+}
+
+// ScanTrends summarises how each metric moved across a range of scans, oldest
+// to newest
+type ScanTrends struct {
+	From         *time.Time                   `json:"from,omitempty"`
+	To           *time.Time                   `json:"to,omitempty"`
+	Scans        int                          `json:"scans"`
+	Quantitative map[string]QuantitativeTrend `json:"quantitative"`
+	Qualitative  map[string]QualitativeTrend  `json:"qualitative"`
+}
+
+// QuantitativeTrend follows a percentage metric. Change is Last minus First.
+type QuantitativeTrend struct {
+	Samples int          `json:"samples"`
+	First   float64      `json:"first"`
+	Last    float64      `json:"last"`
+	Change  float64      `json:"change"`
+	Min     float64      `json:"min"`
+	Max     float64      `json:"max"`
+	Mean    float64      `json:"mean"`
+	Series  []TrendPoint `json:"series"`
+}
+
+type TrendPoint struct {
+	ScanID     string    `json:"scan_id"`
+	At         time.Time `json:"at"`
+	Percentage float64   `json:"percentage"`
+}
+
+// QualitativeTrend follows a present-or-not metric: how often it was present
+// and when it first and last was
+type QualitativeTrend struct {
+	Samples       int        `json:"samples"`
+	PresentIn     int        `json:"present_in"`
+	FirstSeen     *time.Time `json:"first_seen,omitempty"`
+	FirstSeenScan string     `json:"first_seen_scan,omitempty"`
+	LastSeen      *time.Time `json:"last_seen,omitempty"`
+	PresentLatest bool       `json:"present_in_latest"`
+}
@@ -0,0 +1,103 @@
+package appschema
+
+import "time"
+
+// FaceScanJob is a validated upload waiting for, or being processed by, a worker
+type FaceScanJob struct {
+	ID          string
+	StreamID    string
+	OwnerID     string
+	CallbackURL string
+	// Analysis names the pipeline the images go through
+	Analysis  string
+	Images    []JobImage
+	CreatedAt time.Time
+}
+
+// JobImage is one validated image of an upload, in form order
+type JobImage struct {
+	Index       int
+	Filename    string
+	ContentType string
+	Data        []byte
+	// ObjectKey is set when the image was uploaded straight to object storage
+	ObjectKey string
+}
+
+// IsBatch reports whether the job carries more than one image. Batches report
+// each image with image_done/image_error and finish with batch_done.
+func (j *FaceScanJob) IsBatch() bool {
+	return len(j.Images) > 1
+}
+
+type JobStatus string
+
+const (
+	// JobAwaitingUpload is a direct upload whose image has not landed yet
+	JobAwaitingUpload JobStatus = "awaiting_upload"
+	JobQueued         JobStatus = "queued"
+	JobRunning        JobStatus = "running"
+	JobSucceeded      JobStatus = "succeeded"
+	JobFailed         JobStatus = "failed"
+	JobCancelled      JobStatus = "cancelled"
+)
+
+// JobStage records when a job reached a pipeline stage
+type JobStage struct {
+	Name       string    `json:"name"`
+	ImageIndex *int      `json:"image_index,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// JobRecord is the persisted state of a face scan job
+type JobRecord struct {
+	ID      string `json:"id"`
+	OwnerID string `json:"-"`
+	// CallbackURL is where the job's terminal event is delivered, if anywhere
+	CallbackURL  string        `json:"-"`
+	StreamID     string        `json:"stream_id"`
+	Analysis     string        `json:"analysis"`
+	Status       JobStatus     `json:"status"`
+	Filename     string        `json:"filename,omitempty"`
+	Stages       []JobStage    `json:"stages"`
+	Result       *FaceScanData `json:"result,omitempty"`
+	Batch        *BatchSummary `json:"batch,omitempty"`
+	Objects      []StoredImage `json:"objects,omitempty"`
+	ErrorCode    string        `json:"error_code,omitempty"`
+	ErrorMessage string        `json:"error_message,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	StartedAt    *time.Time    `json:"started_at,omitempty"`
+	FinishedAt   *time.Time    `json:"finished_at,omitempty"`
+	ExpiresAt    time.Time     `json:"expires_at"`
+}
+
+// StoredImage locates a copy of an uploaded image in object storage. Kind is
+// original or preprocessed.
+type StoredImage struct {
+	ImageIndex int    `json:"image_index"`
+	Kind       string `json:"kind"`
+	Key        string `json:"key"`
+}
+
+type JobList struct {
+	Jobs       []JobRecord `json:"jobs"`
+	NextOffset int         `json:"next_offset,omitempty"`
+}
+
+// BatchImageResult is the outcome of one image in a batch upload
+type BatchImageResult struct {
+	Index     int           `json:"image_index"`
+	Filename  string        `json:"filename"`
+	Status    JobStatus     `json:"status"`
+	Result    *FaceScanData `json:"result,omitempty"`
+	ErrorCode string        `json:"error_code,omitempty"`
+	Message   string        `json:"message,omitempty"`
+}
+
+// BatchSummary is the payload of a batch_done event
+type BatchSummary struct {
+	Total     int                `json:"total"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Images    []BatchImageResult `json:"images"`
+}
@@ -1,27 +1,94 @@
 package appschema
 
-// face scanner service stream structs
+import "encoding/json"
+
+// face scanner service stream structs. Fields are pointers and raw so that a
+// response can be checked for what is missing before it is normalised into
+// FaceScanData.
 type Quantitative struct {
-	Percentage  float64 `json:"percentage"`
-	Coordinates any     `json:"coordinates"`
+	Percentage  *float64        `json:"percentage"`
+	Coordinates json.RawMessage `json:"coordinates"`
 }
 
 type Qualitative struct {
-	IsPresent   bool `json:"is_present"`
-	Coordinates any  `json:"coordinates"`
+	IsPresent   *bool           `json:"is_present"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// UpstreamScanData is the face scanner's result format
+type UpstreamScanData struct {
+	Qualitative  []map[string]Qualitative  `json:"qualitative"`
+	Quantitative []map[string]Quantitative `json:"quantitative"`
 }
 
 type FaceScannerResponse struct {
-	Data FaceScanData `json:"data"`
+	Data *UpstreamScanData `json:"data"`
 }
 
+// FaceScannerStreamEvent is one message of a streaming face scanner response.
+// Event is stage, partial, result or error; an SSE event name fills it in when
+// the payload leaves it out.
+type FaceScannerStreamEvent struct {
+	Event      string          `json:"event"`
+	Stage      string          `json:"stage"`
+	Message    string          `json:"message"`
+	Completion float64         `json:"completion"`
+	Data       json.RawMessage `json:"data"`
+	Error      string          `json:"error"`
+}
+
+// ResultSchemaVersion is the shape of FaceScanData. It moves on its own, not
+// with the face scanner's format; bump it whenever FaceScanData changes.
+const ResultSchemaVersion = 2
+
+// FaceScanData is a scan result as this service reports it, whatever format
+// the analyzer answered in
 type FaceScanData struct {
-	Qualitative  []map[string]Qualitative  `json:"qualitative"`
-	Quantitative []map[string]Quantitative `json:"quantitative"`
+	SchemaVersion int                           `json:"schema_version"`
+	Quantitative  map[string]QuantitativeMetric `json:"quantitative"`
+	Qualitative   map[string]QualitativeMetric  `json:"qualitative"`
+	// Transform is set when the image was preprocessed; coordinates above are
+	// in the preprocessed image's space
+	Transform *ImageTransform `json:"transform,omitempty"`
+	// AnnotatedURL serves the image with the regions above drawn over it, when
+	// annotation is enabled
+	AnnotatedURL string `json:"annotated_url,omitempty"`
+}
+
+// QuantitativeMetric is a metric measured as a percentage of the face
+type QuantitativeMetric struct {
+	Percentage float64  `json:"percentage"`
+	Regions    []Region `json:"regions"`
+}
+
+// QualitativeMetric is a condition that is either present or not
+type QualitativeMetric struct {
+	IsPresent bool     `json:"is_present"`
+	Regions   []Region `json:"regions"`
+}
+
+// ImageTransform records how an upload was reshaped before analysis. Original
+// dimensions are of the upright image, after its EXIF orientation was applied.
+type ImageTransform struct {
+	Orientation    int     `json:"orientation"`
+	OriginalWidth  int     `json:"original_width"`
+	OriginalHeight int     `json:"original_height"`
+	Width          int     `json:"width"`
+	Height         int     `json:"height"`
+	Scale          float64 `json:"scale"`
+}
+
+// ToOriginal maps a point in the analysed image back onto the upright original
+func (t *ImageTransform) ToOriginal(x, y float64) (float64, float64) {
+	if t == nil || t.Scale == 0 {
+		return x, y
+	}
+	return x / t.Scale, y / t.Scale
 }
 
 // user service structs
 type GetUserData struct {
+	ID         string `json:"id"`
 	FirstName  string `json:"first_name"`
 	LastName   string `json:"last_name"`
 	Gender     string `json:"gender"`
@@ -32,3 +99,7 @@ type GetUserData struct {
 	IsVerified bool   `json:"is_active"`
 	CreatedAt  string `json:"created_at"`
 }
+
+type UserAccountResponse struct {
+	Data GetUserData `json:"data"`
+}
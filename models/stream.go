@@ -5,6 +5,11 @@ type EventMessage struct {
 	Data           any       `json:"data,omitempty"`
 	Event          string    `json:"event,omitempty"`
 	Message        string    `json:"message,omitempty"`
+	ErrorCode      string    `json:"error_code,omitempty"`
 	StreamID       string    `json:"stream_id,omitempty"`
+	ImageIndex     *int      `json:"image_index,omitempty"`
+	Filename       string    `json:"filename,omitempty"`
 	Completion     int       `json:"stream_completion,omitempty"`
-}
\ No newline at end of file
+	// Cached marks a done event answered from an earlier analysis of the same image
+	Cached         bool      `json:"cached,omitempty"`
+}
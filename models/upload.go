@@ -0,0 +1,25 @@
+package appschema
+
+import "time"
+
+// DirectUploadRequest asks for a presigned URL to put one image straight into
+// object storage
+type DirectUploadRequest struct {
+	Filename string `json:"filename" binding:"required"`
+	// Analysis defaults to the face analysis
+	Analysis string `json:"analysis"`
+	// CallbackURL overrides the client's registered webhook for this job
+	CallbackURL string `json:"callback_url"`
+}
+
+// DirectUpload tells the client where to PUT the image, which headers to send
+// with it and the stream its scan will report on
+type DirectUpload struct {
+	JobID     string            `json:"job_id"`
+	StreamID  string            `json:"stream"`
+	Key       string            `json:"key"`
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
@@ -0,0 +1,19 @@
+package appschema
+
+import "time"
+
+// webhook delivery kept in the retry queue until the receiver acknowledges it
+type WebhookDelivery struct {
+	ID            string       `json:"id"`
+	URL           string       `json:"url"`
+	StreamID      string       `json:"stream_id"`
+	Event         EventMessage `json:"event"`
+	Attempts      int          `json:"attempts"`
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	LastError     string       `json:"last_error,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+type WebhookRegistration struct {
+	URL string `json:"url" binding:"required"`
+}
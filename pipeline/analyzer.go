@@ -0,0 +1,157 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/storage"
+	"github.com/muthu-kumar-u/go-sse/upstream"
+	"github.com/muthu-kumar-u/go-sse/utils"
+)
+
+// Analyzer is the backend of the analyze stage
+type Analyzer interface {
+	// Analyze returns the image's result, reporting progress through report
+	Analyze(ctx context.Context, image *Image, report Reporter) (*appschema.FaceScanData, error)
+}
+
+// HTTPAnalyzer posts the image as a multipart form to an analysis service. The
+// service may answer with plain JSON or stream its progress as SSE or NDJSON.
+type HTTPAnalyzer struct {
+	Client *upstream.Client
+	// URL is the service endpoint the image is posted to
+	URL     string
+	AuthKey string
+	// Field is the multipart field holding the image
+	Field string
+	// Store and PresignTTL, when set, send stored images as a presigned URL in
+	// a JSON body instead of uploading their bytes
+	Store      storage.Store
+	PresignTTL time.Duration
+}
+
+// imageURLRequest is the body sent in place of the image bytes
+type imageURLRequest struct {
+	ImageURL    string `json:"image_url"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+}
+
+func (a *HTTPAnalyzer) Analyze(ctx context.Context, image *Image, report Reporter) (*appschema.FaceScanData, error) {
+	filename := image.Filename
+	if image.Transform != nil {
+		filename = utils.JPEGFilename(filename)
+	}
+	body, contentType, err := a.body(image, filename)
+	if err != nil {
+		return nil, err
+	}
+	// regions are checked against the image the service is sent
+	size := imageSize(image.Data)
+
+	// each attempt replays the same body
+	newRequest := func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", a.AuthKey)
+		req.Header.Set("Content-Type", contentType)
+		// analyzers that can stream progress will, the rest answer with plain JSON
+		req.Header.Set("Accept", "text/event-stream, application/x-ndjson;q=0.9, application/json;q=0.8")
+		return req, nil
+	}
+	onRetry := func(retry upstream.Retry) {
+		log.Printf("[pipeline] Image %d (%s): attempt %d failed, retrying in %s: %s", image.Index, image.Filename, retry.Attempt, retry.Delay, retry.Reason)
+		report(0, &appschema.EventMessage{
+			Code:    http.StatusAccepted,
+			Event:   faceanalyze_events.EventRetrying,
+			Message: fmt.Sprintf("Face analyze attempt %d of %d failed, retrying", retry.Attempt, retry.MaxAttempts),
+			Data: gin.H{
+				"attempt":      retry.Attempt,
+				"max_attempts": retry.MaxAttempts,
+				"delay_ms":     retry.Delay.Milliseconds(),
+			},
+		})
+	}
+
+	resp, err := a.Client.Do(ctx, newRequest, onRetry)
+	if err != nil {
+		log.Printf("[pipeline] Image %d (%s): analyze call failed: %v", image.Index, image.Filename, err)
+		switch {
+		case errors.Is(err, upstream.ErrCircuitOpen):
+			return nil, &Error{http.StatusServiceUnavailable, faceanalyze_events.ErrCodeCircuitOpen, "Face analyze service unavailable, retry later"}
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, err
+		}
+		return nil, &Error{http.StatusBadGateway, faceanalyze_events.ErrCodeUpstreamUnavailable, "Face analyze failed"}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Printf("[pipeline] Image %d (%s): analyze failed: %s", image.Index, image.Filename, string(body))
+		return nil, &Error{http.StatusBadGateway, faceanalyze_events.ErrCodeUpstreamFailed, "Face scan error"}
+	}
+
+	if format, streaming := stream.NegotiateFormat(resp.Header.Get("Content-Type")); streaming {
+		data, err := relayStream(resp.Body, format, size, report)
+		if err != nil {
+			log.Printf("[pipeline] Image %d (%s): analyze stream failed: %v", image.Index, image.Filename, err)
+			var reported *reportedError
+			var invalid *invalidResponseError
+			switch {
+			case errors.As(err, &reported):
+				return nil, &Error{http.StatusBadGateway, faceanalyze_events.ErrCodeUpstreamFailed, "Face scan error: " + reported.message}
+			case errors.As(err, &invalid):
+				return nil, &Error{http.StatusBadGateway, faceanalyze_events.ErrCodeInvalidUpstream, "Invalid face scan response: " + invalid.Error()}
+			case errors.Is(err, context.DeadlineExceeded):
+				return nil, err
+			}
+			return nil, &Error{http.StatusBadGateway, faceanalyze_events.ErrCodeUpstreamUnavailable, "Face analyze stream interrupted"}
+		}
+		return data, nil
+	}
+
+	var faResp appschema.FaceScannerResponse
+	if err := utils.BindHttpResponseToStruct(resp, &faResp); err != nil {
+		log.Printf("[pipeline] Image %d (%s): analyze response unreadable: %v", image.Index, image.Filename, err)
+		return nil, &Error{http.StatusBadGateway, faceanalyze_events.ErrCodeInvalidUpstream, "Invalid face scan response"}
+	}
+	data, err := normalise(faResp.Data, size)
+	if err != nil {
+		log.Printf("[pipeline] Image %d (%s): analyze response invalid: %v", image.Index, image.Filename, err)
+		return nil, &Error{http.StatusBadGateway, faceanalyze_events.ErrCodeInvalidUpstream, "Invalid face scan response: " + err.Error()}
+	}
+	return data, nil
+}
+
+// body encodes the image for the service: a presigned link when the image is
+// stored and links are enabled, otherwise the bytes as a multipart form
+func (a *HTTPAnalyzer) body(image *Image, filename string) ([]byte, string, error) {
+	if a.Store != nil && a.PresignTTL > 0 && image.ObjectKey != "" {
+		url, err := a.Store.PresignGet(image.ObjectKey, a.PresignTTL)
+		if err == nil {
+			body, err := json.Marshal(imageURLRequest{ImageURL: url, Filename: filename, ContentType: image.ContentType})
+			return body, "application/json", err
+		}
+		log.Printf("[pipeline] Image %d (%s): presign failed, sending bytes: %v", image.Index, image.Filename, err)
+	}
+
+	payload, err := utils.PrepareImagePayload(image.Data, filename, a.Field)
+	if err != nil {
+		return nil, "", err
+	}
+	return payload.MultipartBody.Bytes(), payload.MultipartWriter.FormDataContentType(), nil
+}
@@ -0,0 +1,154 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/upstream"
+)
+
+func TestHTTPAnalyzer(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantErr     string
+		wantEvents  []string
+		wantMetrics []string
+	}{
+		{
+			name:        "plain json",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"data": {"quantitative": [{"acne": {"percentage": 12.5, "coordinates": [10, 10, 20, 20]}}]}}`,
+			wantMetrics: []string{"acne"},
+		},
+		{
+			name:        "sse partials closed by a bare result",
+			status:      http.StatusOK,
+			contentType: "text/event-stream",
+			body: "event: stage\ndata: {\"stage\": \"detect\", \"completion\": 30}\n\n" +
+				": keep-alive\n\n" +
+				"event: partial\ndata: {\"completion\": 60, \"data\": {\"quantitative\": [{\"acne\": {\"percentage\": 12.5}}]}}\n\n" +
+				"event: partial\ndata: {\"completion\": 80, \"data\": {\"qualitative\": [{\"redness\": {\"is_present\": true}}]}}\n\n" +
+				"event: result\ndata: {\"completion\": 100}\n\n",
+			wantEvents:  []string{faceanalyze_events.EventAnalyzingFace, faceanalyze_events.EventPartialResult, faceanalyze_events.EventPartialResult},
+			wantMetrics: []string{"acne", "redness"},
+		},
+		{
+			name:        "ndjson wrapped result",
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson",
+			body: `{"event": "stage", "message": "Detecting", "completion": 50}` + "\n\n" +
+				`{"event": "result", "data": {"data": {"quantitative": [{"wrinkles": {"percentage": 3}}]}}}` + "\n",
+			wantEvents:  []string{faceanalyze_events.EventAnalyzingFace},
+			wantMetrics: []string{"wrinkles"},
+		},
+		{
+			name:        "error in the stream",
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson",
+			body:        `{"event": "error", "error": "model crashed"}` + "\n",
+			wantErr:     faceanalyze_events.ErrCodeUpstreamFailed,
+		},
+		{
+			name:        "stream ends without a result",
+			status:      http.StatusOK,
+			contentType: "text/event-stream",
+			body:        "event: stage\ndata: {\"completion\": 10}\n\n",
+			wantErr:     faceanalyze_events.ErrCodeInvalidUpstream,
+			wantEvents:  []string{faceanalyze_events.EventAnalyzingFace},
+		},
+		{
+			name:        "unreadable stream message",
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson",
+			body:        "not json\n",
+			wantErr:     faceanalyze_events.ErrCodeInvalidUpstream,
+		},
+		{
+			name:        "invalid result",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"data": {"quantitative": [{"acne": {"percentage": 150}}]}}`,
+			wantErr:     faceanalyze_events.ErrCodeInvalidUpstream,
+		},
+		{
+			name:        "upstream error status",
+			status:      http.StatusInternalServerError,
+			contentType: "application/json",
+			body:        `{"error": "boom"}`,
+			wantErr:     faceanalyze_events.ErrCodeUpstreamFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, _, err := r.FormFile("image"); err != nil {
+					http.Error(w, "no image", http.StatusBadRequest)
+					return
+				}
+				if r.Header.Get("Authorization") != "key" {
+					http.Error(w, "no key", http.StatusUnauthorized)
+					return
+				}
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			analyzer := &HTTPAnalyzer{
+				Client:  upstream.NewClient(server.Client(), upstream.Config{MaxAttempts: 1}, nil),
+				URL:     server.URL,
+				AuthKey: "key",
+				Field:   "image",
+			}
+			var events []string
+			fraction := 0.0
+			report := func(f float64, event *appschema.EventMessage) {
+				if f < fraction {
+					t.Errorf("fraction went back from %v to %v", fraction, f)
+				}
+				fraction = f
+				events = append(events, event.Event)
+			}
+
+			data, err := analyzer.Analyze(context.Background(), &Image{Filename: "face.png", Data: testPNG(t)}, report)
+			if len(events) != len(tt.wantEvents) {
+				t.Fatalf("events = %v, want %v", events, tt.wantEvents)
+			}
+			for i := range events {
+				if events[i] != tt.wantEvents[i] {
+					t.Fatalf("events = %v, want %v", events, tt.wantEvents)
+				}
+			}
+			if tt.wantErr != "" {
+				var pipelineErr *Error
+				if !errors.As(err, &pipelineErr) || pipelineErr.Code != tt.wantErr {
+					t.Fatalf("Analyze error = %v, want code %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			for _, name := range tt.wantMetrics {
+				_, quantitative := data.Quantitative[name]
+				_, qualitative := data.Qualitative[name]
+				if !quantitative && !qualitative {
+					t.Errorf("result has no %s: %+v", name, data)
+				}
+			}
+			if got := len(data.Quantitative) + len(data.Qualitative); got != len(tt.wantMetrics) {
+				t.Errorf("result has %d metrics, want %d", got, len(tt.wantMetrics))
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Package pipeline runs uploaded images through named, weighted analysis stages
+// and reports their progress to an EventSink.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/muthu-kumar-u/go-sse/cache"
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// Image is one upload moving through a pipeline. Stages may replace Data and
+// fill in Transform and Result as they go.
+type Image struct {
+	Index    int
+	Filename string
+	// OwnerID scopes cached results and stored objects to the uploader
+	OwnerID     string
+	JobID       string
+	ContentType string
+	Data        []byte
+	// ObjectKey is where Data is stored, empty until a store stage puts it
+	ObjectKey string
+	Transform *appschema.ImageTransform
+	Result    *appschema.FaceScanData
+}
+
+// Error is a stage failure with the status and code reported in the error event
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Reporter publishes an event for the running stage, fraction (0-1) of the way
+// through it
+type Reporter func(fraction float64, event *appschema.EventMessage)
+
+type StageFunc func(ctx context.Context, image *Image, report Reporter) error
+
+// Stage is one named step of a pipeline. Weight is its share of the completion
+// percentage. Upfront stages run while the upload request is still open, so
+// their failures reject it; the rest run on a worker.
+type Stage struct {
+	Name    string
+	Weight  int
+	Upfront bool
+	// SkipWhenCached marks the stage a cached result stands in for; the
+	// others still run so a cache hit is stored and annotated like any scan
+	SkipWhenCached bool
+	// Event, when set, announces the stage as it starts
+	Event   string
+	Message string
+	Run     StageFunc
+}
+
+type Pipeline struct {
+	Name   string
+	Stages []Stage
+	// Version changes whenever the same image could produce a different result,
+	// retiring cached ones
+	Version string
+	// Cache, when set, answers repeated images without running the analysis
+	Cache *cache.Cache
+}
+
+// Check runs the upfront stages; they report nothing
+func (p *Pipeline) Check(ctx context.Context, image *Image) error {
+	for _, stage := range p.Stages {
+		if !stage.Upfront {
+			continue
+		}
+		if err := stage.Run(ctx, image, func(float64, *appschema.EventMessage) {}); err != nil {
+			return asError(err)
+		}
+	}
+	return nil
+}
+
+// Run takes a checked image through the remaining stages, emitting progress,
+// then done with the result or error with the failure. A cancelled ctx ends the
+// run without a terminal event, returning its cause. Events carry the image's
+// index, filename and own completion. An image the owner had analysed before
+// skips the analysis and is answered with the cached result.
+func (p *Pipeline) Run(ctx context.Context, image *Image, sink EventSink) (*appschema.FaceScanData, error) {
+	total := 0
+	for _, stage := range p.Stages {
+		total += stage.Weight
+	}
+	if total <= 0 {
+		total = 1
+	}
+
+	emit := func(completion int, event *appschema.EventMessage) {
+		index := image.Index
+		event.ImageIndex = &index
+		event.Filename = image.Filename
+		event.Completion = completion
+		sink.Emit(event)
+	}
+	fail := func(err error) (*appschema.FaceScanData, error) {
+		// a cancelled run stops quietly; whoever cancelled it reports the outcome
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, context.Cause(ctx)
+		}
+		stageErr := asError(err)
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			stageErr = &Error{http.StatusGatewayTimeout, faceanalyze_events.ErrCodeTimeout, "Face scan timed out"}
+		}
+		emit(100, &appschema.EventMessage{
+			Code:      stageErr.Status,
+			Event:     faceanalyze_events.EventError,
+			ErrorCode: stageErr.Code,
+			Message:   stageErr.Message,
+		})
+		return nil, stageErr
+	}
+
+	// keyed on the bytes as uploaded, before any stage reshapes them
+	var (
+		cacheKey string
+		cached   bool
+	)
+	if p.Cache != nil && image.OwnerID != "" {
+		cacheKey = cache.Key(image.OwnerID, p.Name, p.Version, image.Data)
+		if result, ok := p.Cache.Get(ctx, cacheKey); ok {
+			image.Result, cached = result, true
+		}
+	}
+
+	done := 0
+	for _, stage := range p.Stages {
+		if stage.Upfront || (cached && stage.SkipWhenCached) {
+			done += stage.Weight
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+
+		start, weight := done, stage.Weight
+		report := func(fraction float64, event *appschema.EventMessage) {
+			fraction = min(max(fraction, 0), 1)
+			// 100 belongs to done
+			emit(min((start*100+int(fraction*float64(weight*100)))/total, 99), event)
+		}
+		if stage.Event != "" {
+			report(0, &appschema.EventMessage{Code: http.StatusAccepted, Event: stage.Event, Message: stage.Message})
+		}
+		if err := stage.Run(ctx, image, report); err != nil {
+			return fail(err)
+		}
+		done += weight
+	}
+
+	if image.Result == nil {
+		return fail(&Error{http.StatusBadGateway, faceanalyze_events.ErrCodeInvalidUpstream, "Analysis produced no result"})
+	}
+	if cacheKey != "" && !cached {
+		// the annotation belongs to this job rather than to the image
+		entry := *image.Result
+		entry.AnnotatedURL = ""
+		p.Cache.Set(ctx, cacheKey, &entry)
+	}
+	emit(100, &appschema.EventMessage{
+		Code:    http.StatusOK,
+		Event:   faceanalyze_events.EventCompleted,
+		Data:    image.Result,
+		Message: "Scan complete",
+		Cached:  cached,
+	})
+	return image.Result, nil
+}
+
+func asError(err error) *Error {
+	var stageErr *Error
+	if errors.As(err, &stageErr) {
+		return stageErr
+	}
+	return &Error{http.StatusInternalServerError, faceanalyze_events.ErrCodeInternal, "Internal error"}
+}
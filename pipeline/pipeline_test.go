@@ -0,0 +1,180 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/muthu-kumar-u/go-sse/cache"
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/storage"
+	"github.com/muthu-kumar-u/go-sse/utils"
+)
+
+// countingAnalyzer answers every image with the same result, counting calls
+type countingAnalyzer struct {
+	calls atomic.Int32
+}
+
+func (a *countingAnalyzer) Analyze(ctx context.Context, image *Image, report Reporter) (*appschema.FaceScanData, error) {
+	a.calls.Add(1)
+	report(0.5, &appschema.EventMessage{Event: "analyzing"})
+	return &appschema.FaceScanData{
+		SchemaVersion: appschema.ResultSchemaVersion,
+		Quantitative:  map[string]appschema.QuantitativeMetric{"wrinkles": {Percentage: 12}},
+	}, nil
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRun(t *testing.T) {
+	analyzer := &countingAnalyzer{}
+	p := New("face_scan", analyzer, Options{
+		Limits: utils.ImageLimits{MaxBytes: 1 << 20, MinDimension: 1, MaxDimension: 4096, MaxPixels: 1 << 24},
+	})
+	image := &Image{Index: 1, Filename: "face.png", Data: testPNG(t)}
+	if err := p.Check(context.Background(), image); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	var events []*appschema.EventMessage
+	result, err := p.Run(context.Background(), image, SinkFunc(func(event *appschema.EventMessage) error {
+		events = append(events, event)
+		return nil
+	}))
+	if err != nil || result == nil {
+		t.Fatalf("Run = %v, %v", result, err)
+	}
+	if analyzer.calls.Load() != 1 {
+		t.Fatalf("analyzer called %d times, want 1", analyzer.calls.Load())
+	}
+
+	completion := 0
+	for _, event := range events {
+		if event.Completion < completion {
+			t.Errorf("completion went back from %d to %d at %s", completion, event.Completion, event.Event)
+		}
+		completion = event.Completion
+		if event.ImageIndex == nil || *event.ImageIndex != 1 || event.Filename != "face.png" {
+			t.Errorf("%s event is not tagged with its image: %+v", event.Event, event)
+		}
+	}
+	last := events[len(events)-1]
+	if last.Event != faceanalyze_events.EventCompleted || last.Completion != 100 {
+		t.Fatalf("last event = %s at %d%%, want done at 100%%", last.Event, last.Completion)
+	}
+}
+
+func TestRunCacheHit(t *testing.T) {
+	analyzer := &countingAnalyzer{}
+	images := storage.NewMemory(time.Hour)
+	p := New("face_scan", analyzer, Options{
+		Limits:        utils.ImageLimits{MaxBytes: 1 << 20, MinDimension: 1, MaxDimension: 4096, MaxPixels: 1 << 24},
+		Weights:       map[string]int{StageStoreOriginal: 5, StageAnnotate: 5},
+		Version:       "v1",
+		Cache:         cache.New(10, time.Hour, nil),
+		Storage:       images,
+		StoragePrefix: "scans",
+		Annotations:   images,
+		AnnotationURL: func(jobID string, index int) string { return fmt.Sprintf("/jobs/%s/images/%d/annotated", jobID, index) },
+	})
+	data := testPNG(t)
+
+	tests := []struct {
+		name       string
+		owner      string
+		job        string
+		wantCached bool
+		wantCalls  int32
+	}{
+		{name: "first scan", owner: "u1", job: "j1", wantCached: false, wantCalls: 1},
+		{name: "same image again", owner: "u1", job: "j2", wantCached: true, wantCalls: 1},
+		{name: "another owner", owner: "u2", job: "j3", wantCached: false, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := &Image{Filename: "face.png", OwnerID: tt.owner, JobID: tt.job, Data: data}
+			if err := p.Check(context.Background(), img); err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+
+			var events []*appschema.EventMessage
+			result, err := p.Run(context.Background(), img, SinkFunc(func(event *appschema.EventMessage) error {
+				events = append(events, event)
+				return nil
+			}))
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got := analyzer.calls.Load(); got != tt.wantCalls {
+				t.Errorf("analyzer called %d times, want %d", got, tt.wantCalls)
+			}
+			if result.Quantitative["wrinkles"].Percentage != 12 {
+				t.Errorf("result = %+v", result)
+			}
+
+			// store and annotate run on a hit all the same, under the new job
+			wantURL := fmt.Sprintf("/jobs/%s/images/0/annotated", tt.job)
+			if result.AnnotatedURL != wantURL {
+				t.Errorf("AnnotatedURL = %q, want %q", result.AnnotatedURL, wantURL)
+			}
+			for _, key := range []string{
+				storage.ImageKey("scans", tt.owner, tt.job, 0, storage.KindOriginal, ".png"),
+				storage.ImageKey("scans", tt.owner, tt.job, 0, storage.KindAnnotated, ".png"),
+			} {
+				if _, err := images.Size(context.Background(), key); err != nil {
+					t.Errorf("%s not stored: %v", key, err)
+				}
+			}
+
+			done := events[len(events)-1]
+			if done.Event != faceanalyze_events.EventCompleted || done.Completion != 100 || done.Cached != tt.wantCached {
+				t.Errorf("last event = %+v, want done with Cached %v", done, tt.wantCached)
+			}
+			last := 0
+			for _, event := range events {
+				if event.Completion < last {
+					t.Errorf("completion went back from %d to %d at %s", last, event.Completion, event.Event)
+				}
+				last = event.Completion
+			}
+		})
+	}
+}
+
+func TestRunCacheKeepsNoAnnotation(t *testing.T) {
+	c := cache.New(10, time.Hour, nil)
+	images := storage.NewMemory(time.Hour)
+	p := New("face_scan", &countingAnalyzer{}, Options{
+		Version:       "v1",
+		Cache:         c,
+		Annotations:   images,
+		AnnotationURL: func(jobID string, index int) string { return "/annotated/" + jobID },
+	})
+	data := testPNG(t)
+
+	img := &Image{Filename: "face.png", OwnerID: "u1", JobID: "j1", ContentType: "image/png", Data: data}
+	if _, err := p.Run(context.Background(), img, SinkFunc(func(*appschema.EventMessage) error { return nil })); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	entry, ok := c.Get(context.Background(), cache.Key("u1", p.Name, p.Version, data))
+	if !ok {
+		t.Fatal("result was not cached")
+	}
+	if entry.AnnotatedURL != "" {
+		t.Fatalf("cached AnnotatedURL = %q, want none", entry.AnnotatedURL)
+	}
+}
@@ -0,0 +1,45 @@
+package pipeline
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultAnalysis is the pipeline served by the original upload route
+const DefaultAnalysis = "face"
+
+// Registry holds the pipelines available to upload routes by analysis name
+type Registry struct {
+	mu        sync.RWMutex
+	pipelines map[string]*Pipeline
+}
+
+func NewRegistry() *Registry {
+	return &Registry{pipelines: make(map[string]*Pipeline)}
+}
+
+func (r *Registry) Register(p *Pipeline) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pipelines[p.Name] = p
+}
+
+func (r *Registry) Get(name string) (*Pipeline, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.pipelines[name]
+	return p, ok
+}
+
+// Names lists the registered analyses in a stable order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.pipelines))
+	for name := range r.pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/muthu-kumar-u/go-sse/storage"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	face := &Pipeline{Name: DefaultAnalysis}
+	registry.Register(&Pipeline{Name: "skin_tone"})
+	registry.Register(&Pipeline{Name: DefaultAnalysis})
+	// registering a name again replaces its pipeline
+	registry.Register(face)
+
+	tests := []struct {
+		name   string
+		want   *Pipeline
+		wantOK bool
+	}{
+		{name: DefaultAnalysis, want: face, wantOK: true},
+		{name: "skin_tone", wantOK: true},
+		{name: "unknown"},
+	}
+	for _, tt := range tests {
+		p, ok := registry.Get(tt.name)
+		if ok != tt.wantOK || (tt.want != nil && p != tt.want) {
+			t.Errorf("Get(%q) = %p, %v; want %p, %v", tt.name, p, ok, tt.want, tt.wantOK)
+		}
+	}
+	if got := fmt.Sprint(registry.Names()); got != "[face skin_tone]" {
+		t.Errorf("Names = %s", got)
+	}
+}
+
+func TestNewStages(t *testing.T) {
+	store := storage.NewMemory(time.Hour)
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "without stores",
+			want: []string{StageValidate, StagePreprocess, StageAnalyze, StagePostprocess},
+		},
+		{
+			name: "with stores",
+			opts: Options{Storage: store, Annotations: store},
+			want: []string{StageValidate, StageStoreOriginal, StagePreprocess, StageStorePreprocessed, StageAnalyze, StagePostprocess, StageAnnotate},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New("face", &countingAnalyzer{}, tt.opts)
+			var got []string
+			for _, stage := range p.Stages {
+				got = append(got, stage.Name)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("stages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// normalise checks an analyzer's result and converts it to FaceScanData.
+// Regions must fall inside size, the analysed image's dimensions; a zero size
+// skips that check. Anything missing or out of range is an
+// invalidResponseError.
+func normalise(raw *appschema.UpstreamScanData, size image.Point) (*appschema.FaceScanData, error) {
+	invalid := func(format string, args ...any) error {
+		return &invalidResponseError{fmt.Errorf(format, args...)}
+	}
+	if raw == nil || len(raw.Quantitative)+len(raw.Qualitative) == 0 {
+		return nil, invalid("result has no metrics")
+	}
+
+	data := &appschema.FaceScanData{
+		SchemaVersion: appschema.ResultSchemaVersion,
+		Quantitative:  make(map[string]appschema.QuantitativeMetric),
+		Qualitative:   make(map[string]appschema.QualitativeMetric),
+	}
+	for _, entry := range raw.Quantitative {
+		for name, metric := range entry {
+			if _, dup := data.Quantitative[name]; dup || name == "" {
+				return nil, invalid("quantitative metric %q is empty or repeated", name)
+			}
+			if metric.Percentage == nil {
+				return nil, invalid("quantitative metric %q has no percentage", name)
+			}
+			if p := *metric.Percentage; p < 0 || p > 100 {
+				return nil, invalid("quantitative metric %q percentage %g is out of range", name, p)
+			}
+			regions, err := regions(metric.Coordinates, size)
+			if err != nil {
+				return nil, invalid("quantitative metric %q: %w", name, err)
+			}
+			data.Quantitative[name] = appschema.QuantitativeMetric{Percentage: *metric.Percentage, Regions: regions}
+		}
+	}
+	for _, entry := range raw.Qualitative {
+		for name, metric := range entry {
+			if _, dup := data.Qualitative[name]; dup || name == "" {
+				return nil, invalid("qualitative metric %q is empty or repeated", name)
+			}
+			if metric.IsPresent == nil {
+				return nil, invalid("qualitative metric %q has no is_present", name)
+			}
+			regions, err := regions(metric.Coordinates, size)
+			if err != nil {
+				return nil, invalid("qualitative metric %q: %w", name, err)
+			}
+			data.Qualitative[name] = appschema.QualitativeMetric{IsPresent: *metric.IsPresent, Regions: regions}
+		}
+	}
+	return data, nil
+}
+
+func regions(coordinates []byte, size image.Point) ([]appschema.Region, error) {
+	regions, err := appschema.ParseRegions(coordinates)
+	if err != nil {
+		return nil, err
+	}
+	// an empty list rather than null, so clients need not tell them apart
+	if regions == nil {
+		regions = []appschema.Region{}
+	}
+	if size == (image.Point{}) {
+		return regions, nil
+	}
+	for _, region := range regions {
+		lo, hi := region.Bounds()
+		if lo.X < 0 || lo.Y < 0 || hi.X > float64(size.X) || hi.Y > float64(size.Y) {
+			return nil, fmt.Errorf("%s region reaches outside the %dx%d image", region.Type, size.X, size.Y)
+		}
+		if region.Type == appschema.RegionBox && (region.Points[1].X <= region.Points[0].X || region.Points[1].Y <= region.Points[0].Y) {
+			return nil, errors.New("box region has no area")
+		}
+	}
+	return regions, nil
+}
+
+// imageSize is the pixel size of an image, zero when it cannot be read
+func imageSize(data []byte) image.Point {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return image.Point{}
+	}
+	return image.Pt(config.Width, config.Height)
+}
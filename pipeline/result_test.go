@@ -0,0 +1,94 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"testing"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/utils"
+)
+
+func TestNormalise(t *testing.T) {
+	size := image.Pt(100, 100)
+	tests := []struct {
+		name    string
+		raw     string
+		size    image.Point
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			raw: `{"quantitative": [{"acne": {"percentage": 12.5, "coordinates": [10, 10, 20, 20]}}],
+				"qualitative": [{"redness": {"is_present": true, "coordinates": [[1, 1], [5, 1], [5, 5]]}}]}`,
+			size: size,
+		},
+		{name: "no metrics", raw: `{}`, wantErr: true},
+		{name: "no percentage", raw: `{"quantitative": [{"acne": {}}]}`, wantErr: true},
+		{name: "percentage over 100", raw: `{"quantitative": [{"acne": {"percentage": 101}}]}`, wantErr: true},
+		{name: "negative percentage", raw: `{"quantitative": [{"acne": {"percentage": -1}}]}`, wantErr: true},
+		{name: "repeated metric", raw: `{"quantitative": [{"acne": {"percentage": 1}}, {"acne": {"percentage": 2}}]}`, wantErr: true},
+		{name: "empty name", raw: `{"quantitative": [{"": {"percentage": 1}}]}`, wantErr: true},
+		{name: "no is_present", raw: `{"qualitative": [{"redness": {}}]}`, wantErr: true},
+		{name: "unreadable coordinates", raw: `{"quantitative": [{"acne": {"percentage": 1, "coordinates": [1, 2, 3]}}]}`, wantErr: true},
+		{name: "region outside the image", raw: `{"quantitative": [{"acne": {"percentage": 1, "coordinates": [90, 90, 20, 20]}}]}`, size: size, wantErr: true},
+		{name: "outside an unknown size", raw: `{"quantitative": [{"acne": {"percentage": 1, "coordinates": [90, 90, 20, 20]}}]}`},
+		{name: "box without area", raw: `{"quantitative": [{"acne": {"percentage": 1, "coordinates": [10, 10, 0, 5]}}]}`, size: size, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw appschema.UpstreamScanData
+			if err := json.Unmarshal([]byte(tt.raw), &raw); err != nil {
+				t.Fatal(err)
+			}
+			data, err := normalise(&raw, tt.size)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalise = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				var invalid *invalidResponseError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("error %v is not an invalidResponseError", err)
+				}
+				return
+			}
+			if data.SchemaVersion != appschema.ResultSchemaVersion {
+				t.Errorf("SchemaVersion = %d, want %d", data.SchemaVersion, appschema.ResultSchemaVersion)
+			}
+			// metrics without coordinates still get an empty list
+			for name, metric := range data.Quantitative {
+				if metric.Regions == nil {
+					t.Errorf("%s has nil regions", name)
+				}
+			}
+		})
+	}
+}
+
+func TestNormaliseNil(t *testing.T) {
+	if _, err := normalise(nil, image.Point{}); err == nil {
+		t.Fatal("normalise(nil) succeeded")
+	}
+}
+
+func TestPipelineVersion(t *testing.T) {
+	base := New("face_scan", nil, Options{Version: "m1"})
+	tests := []struct {
+		name string
+		opts Options
+		same bool
+	}{
+		{name: "same options", opts: Options{Version: "m1"}, same: true},
+		{name: "another model", opts: Options{Version: "m2"}},
+		{name: "preprocessing on", opts: Options{Version: "m1", Preprocess: utils.PreprocessConfig{Enabled: true, MaxEdge: 1024, Quality: 85}}},
+	}
+	for _, tt := range tests {
+		if got := New("face_scan", nil, tt.opts).Version; (got == base.Version) != tt.same {
+			t.Errorf("%s: version %q against %q, want equal %v", tt.name, got, base.Version, tt.same)
+		}
+	}
+	if a, b := New("", nil, Options{Preprocess: utils.PreprocessConfig{Enabled: true, MaxEdge: 1024, Quality: 85}}), New("", nil, Options{Preprocess: utils.PreprocessConfig{Enabled: true, MaxEdge: 512, Quality: 85}}); a.Version == b.Version {
+		t.Errorf("preprocessing sizes share version %q", a.Version)
+	}
+}
@@ -0,0 +1,28 @@
+package pipeline
+
+import appschema "github.com/muthu-kumar-u/go-sse/models"
+
+// EventSink receives the events a pipeline publishes, e.g. the stream hub or a
+// response being written inline
+type EventSink interface {
+	Emit(event *appschema.EventMessage) error
+}
+
+type SinkFunc func(event *appschema.EventMessage) error
+
+func (f SinkFunc) Emit(event *appschema.EventMessage) error {
+	return f(event)
+}
+
+// MultiSink emits to every sink in turn, returning the first error
+type MultiSink []EventSink
+
+func (m MultiSink) Emit(event *appschema.EventMessage) error {
+	var first error
+	for _, sink := range m {
+		if err := sink.Emit(event); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,252 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/muthu-kumar-u/go-sse/annotate"
+	"github.com/muthu-kumar-u/go-sse/cache"
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/storage"
+	"github.com/muthu-kumar-u/go-sse/utils"
+)
+
+const (
+	StageValidate          = "validate"
+	StageStoreOriginal     = "store_original"
+	StagePreprocess        = "preprocess"
+	StageStorePreprocessed = "store_preprocessed"
+	StageAnalyze           = "analyze"
+	StagePostprocess       = "postprocess"
+	StageAnnotate          = "annotate"
+)
+
+// DefaultWeights splits the completion percentage across the standard stages;
+// the store stages weigh nothing unless configured
+var DefaultWeights = map[string]int{
+	StageValidate:    25,
+	StagePreprocess:  25,
+	StageAnalyze:     45,
+	StagePostprocess: 5,
+}
+
+// Options configures the standard stages of a pipeline
+type Options struct {
+	Limits     utils.ImageLimits
+	Preprocess utils.PreprocessConfig
+	// Weights overrides DefaultWeights per stage name
+	Weights map[string]int
+	// Version identifies the analyzer's model; see Pipeline.Version
+	Version string
+	Cache   *cache.Cache
+	// Storage, when set, keeps the original and preprocessed images under
+	// StoragePrefix/<owner>/<job>/
+	Storage       storage.Store
+	StoragePrefix string
+	// Annotations, when set, keeps a PNG of each result drawn over its image,
+	// served at AnnotationURL
+	Annotations   storage.Store
+	AnnotationURL func(jobID string, index int) string
+}
+
+// New builds the standard validate, preprocess, analyze and post-process
+// pipeline around analyzer, storing the images on the way when configured
+func New(name string, analyzer Analyzer, opts Options) *Pipeline {
+	weight := func(stage string) int {
+		if w, ok := opts.Weights[stage]; ok {
+			return w
+		}
+		return DefaultWeights[stage]
+	}
+
+	// preprocessing changes what the analyzer sees and the result schema what
+	// is cached, so both are part of the version
+	version := fmt.Sprintf("%s+schema.%d", opts.Version, appschema.ResultSchemaVersion)
+	if opts.Preprocess.Enabled {
+		version += fmt.Sprintf("+preprocess.%d.%d", opts.Preprocess.MaxEdge, opts.Preprocess.Quality)
+	}
+
+	return &Pipeline{
+		Name:    name,
+		Version: version,
+		Cache:   opts.Cache,
+		Stages: slices.DeleteFunc([]Stage{
+			ValidateStage(opts.Limits, weight(StageValidate)),
+			StoreStage(StageStoreOriginal, opts.Storage, opts.StoragePrefix, weight(StageStoreOriginal)),
+			PreprocessStage(opts.Preprocess, weight(StagePreprocess)),
+			StoreStage(StageStorePreprocessed, opts.Storage, opts.StoragePrefix, weight(StageStorePreprocessed)),
+			AnalyzeStage(analyzer, weight(StageAnalyze)),
+			PostprocessStage(weight(StagePostprocess)),
+			AnnotateStage(opts.Annotations, opts.StoragePrefix, opts.AnnotationURL, weight(StageAnnotate)),
+		}, func(stage Stage) bool { return stage.Run == nil }),
+	}
+}
+
+// ValidateStage checks the upload's content against limits before it is queued
+func ValidateStage(limits utils.ImageLimits, weight int) Stage {
+	return Stage{
+		Name:    StageValidate,
+		Weight:  weight,
+		Upfront: true,
+		Run: func(ctx context.Context, image *Image, report Reporter) error {
+			info, err := utils.ValidateImage(image.Data, image.Filename, limits)
+			if err != nil {
+				var imageErr *utils.ImageError
+				if errors.As(err, &imageErr) {
+					return &Error{imageErr.Status, imageErr.Code, imageErr.Message}
+				}
+				return err
+			}
+			image.ContentType = info.ContentType
+			return nil
+		},
+	}
+}
+
+// PreprocessStage normalises orientation, size and encoding when enabled
+func PreprocessStage(config utils.PreprocessConfig, weight int) Stage {
+	return Stage{
+		Name:    StagePreprocess,
+		Weight:  weight,
+		Event:   faceanalyze_events.EventProcessingImage,
+		Message: "Processing image",
+		Run: func(ctx context.Context, image *Image, report Reporter) error {
+			if !config.Enabled {
+				return nil
+			}
+			processed, transform, err := utils.PreprocessImage(image.Data, config)
+			if err != nil {
+				log.Printf("[pipeline] Image %d (%s): preprocess failed: %v", image.Index, image.Filename, err)
+				return &Error{http.StatusUnprocessableEntity, faceanalyze_events.ErrCodePreprocessFailed, "Failed to preprocess image"}
+			}
+			image.Data, image.ContentType, image.Transform = processed, "image/jpeg", transform
+			image.ObjectKey = ""
+
+			report(1, &appschema.EventMessage{
+				Code:    http.StatusAccepted,
+				Event:   faceanalyze_events.EventPreprocessingImage,
+				Message: "Image preprocessed",
+				Data:    transform,
+			})
+			return nil
+		},
+	}
+}
+
+// StoreStage puts the image in store unless it is already there, i.e. the
+// original until preprocessing replaces it. Storage is best effort: a failure
+// is logged and the image is analysed all the same. Without a store the stage
+// is left out.
+func StoreStage(name string, store storage.Store, prefix string, weight int) Stage {
+	if store == nil {
+		return Stage{Name: name}
+	}
+	return Stage{
+		Name:   name,
+		Weight: weight,
+		Run: func(ctx context.Context, image *Image, report Reporter) error {
+			if image.ObjectKey != "" {
+				return nil
+			}
+			kind := storage.KindOriginal
+			if image.Transform != nil {
+				kind = storage.KindPreprocessed
+			}
+			key := storage.ImageKey(prefix, image.OwnerID, image.JobID, image.Index, kind, storage.ImageExt(image.ContentType))
+
+			if err := store.Put(ctx, key, image.ContentType, image.Data); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Printf("[pipeline] Image %d (%s): storing %s failed: %v", image.Index, image.Filename, kind, err)
+				return nil
+			}
+			image.ObjectKey = key
+
+			report(1, &appschema.EventMessage{
+				Code:    http.StatusAccepted,
+				Event:   faceanalyze_events.EventImageStored,
+				Message: "Image stored",
+				Data:    &appschema.StoredImage{ImageIndex: image.Index, Kind: kind, Key: key},
+			})
+			return nil
+		},
+	}
+}
+
+// AnalyzeStage hands the image to analyzer
+func AnalyzeStage(analyzer Analyzer, weight int) Stage {
+	return Stage{
+		Name:           StageAnalyze,
+		Weight:         weight,
+		SkipWhenCached: true,
+		Event:          faceanalyze_events.EventAnalyzingFace,
+		Message:        "Analyzing face",
+		Run: func(ctx context.Context, image *Image, report Reporter) error {
+			result, err := analyzer.Analyze(ctx, image, report)
+			if err != nil {
+				return err
+			}
+			image.Result = result
+			return nil
+		},
+	}
+}
+
+// PostprocessStage attaches what earlier stages learned to the result
+func PostprocessStage(weight int) Stage {
+	return Stage{
+		Name:   StagePostprocess,
+		Weight: weight,
+		Run: func(ctx context.Context, image *Image, report Reporter) error {
+			if image.Result != nil {
+				image.Result.Transform = image.Transform
+			}
+			return nil
+		},
+	}
+}
+
+// AnnotateStage renders the result over the analysed image and links it from
+// the result. Like storage it is best effort; without a store it is left out.
+func AnnotateStage(store storage.Store, prefix string, url func(jobID string, index int) string, weight int) Stage {
+	if store == nil {
+		return Stage{Name: StageAnnotate}
+	}
+	return Stage{
+		Name:   StageAnnotate,
+		Weight: weight,
+		Run: func(ctx context.Context, image *Image, report Reporter) error {
+			if image.Result == nil {
+				return nil
+			}
+			annotated, err := annotate.Render(image.Data, image.Result)
+			if err != nil {
+				log.Printf("[pipeline] Image %d (%s): annotate failed: %v", image.Index, image.Filename, err)
+				return nil
+			}
+			key := storage.ImageKey(prefix, image.OwnerID, image.JobID, image.Index, storage.KindAnnotated, ".png")
+			if err := store.Put(ctx, key, "image/png", annotated); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Printf("[pipeline] Image %d (%s): storing annotation failed: %v", image.Index, image.Filename, err)
+				return nil
+			}
+			image.Result.AnnotatedURL = url(image.JobID, image.Index)
+
+			report(1, &appschema.EventMessage{
+				Code:    http.StatusAccepted,
+				Event:   faceanalyze_events.EventImageStored,
+				Message: "Annotated image stored",
+				Data:    &appschema.StoredImage{ImageIndex: image.Index, Kind: storage.KindAnnotated, Key: key},
+			})
+			return nil
+		},
+	}
+}
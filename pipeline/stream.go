@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+	"github.com/muthu-kumar-u/go-sse/events/stream"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// upstream stream message types
+const (
+	upstreamStage   = "stage"
+	upstreamPartial = "partial"
+	upstreamResult  = "result"
+	upstreamError   = "error"
+)
+
+// reportedError is a failure the analyzer sent inside its own stream
+type reportedError struct {
+	message string
+}
+
+func (e *reportedError) Error() string {
+	return e.message
+}
+
+// invalidResponseError is a stream message that could not be understood
+type invalidResponseError struct {
+	err error
+}
+
+func (e *invalidResponseError) Error() string { return e.err.Error() }
+func (e *invalidResponseError) Unwrap() error { return e.err }
+
+// relayStream follows a streaming analyzer response, reporting its stage updates
+// and partial results as they arrive, and returns the final result normalised
+// against size. The analyzer's own completion becomes the analyze stage's
+// fraction.
+func relayStream(body io.Reader, format stream.Format, size image.Point, report Reporter) (*appschema.FaceScanData, error) {
+	reader := stream.NewReader(body, format)
+	partial := &appschema.UpstreamScanData{}
+	fraction := 0.0
+
+	for {
+		wire, err := reader.Next()
+		if err == io.EOF {
+			return nil, &invalidResponseError{fmt.Errorf("stream ended without a result")}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var msg appschema.FaceScannerStreamEvent
+		if err := json.Unmarshal(wire.Data, &msg); err != nil {
+			return nil, &invalidResponseError{err}
+		}
+		if msg.Event == "" {
+			msg.Event = wire.Event
+		}
+		// progress never moves backwards
+		fraction = max(fraction, min(msg.Completion/100, 1))
+
+		switch msg.Event {
+		case upstreamStage:
+			message := msg.Message
+			if message == "" {
+				message = "Analyzing face"
+			}
+			report(fraction, &appschema.EventMessage{
+				Code:    http.StatusAccepted,
+				Event:   faceanalyze_events.EventAnalyzingFace,
+				Message: message,
+				Data:    gin.H{"stage": msg.Stage},
+			})
+
+		case upstreamPartial:
+			raw, err := decodeScanData(msg.Data)
+			if err != nil {
+				return nil, &invalidResponseError{err}
+			}
+			data, err := normalise(raw, size)
+			if err != nil {
+				return nil, err
+			}
+			partial.Qualitative = append(partial.Qualitative, raw.Qualitative...)
+			partial.Quantitative = append(partial.Quantitative, raw.Quantitative...)
+			report(fraction, &appschema.EventMessage{
+				Code:    http.StatusAccepted,
+				Event:   faceanalyze_events.EventPartialResult,
+				Message: "Partial result",
+				Data:    data,
+			})
+
+		case upstreamResult:
+			// a bare result closes out whatever the partials added up to
+			if len(msg.Data) == 0 || string(msg.Data) == "null" {
+				return normalise(partial, size)
+			}
+			raw, err := decodeScanData(msg.Data)
+			if err != nil {
+				return nil, &invalidResponseError{err}
+			}
+			return normalise(raw, size)
+
+		case upstreamError:
+			message := msg.Error
+			if message == "" {
+				message = msg.Message
+			}
+			return nil, &reportedError{message: message}
+		}
+	}
+}
+
+// decodeScanData accepts scan data either bare or wrapped in {"data": ...} as the
+// non-streaming response is
+func decodeScanData(raw json.RawMessage) (*appschema.UpstreamScanData, error) {
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &wrapper); err != nil {
+		return nil, err
+	}
+	if inner, ok := wrapper["data"]; ok {
+		raw = inner
+	}
+
+	var data appschema.UpstreamScanData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
@@ -0,0 +1,154 @@
+package report
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"strings"
+)
+
+// A4 in points
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+)
+
+// pdf is just enough of PDF 1.4 for reports: pages of text in the standard
+// Helvetica faces, rules, shaded boxes and JPEG images. Standard fonts need no
+// embedding, so nothing outside the standard library is involved.
+type pdf struct {
+	pages  []*pdfPage
+	images []pdfImage
+}
+
+type pdfPage struct {
+	content bytes.Buffer
+	images  []int
+}
+
+type pdfImage struct {
+	width, height int
+	data          []byte
+}
+
+func (d *pdf) addPage() *pdfPage {
+	page := &pdfPage{}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// text draws s with its baseline at y points from the top of the page
+func (p *pdfPage) text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&p.content, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, pageHeight-y, escape(s))
+}
+
+func (p *pdfPage) rule(x1, y1, x2, y2, gray float64) {
+	fmt.Fprintf(&p.content, "%.2f G 0.5 w %.2f %.2f m %.2f %.2f l S\n", gray, x1, pageHeight-y1, x2, pageHeight-y2)
+}
+
+// box fills a rectangle whose top left corner is x, y
+func (p *pdfPage) box(x, y, w, h, gray float64) {
+	fmt.Fprintf(&p.content, "%.2f g %.2f %.2f %.2f %.2f re f 0 g\n", gray, x, pageHeight-y-h, w, h)
+}
+
+// image places img in the w by h box whose top left corner is x, y
+func (d *pdf) image(p *pdfPage, img image.Image, x, y, w, h float64) error {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return err
+	}
+	bounds := img.Bounds()
+	d.images = append(d.images, pdfImage{width: bounds.Dx(), height: bounds.Dy(), data: buf.Bytes()})
+	p.images = append(p.images, len(d.images)-1)
+	fmt.Fprintf(&p.content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, pageHeight-y-h, len(d.images)-1)
+	return nil
+}
+
+// writeTo lays the objects out as 1 catalog, 2 page tree, 3-4 fonts, then the
+// images, then each page followed by its content stream
+func (d *pdf) writeTo(w io.Writer) error {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			out.WriteString("stream\n")
+			out.Write(stream)
+			out.WriteString("\nendstream\n")
+		}
+		out.WriteString("endobj\n")
+	}
+
+	const firstImage = 5
+	firstPage := firstImage + len(d.images)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)), nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>", nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>", nil)
+	for _, img := range d.images {
+		object(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>",
+			img.width, img.height, len(img.data)), img.data)
+	}
+	for i, page := range d.pages {
+		var xobjects strings.Builder
+		for _, index := range page.images {
+			fmt.Fprintf(&xobjects, " /Im%d %d 0 R", index, firstImage+index)
+		}
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> /XObject <<%s >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, xobjects.String(), firstPage+2*i+1), nil)
+
+		var content bytes.Buffer
+		zw := zlib.NewWriter(&content)
+		if _, err := zw.Write(page.content.Bytes()); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		object(fmt.Sprintf("<< /Filter /FlateDecode /Length %d >>", content.Len()), content.Bytes())
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// escape encodes s as a WinAnsi string literal. Characters outside Latin-1
+// have no glyph in the standard fonts and become '?'.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,283 @@
+// Package report exports scan results as PDF and CSV documents.
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	margin = 40.0
+	// images are embedded at no more than this many pixels a side
+	maxImagePixels = 1024
+	maxImageHeight = 360.0
+)
+
+// Report is what a PDF covers: one or more scans, with the trends across them
+// when it covers a range
+type Report struct {
+	Title     string
+	Generated time.Time
+	// From and To describe a range report's dates; zero is open ended
+	From, To time.Time
+	Trends   *appschema.ScanTrends
+	Scans    []Scan
+}
+
+// Scan is one scan of a report, with its annotated image when there is one
+type Scan struct {
+	Record *appschema.ScanRecord
+	Image  image.Image
+}
+
+// PDF writes r as a PDF: the trend summary first when there is one, then a
+// page per scan with its image and metrics
+func PDF(w io.Writer, r *Report) error {
+	l := &layout{doc: &pdf{}}
+	l.newPage()
+	l.text(r.Title, 18, true, 24)
+	l.text("Generated "+r.Generated.UTC().Format(time.RFC1123), 9, false, 18)
+
+	if r.Trends != nil {
+		l.text(fmt.Sprintf("%d scans, %s", r.Trends.Scans, dateRange(r.From, r.To)), 11, false, 24)
+		l.trends(r.Trends)
+	}
+	for i, scan := range r.Scans {
+		if r.Trends != nil || i > 0 {
+			l.newPage()
+		}
+		if err := l.scan(scan); err != nil {
+			return err
+		}
+	}
+	if len(r.Scans) == 0 {
+		l.text("No scans to report.", 11, false, 16)
+	}
+	return l.doc.writeTo(w)
+}
+
+// CSV writes one row per metric of each record
+func CSV(w io.Writer, records []appschema.ScanRecord) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"scan_id", "job_id", "image_index", "analysis", "filename", "created_at", "kind", "metric", "percentage", "is_present", "regions"})
+	for _, record := range records {
+		if record.Result == nil {
+			continue
+		}
+		scan := []string{record.ID, record.JobID, strconv.Itoa(record.ImageIndex), record.Analysis, record.Filename, record.CreatedAt.UTC().Format(time.RFC3339)}
+		for _, name := range slices.Sorted(maps.Keys(record.Result.Quantitative)) {
+			metric := record.Result.Quantitative[name]
+			cw.Write(append(scan, "quantitative", name, strconv.FormatFloat(metric.Percentage, 'f', -1, 64), "", strconv.Itoa(len(metric.Regions))))
+		}
+		for _, name := range slices.Sorted(maps.Keys(record.Result.Qualitative)) {
+			metric := record.Result.Qualitative[name]
+			cw.Write(append(scan, "qualitative", name, "", strconv.FormatBool(metric.IsPresent), strconv.Itoa(len(metric.Regions))))
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// layout flows content down the page, starting another when it runs out
+type layout struct {
+	doc  *pdf
+	page *pdfPage
+	y    float64
+}
+
+type column struct {
+	title string
+	width float64
+}
+
+func (l *layout) newPage() {
+	l.page = l.doc.addPage()
+	l.y = margin
+}
+
+// need starts a new page unless height more points fit on this one
+func (l *layout) need(height float64) {
+	if l.y+height > pageHeight-margin {
+		l.newPage()
+	}
+}
+
+// text writes one line and moves down by advance
+func (l *layout) text(s string, size float64, bold bool, advance float64) {
+	l.need(advance)
+	l.page.text(margin, l.y+size, size, bold, s)
+	l.y += advance
+}
+
+func (l *layout) table(columns []column, rows [][]string) {
+	const (
+		row  = 16.0
+		size = 9.0
+	)
+	width := 0.0
+	for _, c := range columns {
+		width += c.width
+	}
+	header := func() {
+		l.page.box(margin, l.y, width, row, 0.9)
+		x := margin
+		for _, c := range columns {
+			l.page.text(x+4, l.y+row-5, size, true, fit(c.title, c.width, size))
+			x += c.width
+		}
+		l.y += row
+	}
+
+	l.need(row * 2)
+	header()
+	for _, cells := range rows {
+		if l.y+row > pageHeight-margin {
+			l.newPage()
+			header()
+		}
+		x := margin
+		for i, c := range columns {
+			l.page.text(x+4, l.y+row-5, size, false, fit(cells[i], c.width, size))
+			x += c.width
+		}
+		l.page.rule(margin, l.y+row, margin+width, l.y+row, 0.8)
+		l.y += row
+	}
+	l.y += 12
+}
+
+func (l *layout) trends(trends *appschema.ScanTrends) {
+	if len(trends.Quantitative) > 0 {
+		l.text("Quantitative trends", 12, true, 20)
+		var rows [][]string
+		for _, name := range slices.Sorted(maps.Keys(trends.Quantitative)) {
+			t := trends.Quantitative[name]
+			rows = append(rows, []string{name, strconv.Itoa(t.Samples), percent(t.First), percent(t.Last),
+				fmt.Sprintf("%+.1f", t.Change), percent(t.Min), percent(t.Max), percent(t.Mean)})
+		}
+		l.table([]column{{"Metric", 115}, {"Samples", 55}, {"First", 55}, {"Last", 55}, {"Change", 55}, {"Min", 60}, {"Max", 60}, {"Mean", 60}}, rows)
+	}
+	if len(trends.Qualitative) > 0 {
+		l.text("Qualitative trends", 12, true, 20)
+		var rows [][]string
+		for _, name := range slices.Sorted(maps.Keys(trends.Qualitative)) {
+			t := trends.Qualitative[name]
+			rows = append(rows, []string{name, fmt.Sprintf("%d of %d", t.PresentIn, t.Samples), date(t.FirstSeen), date(t.LastSeen), yesNo(t.PresentLatest)})
+		}
+		l.table([]column{{"Metric", 135}, {"Present in", 80}, {"First seen", 110}, {"Last seen", 110}, {"In latest", 80}}, rows)
+	}
+}
+
+func (l *layout) scan(scan Scan) error {
+	record := scan.Record
+	l.text("Scan of "+record.CreatedAt.UTC().Format("2 Jan 2006 15:04 MST"), 14, true, 22)
+	if record.ID != "" {
+		l.text("Scan "+record.ID, 9, false, 13)
+	}
+	l.text(fmt.Sprintf("Job %s, image %d", record.JobID, record.ImageIndex), 9, false, 13)
+	l.text("Analysis "+record.Analysis, 9, false, 13)
+	if record.Filename != "" {
+		l.text("File "+record.Filename, 9, false, 13)
+	}
+	l.y += 8
+
+	if scan.Image != nil {
+		if err := l.image(scan.Image); err != nil {
+			return err
+		}
+	} else {
+		l.text("Image not available", 9, false, 20)
+	}
+
+	result := record.Result
+	if len(result.Quantitative) > 0 {
+		var rows [][]string
+		for _, name := range slices.Sorted(maps.Keys(result.Quantitative)) {
+			metric := result.Quantitative[name]
+			rows = append(rows, []string{name, percent(metric.Percentage), strconv.Itoa(len(metric.Regions))})
+		}
+		l.table([]column{{"Metric", 255}, {"Percentage", 130}, {"Regions", 130}}, rows)
+	}
+	if len(result.Qualitative) > 0 {
+		var rows [][]string
+		for _, name := range slices.Sorted(maps.Keys(result.Qualitative)) {
+			metric := result.Qualitative[name]
+			rows = append(rows, []string{name, yesNo(metric.IsPresent), strconv.Itoa(len(metric.Regions))})
+		}
+		l.table([]column{{"Metric", 255}, {"Present", 130}, {"Regions", 130}}, rows)
+	}
+	return nil
+}
+
+// image fits img to the page width and maxImageHeight, downscaling the pixels
+// it embeds to maxImagePixels
+func (l *layout) image(img image.Image) error {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil
+	}
+	if longest := max(bounds.Dx(), bounds.Dy()); longest > maxImagePixels {
+		scaled := image.NewRGBA(image.Rect(0, 0, bounds.Dx()*maxImagePixels/longest, bounds.Dy()*maxImagePixels/longest))
+		xdraw.ApproxBiLinear.Scale(scaled, scaled.Bounds(), img, bounds, xdraw.Src, nil)
+		img, bounds = scaled, scaled.Bounds()
+	}
+
+	scale := min((pageWidth-2*margin)/float64(bounds.Dx()), maxImageHeight/float64(bounds.Dy()))
+	w, h := float64(bounds.Dx())*scale, float64(bounds.Dy())*scale
+	l.need(h + 12)
+	if err := l.doc.image(l.page, img, margin, l.y, w, h); err != nil {
+		return err
+	}
+	l.y += h + 12
+	return nil
+}
+
+// fit cuts s to roughly what fits in width at size, Helvetica averaging about
+// half an em a character
+func fit(s string, width, size float64) string {
+	limit := int((width - 8) / (size * 0.5))
+	runes := []rune(s)
+	if len(runes) <= limit || limit < 2 {
+		return s
+	}
+	return string(runes[:limit-1]) + "..."
+}
+
+func percent(v float64) string {
+	return fmt.Sprintf("%.1f%%", v)
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
+
+func date(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.DateOnly)
+}
+
+func dateRange(from, to time.Time) string {
+	switch {
+	case from.IsZero() && to.IsZero():
+		return "all time"
+	case from.IsZero():
+		return "up to " + to.UTC().Format(time.DateOnly)
+	case to.IsZero():
+		return "from " + from.UTC().Format(time.DateOnly)
+	}
+	return from.UTC().Format(time.DateOnly) + " to " + to.UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,230 @@
+package report
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/csv"
+	"fmt"
+	"image"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+var scanAt = time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+
+func testRecord(id string) *appschema.ScanRecord {
+	return &appschema.ScanRecord{
+		ID: id, JobID: "j1", ImageIndex: 1, Analysis: "face", Filename: "face.jpg", CreatedAt: scanAt,
+		Result: &appschema.FaceScanData{
+			Quantitative: map[string]appschema.QuantitativeMetric{
+				"wrinkles": {Percentage: 12.5, Regions: []appschema.Region{{Type: appschema.RegionPoint, Points: []appschema.Point{{X: 1, Y: 1}}}}},
+				"acne":     {Percentage: 3},
+			},
+			Qualitative: map[string]appschema.QualitativeMetric{"redness": {IsPresent: true}},
+		},
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	records := []appschema.ScanRecord{*testRecord("s1"), {ID: "pending"}}
+	if err := CSV(&buf, records); err != nil {
+		t.Fatalf("CSV: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not CSV: %v", err)
+	}
+
+	want := [][]string{
+		{"scan_id", "job_id", "image_index", "analysis", "filename", "created_at", "kind", "metric", "percentage", "is_present", "regions"},
+		{"s1", "j1", "1", "face", "face.jpg", "2024-03-01T10:30:00Z", "quantitative", "acne", "3", "", "0"},
+		{"s1", "j1", "1", "face", "face.jpg", "2024-03-01T10:30:00Z", "quantitative", "wrinkles", "12.5", "", "1"},
+		{"s1", "j1", "1", "face", "face.jpg", "2024-03-01T10:30:00Z", "qualitative", "redness", "", "true", "0"},
+	}
+	if fmt.Sprint(rows) != fmt.Sprint(want) {
+		t.Fatalf("rows =\n%v\nwant\n%v", rows, want)
+	}
+}
+
+// parsePDF checks out's cross-reference table and returns its page count and
+// decompressed page content
+func parsePDF(t *testing.T, out []byte) (pages int, content string) {
+	t.Helper()
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Fatalf("xref entry %d points at %q", i+1, out[offset:offset+10])
+		}
+	}
+
+	count := regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`).FindSubmatch(out)
+	pages, _ = strconv.Atoi(string(count[1]))
+
+	var text strings.Builder
+	for _, stream := range regexp.MustCompile(`(?s)/FlateDecode /Length \d+ >>\nstream\n(.*?)\nendstream`).FindAllSubmatch(out, -1) {
+		zr, err := zlib.NewReader(bytes.NewReader(stream[1]))
+		if err != nil {
+			t.Fatalf("content stream: %v", err)
+		}
+		data, _ := io.ReadAll(zr)
+		text.Write(data)
+	}
+	return pages, text.String()
+}
+
+func TestPDF(t *testing.T) {
+	tests := []struct {
+		name       string
+		report     *Report
+		wantPages  int
+		wantImages int
+		wantText   []string
+		wantNoText []string
+	}{
+		{
+			name:      "single scan",
+			report:    &Report{Title: "Scan report", Scans: []Scan{{Record: testRecord("s1")}}},
+			wantPages: 1,
+			wantText:  []string{"(Scan report)", "(Scan s1)", "(wrinkles)", "(12.5%)", "(yes)", "(Image not available)"},
+		},
+		{
+			name:       "scan with an image",
+			report:     &Report{Title: "Scan report", Scans: []Scan{{Record: testRecord("s1"), Image: image.NewRGBA(image.Rect(0, 0, 2000, 1000))}}},
+			wantPages:  1,
+			wantImages: 1,
+			wantText:   []string{"/Im0 Do"},
+			wantNoText: []string{"(Image not available)"},
+		},
+		{
+			name: "range with trends",
+			report: &Report{
+				Title:  "History report",
+				From:   scanAt,
+				Trends: &appschema.ScanTrends{Scans: 2, Quantitative: map[string]appschema.QuantitativeTrend{"acne": {Samples: 2, First: 5, Last: 3, Change: -2}}},
+				Scans:  []Scan{{Record: testRecord("s1")}, {Record: testRecord("s2")}},
+			},
+			wantPages: 3,
+			wantText:  []string{"(2 scans, from 2024-03-01)", "(Quantitative trends)", "(-2.0)", "(Scan s2)"},
+		},
+		{
+			name:      "no scans",
+			report:    &Report{Title: "History report", Trends: &appschema.ScanTrends{}},
+			wantPages: 1,
+			wantText:  []string{"(No scans to report.)", "(0 scans, all time)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := PDF(&buf, tt.report); err != nil {
+				t.Fatalf("PDF: %v", err)
+			}
+			pages, content := parsePDF(t, buf.Bytes())
+			if pages != tt.wantPages {
+				t.Errorf("%d pages, want %d", pages, tt.wantPages)
+			}
+			if images := bytes.Count(buf.Bytes(), []byte("/Subtype /Image")); images != tt.wantImages {
+				t.Errorf("%d images, want %d", images, tt.wantImages)
+			}
+			for _, want := range tt.wantText {
+				if !strings.Contains(content, want) {
+					t.Errorf("content has no %s", want)
+				}
+			}
+			for _, unwanted := range tt.wantNoText {
+				if strings.Contains(content, unwanted) {
+					t.Errorf("content has %s", unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestPDFPagination(t *testing.T) {
+	// enough metrics to overflow a page
+	record := testRecord("s1")
+	for i := range 80 {
+		record.Result.Quantitative[fmt.Sprintf("metric_%02d", i)] = appschema.QuantitativeMetric{Percentage: float64(i)}
+	}
+	var buf bytes.Buffer
+	if err := PDF(&buf, &Report{Title: "Scan report", Scans: []Scan{{Record: record}}}); err != nil {
+		t.Fatalf("PDF: %v", err)
+	}
+	pages, content := parsePDF(t, buf.Bytes())
+	if pages < 2 {
+		t.Fatalf("%d pages, want the table to continue on another", pages)
+	}
+	// the header repeats on the new page
+	if n := strings.Count(content, "(Percentage)"); n < 2 {
+		t.Fatalf("table header drawn %d times, want it on every page", n)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := map[string]string{
+		"plain":       "plain",
+		"a (b) c\\d":  `a \(b\) c\\d`,
+		"café":        `caf\351`,
+		"emoji 🙂":     "emoji ?",
+		"tab\tbefore": "tab?before",
+	}
+	for in, want := range tests {
+		if got := escape(in); got != want {
+			t.Errorf("escape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		s     string
+		width float64
+		want  string
+	}{
+		{s: "short", width: 100, want: "short"},
+		{s: "a rather long metric name", width: 58, want: "a rather l..."},
+		{s: "tiny", width: 10, want: "tiny"},
+	}
+	for _, tt := range tests {
+		if got := fit(tt.s, tt.width, 9); got != tt.want {
+			t.Errorf("fit(%q, %v) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestDateRange(t *testing.T) {
+	from, to := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		from, to time.Time
+		want     string
+	}{
+		{want: "all time"},
+		{from: from, want: "from 2024-01-01"},
+		{to: to, want: "up to 2024-02-01"},
+		{from: from, to: to, want: "2024-01-01 to 2024-02-01"},
+	}
+	for _, tt := range tests {
+		if got := dateRange(tt.from, tt.to); got != tt.want {
+			t.Errorf("dateRange(%v, %v) = %q, want %q", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 
 	controller "github.com/muthu-kumar-u/go-sse/controller/user"
+	appschema "github.com/muthu-kumar-u/go-sse/models"
 )
 
 type UserService interface {
 	IsUserAuthenticated(ctx context.Context, token string) (bool, error)
+	GetAuthenticatedUser(ctx context.Context, token string) (*appschema.GetUserData, error)
 }
 
 type userControllerImpl struct {
@@ -21,3 +23,7 @@ func NewUserService(c controller.UserController) UserService {
 func (s *userControllerImpl) IsUserAuthenticated(ctx context.Context, token string) (bool, error) {
 	return s.userController.IsUserAuthenticated(ctx, token)
 }
+
+func (s *userControllerImpl) GetAuthenticatedUser(ctx context.Context, token string) (*appschema.GetUserData, error) {
+	return s.userController.GetAuthenticatedUser(ctx, token)
+}
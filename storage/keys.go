@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Image object kinds
+const (
+	KindOriginal     = "original"
+	KindPreprocessed = "preprocessed"
+	KindAnnotated    = "annotated"
+)
+
+// ImageKey places an image under prefix/<owner>/<job>/<index>-<kind><ext>
+func ImageKey(prefix, ownerID, jobID string, index int, kind, ext string) string {
+	return path.Join(prefix, ownerID, jobID, fmt.Sprintf("%d-%s%s", index, kind, ext))
+}
+
+// ImageKeyParts is what ParseImageKey reads back from a key
+type ImageKeyParts struct {
+	OwnerID string
+	JobID   string
+	Index   int
+	Kind    string
+}
+
+// ParseImageKey splits a key made by ImageKey under prefix
+func ParseImageKey(prefix, key string) (ImageKeyParts, bool) {
+	rest, ok := strings.CutPrefix(key, strings.TrimSuffix(prefix, "/")+"/")
+	if !ok {
+		return ImageKeyParts{}, false
+	}
+	segments := strings.Split(rest, "/")
+	if len(segments) != 3 {
+		return ImageKeyParts{}, false
+	}
+
+	name := strings.TrimSuffix(segments[2], path.Ext(segments[2]))
+	rawIndex, kind, ok := strings.Cut(name, "-")
+	if !ok {
+		return ImageKeyParts{}, false
+	}
+	index, err := strconv.Atoi(rawIndex)
+	if err != nil {
+		return ImageKeyParts{}, false
+	}
+	return ImageKeyParts{OwnerID: segments[0], JobID: segments[1], Index: index, Kind: kind}, true
+}
+
+// ImageExt is the extension stored images of contentType get
+func ImageExt(contentType string) string {
+	if contentType == "image/png" {
+		return ".png"
+	}
+	return ".jpg"
+}
@@ -0,0 +1,49 @@
+package storage
+
+import "testing"
+
+func TestImageKeyRoundTrip(t *testing.T) {
+	tests := []struct {
+		prefix string
+		parts  ImageKeyParts
+		ext    string
+	}{
+		{prefix: "scans", parts: ImageKeyParts{OwnerID: "u1", JobID: "j1", Index: 0, Kind: KindOriginal}, ext: ".jpg"},
+		{prefix: "scans/", parts: ImageKeyParts{OwnerID: "u1", JobID: "j1", Index: 3, Kind: KindPreprocessed}, ext: ".png"},
+		{prefix: "a/b", parts: ImageKeyParts{OwnerID: "u2", JobID: "j2", Index: 12, Kind: KindAnnotated}, ext: ".png"},
+	}
+	for _, tt := range tests {
+		key := ImageKey(tt.prefix, tt.parts.OwnerID, tt.parts.JobID, tt.parts.Index, tt.parts.Kind, tt.ext)
+		got, ok := ParseImageKey(tt.prefix, key)
+		if !ok || got != tt.parts {
+			t.Errorf("ParseImageKey(%q, %q) = %+v, %v; want %+v", tt.prefix, key, got, ok, tt.parts)
+		}
+	}
+}
+
+func TestParseImageKeyRejects(t *testing.T) {
+	tests := []struct {
+		name, key string
+	}{
+		{name: "other prefix", key: "uploads/u1/j1/0-original.jpg"},
+		{name: "prefix only as a substring", key: "scansx/u1/j1/0-original.jpg"},
+		{name: "too few segments", key: "scans/j1/0-original.jpg"},
+		{name: "too many segments", key: "scans/u1/j1/x/0-original.jpg"},
+		{name: "no kind", key: "scans/u1/j1/0.jpg"},
+		{name: "index not a number", key: "scans/u1/j1/first-original.jpg"},
+	}
+	for _, tt := range tests {
+		if got, ok := ParseImageKey("scans", tt.key); ok {
+			t.Errorf("%s: ParseImageKey(%q) = %+v, want no match", tt.name, tt.key, got)
+		}
+	}
+}
+
+func TestImageExt(t *testing.T) {
+	tests := map[string]string{"image/png": ".png", "image/jpeg": ".jpg", "": ".jpg"}
+	for contentType, want := range tests {
+		if got := ImageExt(contentType); got != want {
+			t.Errorf("ImageExt(%q) = %q, want %q", contentType, got, want)
+		}
+	}
+}
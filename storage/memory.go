@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrPresignUnsupported is returned by stores that cannot hand out links;
+// callers serve the object's bytes themselves instead
+var ErrPresignUnsupported = errors.New("store cannot presign URLs")
+
+// Memory keeps objects in the process for ttl. It stands in for a bucket
+// when none is configured, so objects do not survive a restart.
+type Memory struct {
+	mu      sync.Mutex
+	objects map[string]memoryObject
+	ttl     time.Duration
+}
+
+type memoryObject struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+func NewMemory(ttl time.Duration) *Memory {
+	return &Memory{objects: make(map[string]memoryObject), ttl: ttl}
+}
+
+func (m *Memory) Put(ctx context.Context, key, contentType string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// expired objects go on the next write rather than on a timer
+	now := time.Now()
+	for k, object := range m.objects {
+		if now.After(object.expiresAt) {
+			delete(m.objects, k)
+		}
+	}
+	m.objects[key] = memoryObject{data: data, expiresAt: now.Add(m.ttl)}
+	return nil
+}
+
+func (m *Memory) Get(ctx context.Context, key string, maxBytes int64) ([]byte, error) {
+	object, err := m.get(key)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(object.data)) > maxBytes {
+		return nil, fmt.Errorf("object %s is larger than %d bytes", key, maxBytes)
+	}
+	return object.data, nil
+}
+
+func (m *Memory) Size(ctx context.Context, key string) (int64, error) {
+	object, err := m.get(key)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(object.data)), nil
+}
+
+func (m *Memory) PresignGet(key string, ttl time.Duration) (string, error) {
+	return "", ErrPresignUnsupported
+}
+
+func (m *Memory) PresignPut(key, contentType string, ttl time.Duration) (string, http.Header, error) {
+	return "", nil, ErrPresignUnsupported
+}
+
+func (m *Memory) get(key string) (memoryObject, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	object, ok := m.objects[key]
+	if !ok || time.Now().After(object.expiresAt) {
+		return memoryObject{}, ErrNotFound
+	}
+	return object, nil
+}
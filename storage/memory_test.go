@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(time.Hour)
+	m.Put(ctx, "k", "image/jpeg", []byte("12345"))
+
+	tests := []struct {
+		name     string
+		key      string
+		maxBytes int64
+		want     string
+		wantErr  bool
+		notFound bool
+	}{
+		{name: "fits", key: "k", maxBytes: 5, want: "12345"},
+		{name: "too large", key: "k", maxBytes: 4, wantErr: true},
+		{name: "missing", key: "none", maxBytes: 5, wantErr: true, notFound: true},
+	}
+	for _, tt := range tests {
+		got, err := m.Get(ctx, tt.key, tt.maxBytes)
+		if (err != nil) != tt.wantErr || errors.Is(err, ErrNotFound) != tt.notFound || string(got) != tt.want {
+			t.Errorf("%s: Get = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if size, err := m.Size(ctx, "k"); err != nil || size != 5 {
+		t.Errorf("Size = %d, %v; want 5", size, err)
+	}
+	if _, err := m.PresignGet("k", time.Minute); !errors.Is(err, ErrPresignUnsupported) {
+		t.Errorf("PresignGet = %v, want ErrPresignUnsupported", err)
+	}
+	if _, _, err := m.PresignPut("k", "image/jpeg", time.Minute); !errors.Is(err, ErrPresignUnsupported) {
+		t.Errorf("PresignPut = %v, want ErrPresignUnsupported", err)
+	}
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(-time.Second)
+	m.Put(ctx, "old", "image/jpeg", []byte("x"))
+	if _, err := m.Size(ctx, "old"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Size of an expired object = %v, want ErrNotFound", err)
+	}
+	// the next write clears it out
+	m.Put(ctx, "new", "image/jpeg", []byte("y"))
+	if len(m.objects) != 1 {
+		t.Fatalf("%d objects kept, want 1", len(m.objects))
+	}
+}
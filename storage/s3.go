@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// S3Options configures where and how objects are written
+type S3Options struct {
+	Bucket string
+	// Encryption is the server-side encryption applied to every object,
+	// AES256 or aws:kms
+	Encryption string
+	// KMSKeyID selects the key for aws:kms; empty uses the bucket's default
+	KMSKeyID string
+	// Endpoint and PathStyle point the client at an S3-compatible stand-in such
+	// as MinIO or LocalStack
+	Endpoint  string
+	PathStyle bool
+}
+
+// S3 stores objects in a single bucket
+type S3 struct {
+	client *s3.S3
+	opts   S3Options
+}
+
+func NewS3(sess *session.Session, opts S3Options) *S3 {
+	config := aws.NewConfig()
+	if opts.Endpoint != "" {
+		config = config.WithEndpoint(opts.Endpoint)
+	}
+	if opts.PathStyle {
+		config = config.WithS3ForcePathStyle(true)
+	}
+	return &S3{client: s3.New(sess, config), opts: opts}
+}
+
+func (s *S3) Put(ctx context.Context, key, contentType string, data []byte) error {
+	input := s.putInput(key, contentType)
+	input.Body = bytes.NewReader(data)
+	_, err := s.client.PutObjectWithContext(ctx, input)
+	return err
+}
+
+func (s *S3) Get(ctx context.Context, key string, maxBytes int64) ([]byte, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.opts.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(out.Body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("object %s is larger than %d bytes", key, maxBytes)
+	}
+	return data, nil
+}
+
+func (s *S3) Size(ctx context.Context, key string) (int64, error) {
+	out, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.opts.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, notFound(err)
+	}
+	return aws.Int64Value(out.ContentLength), nil
+}
+
+func (s *S3) PresignGet(key string, ttl time.Duration) (string, error) {
+	req, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.opts.Bucket),
+		Key:    aws.String(key),
+	})
+	return req.Presign(ttl)
+}
+
+// PresignPut signs the content type and encryption headers, so an upload that
+// leaves them out or changes them is rejected
+func (s *S3) PresignPut(key, contentType string, ttl time.Duration) (string, http.Header, error) {
+	req, _ := s.client.PutObjectRequest(s.putInput(key, contentType))
+	return req.PresignRequest(ttl)
+}
+
+func (s *S3) putInput(key, contentType string) *s3.PutObjectInput {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.opts.Bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	}
+	if s.opts.Encryption != "" {
+		input.ServerSideEncryption = aws.String(s.opts.Encryption)
+	}
+	if s.opts.Encryption == s3.ServerSideEncryptionAwsKms && s.opts.KMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(s.opts.KMSKeyID)
+	}
+	return input
+}
+
+// notFound maps S3's missing key errors to ErrNotFound
+func notFound(err error) error {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && (aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NotFound") {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// fakeS3 answers the path-style object calls S3 makes, keeping the headers
+// each object was written with
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	headers map[string]http.Header
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+		f.headers[r.URL.Path] = r.Header.Clone()
+	case http.MethodGet, http.MethodHead:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>missing</Message></Error>`)
+			}
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	}
+}
+
+func newFakeS3(t *testing.T, opts S3Options) (*S3, *fakeS3) {
+	fake := &fakeS3{objects: make(map[string][]byte), headers: make(map[string]http.Header)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	sess := session.Must(session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", ""))))
+	opts.Endpoint, opts.PathStyle = srv.URL, true
+	return NewS3(sess, opts), fake
+}
+
+func TestS3PutGet(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           S3Options
+		wantEncryption string
+		wantKMSKey     string
+	}{
+		{name: "no encryption", opts: S3Options{Bucket: "images"}},
+		{name: "aes256", opts: S3Options{Bucket: "images", Encryption: "AES256", KMSKeyID: "ignored"}, wantEncryption: "AES256"},
+		{name: "kms", opts: S3Options{Bucket: "images", Encryption: "aws:kms", KMSKeyID: "key-1"}, wantEncryption: "aws:kms", wantKMSKey: "key-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, fake := newFakeS3(t, tt.opts)
+			if err := store.Put(ctx, "scans/u1/face.jpg", "image/jpeg", []byte("jpeg")); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			header := fake.headers["/images/scans/u1/face.jpg"]
+			if header == nil {
+				t.Fatalf("nothing written under the bucket, have %v", fake.headers)
+			}
+			if header.Get("Content-Type") != "image/jpeg" ||
+				header.Get("X-Amz-Server-Side-Encryption") != tt.wantEncryption ||
+				header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id") != tt.wantKMSKey {
+				t.Fatalf("put headers = %v", header)
+			}
+
+			data, err := store.Get(ctx, "scans/u1/face.jpg", 4)
+			if err != nil || string(data) != "jpeg" {
+				t.Fatalf("Get = %q, %v", data, err)
+			}
+			if _, err := store.Get(ctx, "scans/u1/face.jpg", 3); err == nil {
+				t.Fatal("Get over maxBytes succeeded")
+			}
+			if size, err := store.Size(ctx, "scans/u1/face.jpg"); err != nil || size != 4 {
+				t.Fatalf("Size = %d, %v", size, err)
+			}
+		})
+	}
+}
+
+func TestS3NotFound(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newFakeS3(t, S3Options{Bucket: "images"})
+	if _, err := store.Get(ctx, "missing", 10); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Size(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Size = %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3Presign(t *testing.T) {
+	store, _ := newFakeS3(t, S3Options{Bucket: "images", Encryption: "AES256"})
+
+	get, err := store.PresignGet("scans/face.jpg", time.Minute)
+	if err != nil || !strings.Contains(get, "/images/scans/face.jpg?") || !strings.Contains(get, "X-Amz-Expires=60") {
+		t.Fatalf("PresignGet = %s, %v", get, err)
+	}
+
+	put, header, err := store.PresignPut("scans/face.jpg", "image/jpeg", time.Minute)
+	if err != nil || !strings.Contains(put, "/images/scans/face.jpg?") {
+		t.Fatalf("PresignPut = %s, %v", put, err)
+	}
+	// the uploader has to send the signed headers as they are; the signer
+	// hands them back with lower case names
+	signed := http.Header{}
+	for name, values := range header {
+		signed[http.CanonicalHeaderKey(name)] = values
+	}
+	if signed.Get("Content-Type") != "image/jpeg" || signed.Get("X-Amz-Server-Side-Encryption") != "AES256" {
+		t.Fatalf("PresignPut headers = %v", header)
+	}
+}
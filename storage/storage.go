@@ -0,0 +1,25 @@
+// Package storage keeps uploaded images in an object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Store puts image objects and hands out time-limited links to them
+type Store interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	// Get reads key, failing once it is larger than maxBytes
+	Get(ctx context.Context, key string, maxBytes int64) ([]byte, error)
+	// Size reports the stored size of key, or ErrNotFound
+	Size(ctx context.Context, key string) (int64, error)
+	// PresignGet returns a URL that fetches key without credentials until ttl passes
+	PresignGet(key string, ttl time.Duration) (string, error)
+	// PresignPut returns a URL that uploads key until ttl passes, with the
+	// headers the upload must send
+	PresignPut(key, contentType string, ttl time.Duration) (string, http.Header, error)
+}
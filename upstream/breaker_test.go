@@ -0,0 +1,122 @@
+package upstream
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	const cooldown = 30 * time.Millisecond
+
+	// each step calls the breaker once; "allow" checks the answer and "wait"
+	// lets the cooldown pass
+	type step struct {
+		op        string
+		wantAllow bool
+	}
+	tests := []struct {
+		name      string
+		steps     []step
+		wantState BreakerState
+		wantTrips int64
+	}{
+		{
+			name:      "closed below the threshold",
+			steps:     []step{{op: "failure"}, {op: "failure"}, {op: "allow", wantAllow: true}},
+			wantState: BreakerClosed,
+		},
+		{
+			name:      "success resets the count",
+			steps:     []step{{op: "failure"}, {op: "failure"}, {op: "success"}, {op: "failure"}, {op: "failure"}},
+			wantState: BreakerClosed,
+		},
+		{
+			name:      "opens at the threshold",
+			steps:     []step{{op: "failure"}, {op: "failure"}, {op: "failure"}, {op: "allow"}},
+			wantState: BreakerOpen,
+			wantTrips: 1,
+		},
+		{
+			name: "one trial after the cooldown",
+			steps: []step{
+				{op: "failure"}, {op: "failure"}, {op: "failure"},
+				{op: "wait"}, {op: "allow", wantAllow: true}, {op: "allow"},
+			},
+			wantState: BreakerHalfOpen,
+			wantTrips: 1,
+		},
+		{
+			name: "trial success closes",
+			steps: []step{
+				{op: "failure"}, {op: "failure"}, {op: "failure"},
+				{op: "wait"}, {op: "allow", wantAllow: true}, {op: "success"}, {op: "allow", wantAllow: true},
+			},
+			wantState: BreakerClosed,
+			wantTrips: 1,
+		},
+		{
+			name: "trial failure reopens",
+			steps: []step{
+				{op: "failure"}, {op: "failure"}, {op: "failure"},
+				{op: "wait"}, {op: "allow", wantAllow: true}, {op: "failure"}, {op: "allow"},
+			},
+			wantState: BreakerOpen,
+			wantTrips: 2,
+		},
+		{
+			name: "abandoned trial frees the slot",
+			steps: []step{
+				{op: "failure"}, {op: "failure"}, {op: "failure"},
+				{op: "wait"}, {op: "allow", wantAllow: true}, {op: "abandon"}, {op: "allow", wantAllow: true},
+			},
+			wantState: BreakerHalfOpen,
+			wantTrips: 1,
+		},
+		{
+			name:      "failures while open do not trip again",
+			steps:     []step{{op: "failure"}, {op: "failure"}, {op: "failure"}, {op: "failure"}, {op: "failure"}},
+			wantState: BreakerOpen,
+			wantTrips: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreaker(3, cooldown)
+			for i, s := range tt.steps {
+				switch s.op {
+				case "failure":
+					b.Failure()
+				case "success":
+					b.Success()
+				case "abandon":
+					b.Abandon()
+				case "wait":
+					time.Sleep(cooldown + 5*time.Millisecond)
+				case "allow":
+					err := b.Allow()
+					if allowed := err == nil; allowed != s.wantAllow {
+						t.Fatalf("step %d: Allow = %v, want allowed %v", i, err, s.wantAllow)
+					}
+					if err != nil && !errors.Is(err, ErrCircuitOpen) {
+						t.Fatalf("step %d: Allow = %v, want %v", i, err, ErrCircuitOpen)
+					}
+				}
+			}
+			stats := b.Stats()
+			if stats.State != tt.wantState || stats.Trips != tt.wantTrips {
+				t.Fatalf("state %s with %d trips, want %s with %d", stats.State, stats.Trips, tt.wantState, tt.wantTrips)
+			}
+			if (stats.OpenedAt != nil) != (tt.wantState != BreakerClosed) {
+				t.Fatalf("opened at = %v in state %s", stats.OpenedAt, stats.State)
+			}
+		})
+	}
+}
+
+func TestNewBreakerDefaults(t *testing.T) {
+	b := NewBreaker(0, 0)
+	if b.Threshold != 5 || b.Cooldown != 30*time.Second || b.State() != BreakerClosed {
+		t.Fatalf("breaker = %+v", b)
+	}
+}
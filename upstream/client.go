@@ -0,0 +1,250 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+type Config struct {
+	MaxAttempts    int
+	BaseBackoff    time.Duration
+	MaxBackoff     time.Duration
+	AttemptTimeout time.Duration
+	// TotalTimeout bounds every attempt and backoff together
+	TotalTimeout time.Duration
+}
+
+// Retry describes an attempt that failed and is about to be repeated
+type Retry struct {
+	Attempt     int
+	MaxAttempts int
+	Delay       time.Duration
+	Reason      string
+}
+
+// RequestFunc builds a fresh request for each attempt, so bodies can be replayed
+type RequestFunc func(ctx context.Context) (*http.Request, error)
+
+// Client calls an upstream with per-attempt and total deadlines, retrying
+// transient failures with jittered exponential backoff behind a circuit breaker
+type Client struct {
+	http    *http.Client
+	config  Config
+	breaker *Breaker
+
+	requests atomic.Int64
+	attempts atomic.Int64
+	retries  atomic.Int64
+	failures atomic.Int64
+	rejected atomic.Int64
+}
+
+type Stats struct {
+	Requests int64        `json:"requests"`
+	Attempts int64        `json:"attempts"`
+	Retries  int64        `json:"retries"`
+	Failures int64        `json:"failures"`
+	Rejected int64        `json:"rejected"`
+	Breaker  BreakerStats `json:"breaker"`
+}
+
+func NewClient(client *http.Client, config Config, breaker *Breaker) *Client {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = 500 * time.Millisecond
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 5 * time.Second
+	}
+	if config.AttemptTimeout <= 0 {
+		config.AttemptTimeout = 30 * time.Second
+	}
+	if config.TotalTimeout <= 0 {
+		config.TotalTimeout = 90 * time.Second
+	}
+	if breaker == nil {
+		breaker = NewBreaker(0, 0)
+	}
+	return &Client{http: client, config: config, breaker: breaker}
+}
+
+// Do sends the request built by newRequest until it gets a response worth
+// keeping. Network errors, attempt timeouts, 429 and 502-504 are retried;
+// every 5xx and timeout counts against the breaker, retried or not. onRetry,
+// when set, hears about each retry before the backoff. The caller
+// closes the returned body, which also releases the deadlines.
+func (c *Client) Do(ctx context.Context, newRequest RequestFunc, onRetry func(Retry)) (*http.Response, error) {
+	c.requests.Add(1)
+	ctx, cancel := context.WithTimeout(ctx, c.config.TotalTimeout)
+
+	for attempt := 1; ; attempt++ {
+		if err := c.breaker.Allow(); err != nil {
+			c.rejected.Add(1)
+			cancel()
+			return nil, err
+		}
+
+		resp, retryAfter, err := c.attempt(ctx, newRequest)
+		switch {
+		case err == nil:
+			c.breaker.Success()
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		case countsAgainstUpstream(err):
+			c.breaker.Failure()
+		default:
+			c.breaker.Abandon()
+		}
+
+		// the caller's deadline, or ours, is spent: another attempt can't finish
+		if ctx.Err() != nil || attempt >= c.config.MaxAttempts || !retryable(err) {
+			c.failures.Add(1)
+			if resp != nil {
+				// hand back the final upstream answer for the caller to report
+				resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+				return resp, nil
+			}
+			cancel()
+			return nil, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		delay := c.backoff(attempt)
+		if retryAfter > delay {
+			delay = min(retryAfter, c.config.MaxBackoff)
+		}
+		c.retries.Add(1)
+		if onRetry != nil {
+			onRetry(Retry{Attempt: attempt, MaxAttempts: c.config.MaxAttempts, Delay: delay, Reason: err.Error()})
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			c.failures.Add(1)
+			cancel()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// statusError marks a response the upstream answered with 429 or a 5xx
+type statusError struct {
+	status int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("upstream answered %d %s", e.status, http.StatusText(e.status))
+}
+
+// attempt makes one call under its own deadline. A 429 or 5xx comes back as
+// both the response and a statusError.
+func (c *Client) attempt(ctx context.Context, newRequest RequestFunc) (*http.Response, time.Duration, error) {
+	c.attempts.Add(1)
+	attemptCtx, cancel := context.WithTimeout(ctx, c.config.AttemptTimeout)
+
+	req, err := newRequest(attemptCtx)
+	if err != nil {
+		cancel()
+		return nil, 0, &permanentError{err}
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		cancel()
+		return nil, 0, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return resp, retryAfter(resp), &statusError{status: resp.StatusCode}
+	}
+	return resp, 0, nil
+}
+
+// permanentError wraps failures no retry can fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// retryable reports whether another attempt could get a different answer: the
+// upstream may recover from overload and gateway errors, not from a 500
+func retryable(err error) bool {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+	var status *statusError
+	if errors.As(err, &status) {
+		switch status.status {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// countsAgainstUpstream tells upstream trouble apart from our own mistakes and
+// callers that gave up
+func countsAgainstUpstream(err error) bool {
+	var permanent *permanentError
+	return !errors.As(err, &permanent) && !errors.Is(err, context.Canceled)
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.config.BaseBackoff << (attempt - 1)
+	if wait <= 0 || wait > c.config.MaxBackoff {
+		wait = c.config.MaxBackoff
+	}
+	// equal jitter: at least half the backoff, so retries still spread out
+	return wait/2 + rand.N(wait/2+1)
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (c *Client) Breaker() *Breaker {
+	return c.breaker
+}
+
+func (c *Client) Stats() Stats {
+	return Stats{
+		Requests: c.requests.Load(),
+		Attempts: c.attempts.Load(),
+		Retries:  c.retries.Load(),
+		Failures: c.failures.Load(),
+		Rejected: c.rejected.Load(),
+		Breaker:  c.breaker.Stats(),
+	}
+}
+
+// cancelBody releases a request's deadline once its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
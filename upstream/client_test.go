@@ -0,0 +1,152 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientDo(t *testing.T) {
+	tests := []struct {
+		name string
+		// statuses the upstream answers in turn, repeating the last; 0 hangs
+		// past the attempt timeout
+		statuses     []int
+		maxAttempts  int
+		wantStatus   int
+		wantErr      bool
+		wantAttempts int64
+		wantRetries  int64
+		wantFailures int
+		wantState    BreakerState
+	}{
+		{name: "ok", statuses: []int{200}, maxAttempts: 3, wantStatus: 200, wantAttempts: 1},
+		{name: "client error is not upstream trouble", statuses: []int{400}, maxAttempts: 3, wantStatus: 400, wantAttempts: 1},
+		{name: "recovers after a 503", statuses: []int{503, 200}, maxAttempts: 3, wantStatus: 200, wantAttempts: 2, wantRetries: 1},
+		{name: "retries 429 and gateway errors", statuses: []int{429, 504, 200}, maxAttempts: 3, wantStatus: 200, wantAttempts: 3, wantRetries: 2},
+		{name: "gives up at the limit", statuses: []int{503}, maxAttempts: 3, wantStatus: 503, wantAttempts: 3, wantRetries: 2, wantFailures: 3, wantState: BreakerOpen},
+		{name: "500 is not retried but counts", statuses: []int{500}, maxAttempts: 3, wantStatus: 500, wantAttempts: 1, wantFailures: 1},
+		{name: "501 is not retried but counts", statuses: []int{501}, maxAttempts: 3, wantStatus: 501, wantAttempts: 1, wantFailures: 1},
+		{name: "timeouts count and are retried", statuses: []int{0, 0, 200}, maxAttempts: 3, wantStatus: 200, wantAttempts: 3, wantRetries: 2},
+		{name: "timeouts up to the limit", statuses: []int{0}, maxAttempts: 3, wantErr: true, wantAttempts: 3, wantRetries: 2, wantFailures: 3, wantState: BreakerOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := int(calls.Add(1)) - 1
+				status := tt.statuses[min(i, len(tt.statuses)-1)]
+				if status == 0 {
+					<-r.Context().Done()
+					return
+				}
+				w.WriteHeader(status)
+			}))
+			defer srv.Close()
+
+			breaker := NewBreaker(3, time.Minute)
+			client := NewClient(srv.Client(), Config{
+				MaxAttempts:    tt.maxAttempts,
+				BaseBackoff:    time.Millisecond,
+				MaxBackoff:     2 * time.Millisecond,
+				AttemptTimeout: 50 * time.Millisecond,
+			}, breaker)
+
+			var retries []Retry
+			resp, err := client.Do(context.Background(), func(ctx context.Context) (*http.Request, error) {
+				return http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+			}, func(r Retry) { retries = append(retries, r) })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+				}
+			}
+
+			stats := client.Stats()
+			if stats.Attempts != tt.wantAttempts || stats.Retries != tt.wantRetries {
+				t.Errorf("attempts %d, retries %d, want %d and %d", stats.Attempts, stats.Retries, tt.wantAttempts, tt.wantRetries)
+			}
+			if int64(len(retries)) != tt.wantRetries {
+				t.Errorf("onRetry called %d times, want %d", len(retries), tt.wantRetries)
+			}
+			wantState := tt.wantState
+			if wantState == "" {
+				wantState = BreakerClosed
+			}
+			if stats.Breaker.State != wantState || stats.Breaker.ConsecutiveFailures != tt.wantFailures {
+				t.Errorf("breaker %s with %d failures, want %s with %d", stats.Breaker.State, stats.Breaker.ConsecutiveFailures, wantState, tt.wantFailures)
+			}
+		})
+	}
+}
+
+func TestClientDoOpenBreaker(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	breaker := NewBreaker(1, time.Minute)
+	breaker.Failure()
+	client := NewClient(srv.Client(), Config{}, breaker)
+	_, err := client.Do(context.Background(), func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	}, nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Do = %v, want %v", err, ErrCircuitOpen)
+	}
+	if calls.Load() != 0 || client.Stats().Rejected != 1 {
+		t.Fatalf("upstream called %d times with %d rejections", calls.Load(), client.Stats().Rejected)
+	}
+}
+
+func TestClientDoCallerGaveUp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	breaker := NewBreaker(1, time.Minute)
+	client := NewClient(srv.Client(), Config{}, breaker)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	_, err := client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do = %v, want %v", err, context.Canceled)
+	}
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("breaker %s after the caller cancelled, want closed", breaker.State())
+	}
+}
+
+func TestClientBackoff(t *testing.T) {
+	client := NewClient(nil, Config{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}, nil)
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{attempt: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{attempt: 3, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{attempt: 5, min: 500 * time.Millisecond, max: time.Second},
+		{attempt: 70, min: 500 * time.Millisecond, max: time.Second},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			if got := client.backoff(tt.attempt); got < tt.min || got > tt.max {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", tt.attempt, got, tt.min, tt.max)
+			}
+		}
+	}
+}
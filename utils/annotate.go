@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/storage"
+)
+
+// ConfigureAnnotations turns on annotated result images when RESULT_ANNOTATIONS
+// is true. They are kept in the image store, or in memory for as long as job
+// records when there is no bucket. Results served from the cache skip the
+// pipeline and so come without one. Run it after the job and image stores.
+func ConfigureAnnotations() {
+	if enabled, _ := strconv.ParseBool(os.Getenv("RESULT_ANNOTATIONS")); !enabled {
+		return
+	}
+	if globals.ImageStore != nil {
+		globals.AnnotationStore = globals.ImageStore
+		log.Println("Annotated results: image store")
+		return
+	}
+	globals.AnnotationStore = storage.NewMemory(globals.JobTracker.Retention)
+	log.Println("Annotated results: memory")
+}
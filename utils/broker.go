@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/muthu-kumar-u/go-sse/events/broker"
+	"github.com/muthu-kumar-u/go-sse/globals"
+)
+
+// ConfigureEventStore selects the external event store that lets subscribers
+// follow streams published from another process (EVENT_STORE=dynamodb|memory).
+func ConfigureEventStore() error {
+	ttl := time.Hour
+	if raw := os.Getenv("EVENT_STORE_TTL"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid EVENT_STORE_TTL: %w", err)
+		}
+		ttl = parsed
+	}
+
+	switch store := os.Getenv("EVENT_STORE"); store {
+	case "":
+		return nil
+	case "memory":
+		globals.EventStore = broker.NewMemory(ttl)
+	case "dynamodb":
+		table := os.Getenv("EVENT_STORE_TABLE")
+		if table == "" {
+			return fmt.Errorf("EVENT_STORE_TABLE is required for the dynamodb event store")
+		}
+		globals.EventStore = broker.NewDynamoDB(globals.AWSSession, table, ttl)
+	default:
+		return fmt.Errorf("unknown EVENT_STORE %q", store)
+	}
+
+	log.Printf("Event store enabled: %s", os.Getenv("EVENT_STORE"))
+	return nil
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/muthu-kumar-u/go-sse/cache"
+	"github.com/muthu-kumar-u/go-sse/globals"
+)
+
+// ConfigureResultCache sets up the analysis result cache. RESULT_CACHE picks
+// memory (default), sqlite to also persist results, or off. RESULT_CACHE_SIZE
+// (default 1000) bounds the in-memory entries and RESULT_CACHE_TTL (default
+// 24h) how long a result is reused.
+func ConfigureResultCache(ctx context.Context) error {
+	size := 1000
+	if v := envInt("RESULT_CACHE_SIZE"); v > 0 {
+		size = v
+	}
+	ttl := 24 * time.Hour
+	if v := envDuration("RESULT_CACHE_TTL"); v > 0 {
+		ttl = v
+	}
+
+	var backend cache.Backend
+	switch mode := os.Getenv("RESULT_CACHE"); mode {
+	case "off":
+		return nil
+	case "", "memory":
+	case "sqlite":
+		path := os.Getenv("RESULT_CACHE_PATH")
+		if path == "" {
+			path = "results.db"
+		}
+		sqliteBackend, err := cache.NewSQLiteBackend(path)
+		if err != nil {
+			return fmt.Errorf("failed to open result cache %s: %w", path, err)
+		}
+		backend = sqliteBackend
+		log.Printf("Result cache: sqlite at %s", path)
+	default:
+		return fmt.Errorf("unknown RESULT_CACHE %q", mode)
+	}
+
+	globals.ResultCache = cache.New(size, ttl, backend)
+	go globals.ResultCache.StartJanitor(ctx, time.Minute)
+	return nil
+}
@@ -3,12 +3,15 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/muthu-kumar-u/go-sse/globals"
 	appschema "github.com/muthu-kumar-u/go-sse/models"
+	"github.com/muthu-kumar-u/go-sse/upstream"
 )
 
 // CreateHttpClients initializes HTTP clients for the microservices with connection pool management.
@@ -16,6 +19,26 @@ func CreateHttpClients() error {
 	faceAnalyzeUrl := os.Getenv("FACE_ANALYZE_SERVICE")
 	userServiceUrl := os.Getenv("USER_SERVICE")
 
+	// MOCK_SERVICES stands in for whichever service has no URL
+	mock, err := MockServicesEnabled()
+	if err != nil {
+		return err
+	}
+	if mock && (faceAnalyzeUrl == "" || userServiceUrl == "") {
+		mockUrl, err := startMockServices()
+		if err != nil {
+			return err
+		}
+		if faceAnalyzeUrl == "" {
+			log.Println("WARNING: face analyze calls go to the mock analyzer")
+			faceAnalyzeUrl = mockUrl
+		}
+		if userServiceUrl == "" {
+			log.Println("WARNING: user service calls go to the mock user service")
+			userServiceUrl = mockUrl
+		}
+	}
+
 	if len(faceAnalyzeUrl) == 0 || len(userServiceUrl) == 0 {
 		return errors.New("one or more service url empty")
 	}
@@ -34,6 +57,10 @@ func CreateHttpClients() error {
 		URL: faceAnalyzeUrl,
 	}
 
+	globals.FaceAnalyzeUpstream = upstream.NewClient(globals.FaceAnalyzeService.Client, UpstreamConfig(), upstream.NewBreaker(
+		envInt("UPSTREAM_BREAKER_THRESHOLD"), envDuration("UPSTREAM_BREAKER_COOLDOWN"),
+	))
+
 	globals.UserService = &appschema.ServiceConnection{
 		Client: &http.Client{
 			Transport: transport,
@@ -45,3 +72,23 @@ func CreateHttpClients() error {
 	fmt.Println("All Services up and running")
 	return nil
 }
+
+// UpstreamConfig reads UPSTREAM_MAX_ATTEMPTS, UPSTREAM_ATTEMPT_TIMEOUT and
+// UPSTREAM_TOTAL_TIMEOUT; unset values fall back to the client defaults
+func UpstreamConfig() upstream.Config {
+	return upstream.Config{
+		MaxAttempts:    envInt("UPSTREAM_MAX_ATTEMPTS"),
+		AttemptTimeout: envDuration("UPSTREAM_ATTEMPT_TIMEOUT"),
+		TotalTimeout:   envDuration("UPSTREAM_TOTAL_TIMEOUT"),
+	}
+}
+
+func envInt(key string) int {
+	v, _ := strconv.Atoi(os.Getenv(key))
+	return v
+}
+
+func envDuration(key string) time.Duration {
+	v, _ := time.ParseDuration(os.Getenv(key))
+	return v
+}
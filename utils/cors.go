@@ -21,6 +21,7 @@ func GetCorsConfig() gin.HandlerFunc {
 			"authorization", "accept", "accept-encoding",
 			"accept-language", "connection", "content-length",
 			"content-type", "host", "origin", "referer", "user-agent",
+			"last-event-id", "x-admin-key",
 		},
 		AllowCredentials: true,
 		MaxAge:           24 * time.Hour,
@@ -25,10 +25,16 @@ func PrepareImagePayloadFromBytes(file multipart.File, header *multipart.FileHea
         return nil, fmt.Errorf("failed to read image file: %w", err)
     }
 
+    return PrepareImagePayload(rawBytes, header.Filename, fieldName)
+}
+
+// PrepareImagePayload wraps already read image bytes in a multipart body for the
+// face analyze service
+func PrepareImagePayload(rawBytes []byte, filename, fieldName string) (*appschema.ImagePayload, error) {
     multipartBuf := &bytes.Buffer{}
     writer := multipart.NewWriter(multipartBuf)
     
-    part, err := writer.CreateFormFile(fieldName, header.Filename)
+    part, err := writer.CreateFormFile(fieldName, filename)
     if err != nil {
         return nil, fmt.Errorf("failed to create multipart form file: %w", err)
     }
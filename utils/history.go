@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/history"
+)
+
+// ConfigureScanHistory opens the per-user scan history. SCAN_HISTORY picks
+// memory (default), which keeps each user's newest SCAN_HISTORY_MAX_PER_USER
+// scans (default 500), sqlite to persist it at SCAN_HISTORY_PATH (default
+// history.db), or off.
+func ConfigureScanHistory() error {
+	switch mode := os.Getenv("SCAN_HISTORY"); mode {
+	case "off":
+		return nil
+	case "", "memory":
+		maxPerUser := 500
+		if raw := os.Getenv("SCAN_HISTORY_MAX_PER_USER"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid SCAN_HISTORY_MAX_PER_USER %q", raw)
+			}
+			maxPerUser = parsed
+		}
+		globals.ScanHistory = history.NewMemoryStore(maxPerUser)
+	case "sqlite":
+		path := os.Getenv("SCAN_HISTORY_PATH")
+		if path == "" {
+			path = "history.db"
+		}
+		store, err := history.NewSQLiteStore(path)
+		if err != nil {
+			return fmt.Errorf("failed to open scan history %s: %w", path, err)
+		}
+		globals.ScanHistory = store
+		log.Printf("Scan history: sqlite at %s", path)
+	default:
+		return fmt.Errorf("unknown SCAN_HISTORY %q", mode)
+	}
+	return nil
+}
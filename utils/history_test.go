@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/muthu-kumar-u/go-sse/globals"
+)
+
+func TestConfigureScanHistory(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantErr     bool
+		wantEnabled bool
+	}{
+		{name: "default memory", wantEnabled: true},
+		{name: "capped memory", env: map[string]string{"SCAN_HISTORY": "memory", "SCAN_HISTORY_MAX_PER_USER": "10"}, wantEnabled: true},
+		{name: "invalid cap", env: map[string]string{"SCAN_HISTORY_MAX_PER_USER": "0"}, wantErr: true},
+		{name: "off", env: map[string]string{"SCAN_HISTORY": "off"}},
+		{name: "unknown", env: map[string]string{"SCAN_HISTORY": "redis"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"SCAN_HISTORY", "SCAN_HISTORY_PATH", "SCAN_HISTORY_MAX_PER_USER"} {
+				t.Setenv(key, tt.env[key])
+			}
+			globals.ScanHistory = nil
+			defer func() { globals.ScanHistory = nil }()
+
+			err := ConfigureScanHistory()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConfigureScanHistory = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (globals.ScanHistory != nil) != tt.wantEnabled {
+				t.Fatalf("scan history enabled = %v, want %v", globals.ScanHistory != nil, tt.wantEnabled)
+			}
+		})
+	}
+}
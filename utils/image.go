@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	constants "github.com/muthu-kumar-u/go-sse/const"
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+)
+
+// ImageLimits bounds what an uploaded image may look like before it is sent upstream
+type ImageLimits struct {
+	MaxBytes     int64
+	MinDimension int
+	MaxDimension int
+	MaxPixels    int64
+}
+
+// ImageError is a validation failure with the code reported in the error event
+type ImageError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *ImageError) Error() string {
+	return e.Message
+}
+
+// ImageInfo is what validation learned from the image header
+type ImageInfo struct {
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// ImageLimitsConfig reads IMAGE_MAX_BYTES, IMAGE_MIN_DIMENSION, IMAGE_MAX_DIMENSION
+// and IMAGE_MAX_PIXELS, falling back to defaults for unset values
+func ImageLimitsConfig() ImageLimits {
+	limits := ImageLimits{
+		MaxBytes:     10 << 20,
+		MinDimension: 64,
+		MaxDimension: 8192,
+		MaxPixels:    40_000_000,
+	}
+	if v, err := strconv.ParseInt(os.Getenv("IMAGE_MAX_BYTES"), 10, 64); err == nil && v > 0 {
+		limits.MaxBytes = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("IMAGE_MIN_DIMENSION")); err == nil && v > 0 {
+		limits.MinDimension = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("IMAGE_MAX_DIMENSION")); err == nil && v > 0 {
+		limits.MaxDimension = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("IMAGE_MAX_PIXELS"), 10, 64); err == nil && v > 0 {
+		limits.MaxPixels = v
+	}
+	return limits
+}
+
+// ValidateImage checks an upload by its content rather than its name: the magic
+// bytes must match the filename's extension, the header must decode, the image
+// must be a single frame and its size must fall within limits.
+func ValidateImage(data []byte, filename string, limits ImageLimits) (*ImageInfo, error) {
+	if int64(len(data)) > limits.MaxBytes {
+		return nil, &ImageError{http.StatusRequestEntityTooLarge, faceanalyze_events.ErrCodeFileTooLarge,
+			fmt.Sprintf("Image is larger than %d bytes", limits.MaxBytes)}
+	}
+
+	detected := mimetype.Detect(data)
+	if detected.Is("image/vnd.mozilla.apng") {
+		return nil, &ImageError{http.StatusBadRequest, faceanalyze_events.ErrCodeAnimatedImage, "Animated images are not supported"}
+	}
+	expected := constants.IMAGE_CONTENT_TYPES[strings.ToLower(filepath.Ext(filename))]
+	if !detected.Is("image/jpeg") && !detected.Is("image/png") {
+		return nil, &ImageError{http.StatusBadRequest, faceanalyze_events.ErrCodeUnsupportedType,
+			fmt.Sprintf("File content is %s, only jpg, jpeg, png allowed", detected.String())}
+	}
+	if !detected.Is(expected) {
+		return nil, &ImageError{http.StatusBadRequest, faceanalyze_events.ErrCodeTypeMismatch,
+			fmt.Sprintf("File content is %s but the name says %s", detected.String(), expected)}
+	}
+	if detected.Is("image/jpeg") && isMultiPictureJPEG(data) {
+		return nil, &ImageError{http.StatusBadRequest, faceanalyze_events.ErrCodeAnimatedImage, "Multi-frame images are not supported"}
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, &ImageError{http.StatusBadRequest, faceanalyze_events.ErrCodeCorruptImage, "Image header could not be decoded"}
+	}
+	if config.Width < limits.MinDimension || config.Height < limits.MinDimension {
+		return nil, &ImageError{http.StatusBadRequest, faceanalyze_events.ErrCodeImageTooSmall,
+			fmt.Sprintf("Image is %dx%d, at least %dx%d required", config.Width, config.Height, limits.MinDimension, limits.MinDimension)}
+	}
+	if config.Width > limits.MaxDimension || config.Height > limits.MaxDimension {
+		return nil, &ImageError{http.StatusBadRequest, faceanalyze_events.ErrCodeImageTooLarge,
+			fmt.Sprintf("Image is %dx%d, at most %dx%d allowed", config.Width, config.Height, limits.MaxDimension, limits.MaxDimension)}
+	}
+	if int64(config.Width)*int64(config.Height) > limits.MaxPixels {
+		return nil, &ImageError{http.StatusBadRequest, faceanalyze_events.ErrCodeTooManyPixels,
+			fmt.Sprintf("Image has more than %d pixels", limits.MaxPixels)}
+	}
+
+	return &ImageInfo{ContentType: detected.String(), Width: config.Width, Height: config.Height}, nil
+}
+
+// isMultiPictureJPEG walks the JPEG header segments looking for the APP2 "MPF"
+// marker that multi-picture (MPO) files carry
+func isMultiPictureJPEG(data []byte) bool {
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return false
+		}
+		marker := data[i+1]
+		// start of scan: the header is over
+		if marker == 0xDA {
+			return false
+		}
+		length := int(data[i+2])<<8 | int(data[i+3])
+		if length < 2 {
+			return false
+		}
+		if marker == 0xE2 && i+8 <= len(data) && bytes.Equal(data[i+4:i+8], []byte("MPF\x00")) {
+			return true
+		}
+		i += 2 + length
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"testing"
+
+	faceanalyze_events "github.com/muthu-kumar-u/go-sse/events/faceAnalyze"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withAfter returns data with extra spliced in at offset
+func withAfter(data []byte, offset int, extra []byte) []byte {
+	out := append([]byte{}, data[:offset]...)
+	out = append(out, extra...)
+	return append(out, data[offset:]...)
+}
+
+func TestValidateImage(t *testing.T) {
+	limits := ImageLimits{MaxBytes: 1 << 20, MinDimension: 64, MaxDimension: 1024, MaxPixels: 500_000}
+	jpg := encodeJPEG(t, 128, 96)
+	pngData := encodePNG(t, 128, 96)
+	// an APP2 MPF segment straight after the start of image marks an MPO
+	mpo := withAfter(jpg, 2, []byte{0xFF, 0xE2, 0x00, 0x06, 'M', 'P', 'F', 0x00})
+	// an acTL chunk straight after IHDR marks an animated PNG
+	apng := withAfter(pngData, 33, []byte{0, 0, 0, 8, 'a', 'c', 'T', 'L', 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0})
+
+	tests := []struct {
+		name       string
+		data       []byte
+		filename   string
+		wantType   string
+		wantCode   string
+		wantStatus int
+	}{
+		{name: "jpeg", data: jpg, filename: "face.jpg", wantType: "image/jpeg"},
+		{name: "jpeg extension", data: jpg, filename: "FACE.JPEG", wantType: "image/jpeg"},
+		{name: "png", data: pngData, filename: "face.png", wantType: "image/png"},
+		{name: "too many bytes", data: make([]byte, 2<<20), filename: "face.jpg", wantCode: faceanalyze_events.ErrCodeFileTooLarge, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "not an image", data: []byte("hello, world"), filename: "face.jpg", wantCode: faceanalyze_events.ErrCodeUnsupportedType},
+		{name: "gif", data: []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), filename: "face.gif", wantCode: faceanalyze_events.ErrCodeUnsupportedType},
+		{name: "png named jpg", data: pngData, filename: "face.jpg", wantCode: faceanalyze_events.ErrCodeTypeMismatch},
+		{name: "no extension", data: jpg, filename: "face", wantCode: faceanalyze_events.ErrCodeTypeMismatch},
+		{name: "multi-picture jpeg", data: mpo, filename: "face.jpg", wantCode: faceanalyze_events.ErrCodeAnimatedImage},
+		{name: "animated png", data: apng, filename: "face.png", wantCode: faceanalyze_events.ErrCodeAnimatedImage},
+		{name: "corrupt header", data: pngData[:20], filename: "face.png", wantCode: faceanalyze_events.ErrCodeCorruptImage},
+		{name: "too small", data: encodePNG(t, 32, 128), filename: "face.png", wantCode: faceanalyze_events.ErrCodeImageTooSmall},
+		{name: "too large", data: encodePNG(t, 2048, 64), filename: "face.png", wantCode: faceanalyze_events.ErrCodeImageTooLarge},
+		{name: "too many pixels", data: encodePNG(t, 1000, 1000), filename: "face.png", wantCode: faceanalyze_events.ErrCodeTooManyPixels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ValidateImage(tt.data, tt.filename, limits)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("ValidateImage: %v", err)
+				}
+				if info.ContentType != tt.wantType || info.Width != 128 || info.Height != 96 {
+					t.Fatalf("info = %+v", info)
+				}
+				return
+			}
+
+			var imageErr *ImageError
+			if !errors.As(err, &imageErr) {
+				t.Fatalf("ValidateImage = %v, want an ImageError", err)
+			}
+			status := tt.wantStatus
+			if status == 0 {
+				status = http.StatusBadRequest
+			}
+			if imageErr.Code != tt.wantCode || imageErr.Status != status {
+				t.Fatalf("error = %d %s (%s), want %d %s", imageErr.Status, imageErr.Code, imageErr.Message, status, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestImageLimitsConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want ImageLimits
+	}{
+		{name: "defaults", want: ImageLimits{MaxBytes: 10 << 20, MinDimension: 64, MaxDimension: 8192, MaxPixels: 40_000_000}},
+		{
+			name: "overrides",
+			env:  map[string]string{"IMAGE_MAX_BYTES": "1024", "IMAGE_MIN_DIMENSION": "10", "IMAGE_MAX_DIMENSION": "100", "IMAGE_MAX_PIXELS": "5000"},
+			want: ImageLimits{MaxBytes: 1024, MinDimension: 10, MaxDimension: 100, MaxPixels: 5000},
+		},
+		{
+			name: "invalid values keep the defaults",
+			env:  map[string]string{"IMAGE_MAX_BYTES": "lots", "IMAGE_MIN_DIMENSION": "-1", "IMAGE_MAX_DIMENSION": "0"},
+			want: ImageLimits{MaxBytes: 10 << 20, MinDimension: 64, MaxDimension: 8192, MaxPixels: 40_000_000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"IMAGE_MAX_BYTES", "IMAGE_MIN_DIMENSION", "IMAGE_MAX_DIMENSION", "IMAGE_MAX_PIXELS"} {
+				t.Setenv(key, tt.env[key])
+			}
+			if got := ImageLimitsConfig(); got != tt.want {
+				t.Fatalf("ImageLimitsConfig = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/jobs"
+)
+
+// JobPoolConfig reads JOB_WORKERS, JOB_QUEUE_DEPTH, JOB_TIMEOUT, JOB_MAX_IMAGES
+// and JOB_IMAGE_PARALLELISM; unset values fall back to the pool defaults
+func JobPoolConfig() jobs.Config {
+	workers, _ := strconv.Atoi(os.Getenv("JOB_WORKERS"))
+	depth, _ := strconv.Atoi(os.Getenv("JOB_QUEUE_DEPTH"))
+	timeout, _ := time.ParseDuration(os.Getenv("JOB_TIMEOUT"))
+	maxImages, _ := strconv.Atoi(os.Getenv("JOB_MAX_IMAGES"))
+	parallelism, _ := strconv.Atoi(os.Getenv("JOB_IMAGE_PARALLELISM"))
+
+	return jobs.Config{
+		Workers:          workers,
+		QueueDepth:       depth,
+		Timeout:          timeout,
+		MaxImages:        maxImages,
+		ImageParallelism: parallelism,
+	}
+}
+
+// ConfigureJobStore opens the job record store (JOB_STORE=memory|sqlite|dynamodb,
+// default memory) and starts purging records older than JOB_RETENTION (default
+// 24h). In production, direct uploads are started by whichever instance the
+// storage event or finalize call reaches, so they need the shared dynamodb store.
+func ConfigureJobStore(ctx context.Context) error {
+	retention := 24 * time.Hour
+	if raw := os.Getenv("JOB_RETENTION"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid JOB_RETENTION: %w", err)
+		}
+		retention = parsed
+	}
+
+	backend := os.Getenv("JOB_STORE")
+	if backend == "" {
+		backend = "memory"
+	}
+	if backend != "dynamodb" && os.Getenv("PRODUCTION") == "true" && os.Getenv("IMAGE_BUCKET") != "" {
+		return fmt.Errorf("direct uploads in production need JOB_STORE=dynamodb, %q is local to one instance", backend)
+	}
+
+	var store jobs.Store
+	switch backend {
+	case "memory":
+		store = jobs.NewMemoryStore()
+	case "sqlite":
+		path := os.Getenv("JOB_STORE_PATH")
+		if path == "" {
+			path = "jobs.db"
+		}
+		sqliteStore, err := jobs.NewSQLiteStore(path)
+		if err != nil {
+			return fmt.Errorf("failed to open job store %s: %w", path, err)
+		}
+		store = sqliteStore
+		log.Printf("Job store: sqlite at %s", path)
+	case "dynamodb":
+		table := os.Getenv("JOB_STORE_TABLE")
+		if table == "" {
+			return fmt.Errorf("JOB_STORE_TABLE is required for the dynamodb job store")
+		}
+		store = jobs.NewDynamoDBStore(globals.AWSSession, table)
+		log.Printf("Job store: dynamodb table %s", table)
+	default:
+		return fmt.Errorf("unknown JOB_STORE %q", backend)
+	}
+
+	globals.JobTracker = &jobs.Tracker{Store: store, Retention: retention}
+	go globals.JobTracker.StartJanitor(ctx, time.Minute)
+	return nil
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/muthu-kumar-u/go-sse/globals"
+)
+
+func TestConfigureJobStore(t *testing.T) {
+	sqlitePath := filepath.Join(t.TempDir(), "jobs.db")
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{name: "default memory"},
+		{name: "sqlite", env: map[string]string{"JOB_STORE": "sqlite", "JOB_STORE_PATH": sqlitePath}},
+		{name: "dynamodb without a table", env: map[string]string{"JOB_STORE": "dynamodb"}, wantErr: true},
+		{name: "unknown store", env: map[string]string{"JOB_STORE": "redis"}, wantErr: true},
+		{name: "memory in production", env: map[string]string{"PRODUCTION": "true"}},
+		// storage events and finalize calls reach any instance
+		{name: "direct uploads in production", env: map[string]string{"PRODUCTION": "true", "IMAGE_BUCKET": "b"}, wantErr: true},
+		{name: "sqlite direct uploads in production", env: map[string]string{"PRODUCTION": "true", "IMAGE_BUCKET": "b", "JOB_STORE": "sqlite", "JOB_STORE_PATH": sqlitePath}, wantErr: true},
+		{name: "direct uploads outside production", env: map[string]string{"IMAGE_BUCKET": "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"JOB_STORE", "JOB_STORE_PATH", "JOB_STORE_TABLE", "JOB_RETENTION", "PRODUCTION", "IMAGE_BUCKET"} {
+				t.Setenv(key, tt.env[key])
+			}
+			globals.JobTracker = nil
+			defer func() { globals.JobTracker = nil }()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err := ConfigureJobStore(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConfigureJobStore = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (globals.JobTracker != nil) == tt.wantErr {
+				t.Fatalf("job tracker configured = %v", globals.JobTracker != nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// LambdaURLHandler is the signature lambda.Start receives for a streaming function URL
+type LambdaURLHandler func(ctx context.Context, req events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error)
+
+// LambdaURLEmulator serves a function URL handler over plain HTTP the way the
+// Lambda runtime would: each request becomes a LambdaFunctionURLRequest with a
+// base64 body and the streaming response body is relayed as it is produced.
+type LambdaURLEmulator struct {
+	Handler LambdaURLHandler
+}
+
+func NewLambdaURLEmulator(handler LambdaURLHandler) *LambdaURLEmulator {
+	return &LambdaURLEmulator{Handler: handler}
+}
+
+func (e *LambdaURLEmulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	event, err := NewLambdaRequestFromHTTP(r)
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := e.Handler(r.Context(), event)
+	if err != nil {
+		log.Printf("[lambda-local] handler error: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusBadGateway)
+		return
+	}
+	if closer, ok := resp.Body.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	for k, v := range resp.Headers {
+		w.Header().Set(k, v)
+	}
+	for _, cookie := range resp.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+	status := resp.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+
+	if resp.Body == nil {
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("[lambda-local] response body error: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// NewLambdaRequestFromHTTP builds the function URL event for an incoming request
+func NewLambdaRequestFromHTTP(r *http.Request) (events.LambdaFunctionURLRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return events.LambdaFunctionURLRequest{}, err
+	}
+
+	// function URLs deliver lower-cased header names with repeated values joined
+	headers := make(map[string]string, len(r.Header))
+	for k, v := range r.Header {
+		if strings.EqualFold(k, "Cookie") {
+			continue
+		}
+		headers[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+	headers["host"] = r.Host
+
+	var cookies []string
+	for _, cookie := range r.Cookies() {
+		cookies = append(cookies, cookie.String())
+	}
+
+	query := make(map[string]string)
+	for k, v := range r.URL.Query() {
+		query[k] = strings.Join(v, ",")
+	}
+
+	sourceIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		sourceIP = host
+	}
+	now := time.Now()
+
+	return events.LambdaFunctionURLRequest{
+		Version:               "2.0",
+		RawPath:               r.URL.Path,
+		RawQueryString:        r.URL.RawQuery,
+		Cookies:               cookies,
+		Headers:               headers,
+		QueryStringParameters: query,
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			RequestID:  uuid.NewString(),
+			DomainName: r.Host,
+			Time:       now.Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch:  now.UnixMilli(),
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Protocol:  r.Proto,
+				SourceIP:  sourceIP,
+				UserAgent: r.UserAgent(),
+			},
+		},
+		Body:            base64.StdEncoding.EncodeToString(body),
+		IsBase64Encoded: true,
+	}, nil
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestLambdaURLEmulator(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    LambdaURLHandler
+		wantStatus int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{
+			name: "streams the body",
+			handler: func(ctx context.Context, req events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+				return &events.LambdaFunctionURLStreamingResponse{
+					StatusCode: http.StatusAccepted,
+					Headers:    map[string]string{"Content-Type": "text/event-stream"},
+					Cookies:    []string{"session=abc"},
+					Body:       strings.NewReader("event: done\ndata: {}\n\n"),
+				}, nil
+			},
+			wantStatus: http.StatusAccepted,
+			wantBody:   "event: done\ndata: {}\n\n",
+			wantHeader: map[string]string{"Content-Type": "text/event-stream", "Set-Cookie": "session=abc"},
+		},
+		{
+			name: "no status",
+			handler: func(ctx context.Context, req events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+				return &events.LambdaFunctionURLStreamingResponse{}, nil
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "handler error",
+			handler: func(ctx context.Context, req events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+				return nil, errors.New("boom")
+			},
+			wantStatus: http.StatusBadGateway,
+			wantBody:   "Internal Server Error\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(NewLambdaURLEmulator(tt.handler))
+			defer srv.Close()
+
+			resp, err := srv.Client().Get(srv.URL)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			for k, v := range tt.wantHeader {
+				if got := resp.Header.Get(k); got != v {
+					t.Errorf("header %s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestNewLambdaRequestFromHTTP(t *testing.T) {
+	body := []byte("--x\r\nbinary \x00\xff\r\n")
+	r := httptest.NewRequest(http.MethodPost, "http://api.example/api/facelog/upload?inline=true&tag=a&tag=b", strings.NewReader(string(body)))
+	r.RemoteAddr = "203.0.113.9:51234"
+	r.Header.Set("Authorization", "Bearer dev-token")
+	r.Header.Add("Accept", "text/event-stream")
+	r.Header.Add("Accept", "application/json")
+	r.Header.Set("Cookie", "a=1; b=2")
+
+	req, err := NewLambdaRequestFromHTTP(r)
+	if err != nil {
+		t.Fatalf("NewLambdaRequestFromHTTP: %v", err)
+	}
+
+	decoded, _ := base64.StdEncoding.DecodeString(req.Body)
+	tests := []struct {
+		field string
+		got   string
+		want  string
+	}{
+		{field: "body", got: string(decoded), want: string(body)},
+		{field: "raw path", got: req.RawPath, want: "/api/facelog/upload"},
+		{field: "raw query", got: req.RawQueryString, want: "inline=true&tag=a&tag=b"},
+		{field: "query tag", got: req.QueryStringParameters["tag"], want: "a,b"},
+		{field: "authorization", got: req.Headers["authorization"], want: "Bearer dev-token"},
+		{field: "accept", got: req.Headers["accept"], want: "text/event-stream,application/json"},
+		{field: "cookie header", got: req.Headers["cookie"], want: ""},
+		{field: "cookies", got: strings.Join(req.Cookies, ";"), want: "a=1;b=2"},
+		{field: "host", got: req.Headers["host"], want: "api.example"},
+		{field: "method", got: req.RequestContext.HTTP.Method, want: http.MethodPost},
+		{field: "source ip", got: req.RequestContext.HTTP.SourceIP, want: "203.0.113.9"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+	if !req.IsBase64Encoded || req.RequestContext.RequestID == "" {
+		t.Errorf("request = %+v, want a base64 body and a request id", req)
+	}
+}
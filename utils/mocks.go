@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/muthu-kumar-u/go-sse/mockservice"
+)
+
+// MockServicesConfig reads the mock services' behaviour: MOCK_LATENCY and
+// MOCK_JITTER delays, a MOCK_FAILURE_RATE between 0 and 1, MOCK_RESULTS canned
+// (default) or random, a MOCK_RESULT_FILE served verbatim instead, MOCK_STREAM
+// sse or ndjson, a MOCK_SEED for repeatable runs, and MOCK_TOKENS_FILE, a JSON
+// object of bearer tokens to accounts replacing the dev-token fixtures, with
+// MOCK_ANY_TOKEN accepting every other token too. The analyzer expects the API
+// key the app sends, FACEANALYZE_SERVICE_AUTH_API_KEY.
+func MockServicesConfig() (mockservice.Config, error) {
+	cfg := mockservice.Config{
+		Latency: envDuration("MOCK_LATENCY"),
+		Jitter:  envDuration("MOCK_JITTER"),
+		Stream:  os.Getenv("MOCK_STREAM"),
+		APIKey:  os.Getenv("FACEANALYZE_SERVICE_AUTH_API_KEY"),
+	}
+	cfg.AnyToken, _ = strconv.ParseBool(os.Getenv("MOCK_ANY_TOKEN"))
+	cfg.Seed, _ = strconv.ParseUint(os.Getenv("MOCK_SEED"), 10, 64)
+
+	if raw := os.Getenv("MOCK_FAILURE_RATE"); raw != "" {
+		rate, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return cfg, fmt.Errorf("MOCK_FAILURE_RATE must be between 0 and 1, got %q", raw)
+		}
+		cfg.FailureRate = rate
+	}
+	switch results := os.Getenv("MOCK_RESULTS"); results {
+	case "", "canned":
+	case "random":
+		cfg.Random = true
+	default:
+		return cfg, fmt.Errorf("unknown MOCK_RESULTS %q", results)
+	}
+	switch cfg.Stream {
+	case mockservice.StreamOff, mockservice.StreamSSE, mockservice.StreamNDJSON:
+	default:
+		return cfg, fmt.Errorf("unknown MOCK_STREAM %q", cfg.Stream)
+	}
+
+	if path := os.Getenv("MOCK_RESULT_FILE"); path != "" {
+		result, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		if !json.Valid(result) {
+			return cfg, fmt.Errorf("MOCK_RESULT_FILE %s is not JSON", path)
+		}
+		cfg.Result = result
+	}
+	if path := os.Getenv("MOCK_TOKENS_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		if err := json.Unmarshal(raw, &cfg.Tokens); err != nil {
+			return cfg, fmt.Errorf("MOCK_TOKENS_FILE %s: %w", path, err)
+		}
+	}
+	return cfg, nil
+}
+
+// MockServicesEnabled reports whether MOCK_SERVICES is set. The mocks accept
+// fixed tokens and make up results, so they are refused with PRODUCTION=true.
+func MockServicesEnabled() (bool, error) {
+	mock, _ := strconv.ParseBool(os.Getenv("MOCK_SERVICES"))
+	if mock && os.Getenv("PRODUCTION") == "true" {
+		return false, errors.New("MOCK_SERVICES cannot be used in production")
+	}
+	return mock, nil
+}
+
+// startMockServices serves the mocks in process on MOCK_SERVICES_ADDR, a free
+// loopback port by default, and returns their base URL
+func startMockServices() (string, error) {
+	cfg, err := MockServicesConfig()
+	if err != nil {
+		return "", err
+	}
+	addr := os.Getenv("MOCK_SERVICES_ADDR")
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	url, err := mockservice.Listen(addr, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	tokens := cfg.Tokens
+	if tokens == nil {
+		tokens = mockservice.DefaultTokens
+	}
+	log.Printf("WARNING: MOCK_SERVICES is on, mock services on %s stand in for the real ones (latency %s, failure rate %.2f, random %v, %d tokens)",
+		url, cfg.Latency.Round(time.Millisecond), cfg.FailureRate, cfg.Random, len(tokens))
+	return url, nil
+}
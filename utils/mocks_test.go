@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMockServicesEnabled(t *testing.T) {
+	tests := []struct {
+		mock, production string
+		want             bool
+		wantErr          bool
+	}{
+		{mock: "", production: "", want: false},
+		{mock: "true", production: "", want: true},
+		{mock: "1", production: "false", want: true},
+		{mock: "false", production: "true", want: false},
+		{mock: "true", production: "true", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Setenv("MOCK_SERVICES", tt.mock)
+		t.Setenv("PRODUCTION", tt.production)
+		got, err := MockServicesEnabled()
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("MOCK_SERVICES=%q PRODUCTION=%q: got %v, %v; want %v, error %v", tt.mock, tt.production, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCreateHttpClientsRefusesMocksInProduction(t *testing.T) {
+	t.Setenv("MOCK_SERVICES", "true")
+	t.Setenv("PRODUCTION", "true")
+	t.Setenv("FACE_ANALYZE_SERVICE", "")
+	t.Setenv("USER_SERVICE", "")
+	if err := CreateHttpClients(); err == nil {
+		t.Fatal("CreateHttpClients started the mocks in production")
+	}
+}
+
+func TestMockServicesConfig(t *testing.T) {
+	dir := t.TempDir()
+	resultFile := filepath.Join(dir, "result.json")
+	os.WriteFile(resultFile, []byte(`{"data":{}}`), 0o644)
+	badResultFile := filepath.Join(dir, "bad.json")
+	os.WriteFile(badResultFile, []byte(`{`), 0o644)
+	tokensFile := filepath.Join(dir, "tokens.json")
+	os.WriteFile(tokensFile, []byte(`{"tok":{"id":"u1","email":"u1@example.com"}}`), 0o644)
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+		check   func(t *testing.T, env map[string]string)
+	}{
+		{name: "defaults", env: map[string]string{}},
+		{name: "timing", env: map[string]string{"MOCK_LATENCY": "200ms", "MOCK_JITTER": "1s", "MOCK_FAILURE_RATE": "0.25", "MOCK_SEED": "7"}},
+		{name: "failure rate too high", env: map[string]string{"MOCK_FAILURE_RATE": "1.5"}, wantErr: true},
+		{name: "failure rate not a number", env: map[string]string{"MOCK_FAILURE_RATE": "often"}, wantErr: true},
+		{name: "random results", env: map[string]string{"MOCK_RESULTS": "random"}},
+		{name: "unknown results", env: map[string]string{"MOCK_RESULTS": "fancy"}, wantErr: true},
+		{name: "ndjson stream", env: map[string]string{"MOCK_STREAM": "ndjson"}},
+		{name: "unknown stream", env: map[string]string{"MOCK_STREAM": "websocket"}, wantErr: true},
+		{name: "result file", env: map[string]string{"MOCK_RESULT_FILE": resultFile}},
+		{name: "result file not json", env: map[string]string{"MOCK_RESULT_FILE": badResultFile}, wantErr: true},
+		{name: "missing result file", env: map[string]string{"MOCK_RESULT_FILE": filepath.Join(dir, "none.json")}, wantErr: true},
+		{name: "tokens file", env: map[string]string{"MOCK_TOKENS_FILE": tokensFile, "MOCK_ANY_TOKEN": "true"}},
+		{name: "tokens file not json", env: map[string]string{"MOCK_TOKENS_FILE": badResultFile}, wantErr: true},
+	}
+	keys := []string{"MOCK_LATENCY", "MOCK_JITTER", "MOCK_FAILURE_RATE", "MOCK_SEED", "MOCK_RESULTS", "MOCK_STREAM", "MOCK_RESULT_FILE", "MOCK_TOKENS_FILE", "MOCK_ANY_TOKEN", "FACEANALYZE_SERVICE_AUTH_API_KEY"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range keys {
+				t.Setenv(key, tt.env[key])
+			}
+			cfg, err := MockServicesConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MockServicesConfig = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			wantLatency, _ := time.ParseDuration(tt.env["MOCK_LATENCY"])
+			if cfg.Latency != wantLatency {
+				t.Errorf("Latency = %s, want %s", cfg.Latency, wantLatency)
+			}
+			if cfg.Random != (tt.env["MOCK_RESULTS"] == "random") {
+				t.Errorf("Random = %v", cfg.Random)
+			}
+			if cfg.Stream != tt.env["MOCK_STREAM"] {
+				t.Errorf("Stream = %q", cfg.Stream)
+			}
+			if (cfg.Result != nil) != (tt.env["MOCK_RESULT_FILE"] != "") {
+				t.Errorf("Result = %s", cfg.Result)
+			}
+			if tt.env["MOCK_TOKENS_FILE"] != "" && (cfg.Tokens["tok"].ID != "u1" || !cfg.AnyToken) {
+				t.Errorf("Tokens = %+v, AnyToken = %v", cfg.Tokens, cfg.AnyToken)
+			}
+			if tt.env["MOCK_FAILURE_RATE"] == "0.25" && (cfg.FailureRate != 0.25 || cfg.Seed != 7 || cfg.Jitter != time.Second) {
+				t.Errorf("FailureRate %v, Seed %d, Jitter %s", cfg.FailureRate, cfg.Seed, cfg.Jitter)
+			}
+		})
+	}
+}
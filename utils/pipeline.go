@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PipelineWeights reads PIPELINE_WEIGHTS, a list like
+// "validate=20,preprocess=20,analyze=55,postprocess=5". Stages left out keep
+// their default weight.
+func PipelineWeights() map[string]int {
+	weights := map[string]int{}
+	for name, raw := range envPairs("PIPELINE_WEIGHTS") {
+		weight, err := strconv.Atoi(raw)
+		if err != nil || weight < 0 {
+			log.Printf("[pipeline] Ignoring weight %q for stage %s", raw, name)
+			continue
+		}
+		weights[name] = weight
+	}
+	return weights
+}
+
+// AnalyzerPaths reads ANALYZERS, a list like "skin_tone=skin/tone,acne=acne/detect"
+// naming extra analyses and their path on the face analyze service
+func AnalyzerPaths() map[string]string {
+	return envPairs("ANALYZERS")
+}
+
+// envPairs parses a comma separated list of name=value pairs
+func envPairs(key string) map[string]string {
+	pairs := map[string]string{}
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" || value == "" {
+			continue
+		}
+		pairs[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return pairs
+}
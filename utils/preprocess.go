@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	appschema "github.com/muthu-kumar-u/go-sse/models"
+)
+
+// PreprocessConfig controls the optional normalisation applied to images before
+// they are sent for analysis
+type PreprocessConfig struct {
+	Enabled bool
+	// MaxEdge is the longest side, in pixels, an image is downscaled to
+	MaxEdge int
+	Quality int
+}
+
+// PreprocessConfigFromEnv reads IMAGE_PREPROCESS, IMAGE_PREPROCESS_MAX_EDGE
+// (default 2048) and IMAGE_PREPROCESS_QUALITY (default 85)
+func PreprocessConfigFromEnv() PreprocessConfig {
+	config := PreprocessConfig{MaxEdge: 2048, Quality: 85}
+	config.Enabled, _ = strconv.ParseBool(os.Getenv("IMAGE_PREPROCESS"))
+	if v, err := strconv.Atoi(os.Getenv("IMAGE_PREPROCESS_MAX_EDGE")); err == nil && v > 0 {
+		config.MaxEdge = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("IMAGE_PREPROCESS_QUALITY")); err == nil && v > 0 && v <= 100 {
+		config.Quality = v
+	}
+	return config
+}
+
+// PreprocessImage turns an upload upright according to its EXIF orientation,
+// downscales it so neither side exceeds MaxEdge and re-encodes it as a JPEG
+// without metadata. The transform describes how to map results back.
+func PreprocessImage(data []byte, config PreprocessConfig) ([]byte, *appschema.ImageTransform, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	orientation := exifOrientation(data)
+	upright := orient(flatten(src), orientation)
+
+	width, height := upright.Bounds().Dx(), upright.Bounds().Dy()
+	transform := &appschema.ImageTransform{
+		Orientation:    orientation,
+		OriginalWidth:  width,
+		OriginalHeight: height,
+		Width:          width,
+		Height:         height,
+		Scale:          1,
+	}
+
+	if longest := max(width, height); config.MaxEdge > 0 && longest > config.MaxEdge {
+		transform.Scale = float64(config.MaxEdge) / float64(longest)
+		transform.Width = max(1, int(float64(width)*transform.Scale+0.5))
+		transform.Height = max(1, int(float64(height)*transform.Scale+0.5))
+		upright = downscale(upright, transform.Width, transform.Height)
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, upright, &jpeg.Options{Quality: config.Quality}); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return out.Bytes(), transform, nil
+}
+
+// JPEGFilename swaps filename's extension for .jpg once it has been re-encoded
+func JPEGFilename(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + ".jpg"
+}
+
+// flatten copies src onto a white canvas, since JPEG has no transparency
+func flatten(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Over)
+	return dst
+}
+
+// orient applies an EXIF orientation (1-8) so the image displays upright
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for sy := 0; sy < h; sy++ {
+		for sx := 0; sx < w; sx++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-sx, sy
+			case 3:
+				dx, dy = w-1-sx, h-1-sy
+			case 4:
+				dx, dy = sx, h-1-sy
+			case 5:
+				dx, dy = sy, sx
+			case 6:
+				dx, dy = h-1-sy, sx
+			case 7:
+				dx, dy = h-1-sy, w-1-sx
+			case 8:
+				dx, dy = sy, w-1-sx
+			}
+			si, di := src.PixOffset(sx, sy), dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// downscale shrinks src to w x h, averaging the source pixels each output pixel covers
+func downscale(src *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, (y+1)*sh/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, (x+1)*sw/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(src.Pix[i])
+					g += uint64(src.Pix[i+1])
+					b += uint64(src.Pix[i+2])
+					a += uint64(src.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+			di := dst.PixOffset(x, y)
+			dst.Pix[di], dst.Pix[di+1], dst.Pix[di+2], dst.Pix[di+3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
+
+// exifOrientation reads the orientation tag from a JPEG's EXIF segment, returning
+// 1 (upright) when there is none
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation finds tag 0x0112 in the first IFD of a TIFF structure
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for e := 0; e < entries; e++ {
+		entry := ifd + 2 + e*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// exifSegment is an APP1 segment holding a single orientation tag
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func TestExifOrientation(t *testing.T) {
+	jpg := encodeJPEG(t, 16, 8)
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{name: "no exif", data: jpg, want: 1},
+		{name: "little endian", data: withAfter(jpg, 2, exifSegment(binary.LittleEndian, 6)), want: 6},
+		{name: "big endian", data: withAfter(jpg, 2, exifSegment(binary.BigEndian, 8)), want: 8},
+		{name: "out of range", data: withAfter(jpg, 2, exifSegment(binary.LittleEndian, 9)), want: 1},
+		{name: "png", data: encodePNG(t, 16, 8), want: 1},
+		{name: "truncated", data: withAfter(jpg, 2, exifSegment(binary.LittleEndian, 6))[:12], want: 1},
+	}
+	for _, tt := range tests {
+		if got := exifOrientation(tt.data); got != tt.want {
+			t.Errorf("%s: exifOrientation = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// a 3x2 image with its top-left pixel marked
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.SetRGBA(0, 0, color.RGBA{0xff, 0, 0, 0xff})
+
+	tests := []struct {
+		orientation int
+		size        image.Point
+		marked      image.Point
+	}{
+		{orientation: 1, size: image.Pt(3, 2), marked: image.Pt(0, 0)},
+		{orientation: 2, size: image.Pt(3, 2), marked: image.Pt(2, 0)},
+		{orientation: 3, size: image.Pt(3, 2), marked: image.Pt(2, 1)},
+		{orientation: 4, size: image.Pt(3, 2), marked: image.Pt(0, 1)},
+		{orientation: 5, size: image.Pt(2, 3), marked: image.Pt(0, 0)},
+		{orientation: 6, size: image.Pt(2, 3), marked: image.Pt(1, 0)},
+		{orientation: 7, size: image.Pt(2, 3), marked: image.Pt(1, 2)},
+		{orientation: 8, size: image.Pt(2, 3), marked: image.Pt(0, 2)},
+	}
+	for _, tt := range tests {
+		dst := orient(src, tt.orientation)
+		if dst.Bounds().Size() != tt.size {
+			t.Errorf("orientation %d: size %v, want %v", tt.orientation, dst.Bounds().Size(), tt.size)
+			continue
+		}
+		if dst.RGBAAt(tt.marked.X, tt.marked.Y).R != 0xff {
+			t.Errorf("orientation %d: marked pixel not at %v", tt.orientation, tt.marked)
+		}
+	}
+}
+
+func TestPreprocessImage(t *testing.T) {
+	rotated := withAfter(encodeJPEG(t, 400, 200), 2, exifSegment(binary.LittleEndian, 6))
+	tests := []struct {
+		name          string
+		data          []byte
+		maxEdge       int
+		wantSize      image.Point
+		wantOriginal  image.Point
+		wantScale     float64
+		wantOrientTag int
+	}{
+		{name: "small enough", data: encodePNG(t, 300, 200), maxEdge: 1000, wantSize: image.Pt(300, 200), wantOriginal: image.Pt(300, 200), wantScale: 1, wantOrientTag: 1},
+		{name: "downscaled", data: encodePNG(t, 2000, 500), maxEdge: 1000, wantSize: image.Pt(1000, 250), wantOriginal: image.Pt(2000, 500), wantScale: 0.5, wantOrientTag: 1},
+		{name: "turned upright", data: rotated, maxEdge: 1000, wantSize: image.Pt(200, 400), wantOriginal: image.Pt(200, 400), wantScale: 1, wantOrientTag: 6},
+		{name: "turned then downscaled", data: rotated, maxEdge: 100, wantSize: image.Pt(50, 100), wantOriginal: image.Pt(200, 400), wantScale: 0.25, wantOrientTag: 6},
+		{name: "no limit", data: encodePNG(t, 2000, 500), wantSize: image.Pt(2000, 500), wantOriginal: image.Pt(2000, 500), wantScale: 1, wantOrientTag: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, transform, err := PreprocessImage(tt.data, PreprocessConfig{Enabled: true, MaxEdge: tt.maxEdge, Quality: 85})
+			if err != nil {
+				t.Fatalf("PreprocessImage: %v", err)
+			}
+			config, err := jpeg.DecodeConfig(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("output is not a JPEG: %v", err)
+			}
+			if image.Pt(config.Width, config.Height) != tt.wantSize {
+				t.Errorf("output is %dx%d, want %v", config.Width, config.Height, tt.wantSize)
+			}
+			if transform.Width != tt.wantSize.X || transform.Height != tt.wantSize.Y ||
+				transform.OriginalWidth != tt.wantOriginal.X || transform.OriginalHeight != tt.wantOriginal.Y ||
+				transform.Scale != tt.wantScale || transform.Orientation != tt.wantOrientTag {
+				t.Errorf("transform = %+v", transform)
+			}
+			// the EXIF segment is not carried over
+			if exifOrientation(out) != 1 {
+				t.Error("output still carries an orientation")
+			}
+		})
+	}
+
+	if _, _, err := PreprocessImage([]byte("nope"), PreprocessConfig{MaxEdge: 100, Quality: 85}); err == nil {
+		t.Error("PreprocessImage accepted data that is not an image")
+	}
+}
+
+func TestPreprocessFlattensTransparency(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 16, 16)))
+	out, _, err := PreprocessImage(buf.Bytes(), PreprocessConfig{MaxEdge: 100, Quality: 100})
+	if err != nil {
+		t.Fatalf("PreprocessImage: %v", err)
+	}
+	img, _ := jpeg.Decode(bytes.NewReader(out))
+	if r, g, b, _ := img.At(8, 8).RGBA(); r>>8 < 0xf0 || g>>8 < 0xf0 || b>>8 < 0xf0 {
+		t.Fatalf("transparent pixel became %v, want white", img.At(8, 8))
+	}
+}
+
+func TestPreprocessConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want PreprocessConfig
+	}{
+		{name: "defaults", want: PreprocessConfig{MaxEdge: 2048, Quality: 85}},
+		{name: "enabled", env: map[string]string{"IMAGE_PREPROCESS": "true", "IMAGE_PREPROCESS_MAX_EDGE": "1024", "IMAGE_PREPROCESS_QUALITY": "70"}, want: PreprocessConfig{Enabled: true, MaxEdge: 1024, Quality: 70}},
+		{name: "quality out of range", env: map[string]string{"IMAGE_PREPROCESS_QUALITY": "101"}, want: PreprocessConfig{MaxEdge: 2048, Quality: 85}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"IMAGE_PREPROCESS", "IMAGE_PREPROCESS_MAX_EDGE", "IMAGE_PREPROCESS_QUALITY"} {
+				t.Setenv(key, tt.env[key])
+			}
+			if got := PreprocessConfigFromEnv(); got != tt.want {
+				t.Fatalf("PreprocessConfigFromEnv = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJPEGFilename(t *testing.T) {
+	tests := map[string]string{"face.png": "face.jpg", "face.jpeg": "face.jpg", "face": "face.jpg", "a.b.PNG": "a.b.jpg"}
+	for in, want := range tests {
+		if got := JPEGFilename(in); got != want {
+			t.Errorf("JPEGFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
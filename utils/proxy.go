@@ -2,33 +2,161 @@ package utils
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
 )
 
-type StreamingResponseWriter struct {
-	Headers http.Header
-	Body    *bytes.Buffer
-	Status  int
+// LambdaResponseWriter adapts http.ResponseWriter to a Lambda streaming response.
+// Status and headers are held until the first write or flush, then the body is
+// piped to the Lambda runtime as the handler produces it.
+type LambdaResponseWriter struct {
+	header    http.Header
+	status    int
+	committed http.Header
+	body      *io.PipeWriter
+	ready     chan struct{}
+	once      sync.Once
 }
 
-func (w *StreamingResponseWriter) Header() http.Header {
-	return w.Headers
+func NewLambdaResponseWriter(body *io.PipeWriter) *LambdaResponseWriter {
+	return &LambdaResponseWriter{
+		header: make(http.Header),
+		status: http.StatusOK,
+		body:   body,
+		ready:  make(chan struct{}),
+	}
 }
 
-func (w *StreamingResponseWriter) Write(b []byte) (int, error) {
-	return w.Body.Write(b)
+func (w *LambdaResponseWriter) Header() http.Header {
+	return w.header
 }
 
-func (w *StreamingResponseWriter) WriteHeader(statusCode int) {
-	w.Status = statusCode
+func (w *LambdaResponseWriter) WriteHeader(statusCode int) {
+	w.once.Do(func() {
+		w.status = statusCode
+		w.committed = w.header.Clone()
+		close(w.ready)
+	})
 }
 
-func FlattenHeaders(h http.Header) map[string]string {
+func (w *LambdaResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+// Flush commits the headers; the pipe itself is unbuffered
+func (w *LambdaResponseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+}
+
+// Ready is closed once status and headers are final
+func (w *LambdaResponseWriter) Ready() <-chan struct{} {
+	return w.ready
+}
+
+// Response builds the streaming response around body. Call it after Ready.
+func (w *LambdaResponseWriter) Response(body io.Reader) *events.LambdaFunctionURLStreamingResponse {
+	headers, cookies := FlattenHeaders(w.committed)
+	return &events.LambdaFunctionURLStreamingResponse{
+		StatusCode: w.status,
+		Headers:    headers,
+		Cookies:    cookies,
+		Body:       body,
+	}
+}
+
+// FlattenHeaders joins multi-value headers and splits out Set-Cookie, which
+// function URLs expect in their own field
+func FlattenHeaders(h http.Header) (map[string]string, []string) {
 	result := make(map[string]string)
+	var cookies []string
 	for k, v := range h {
-		if len(v) > 0 {
-			result[k] = v[0]
+		if len(v) == 0 {
+			continue
+		}
+		if http.CanonicalHeaderKey(k) == "Set-Cookie" {
+			cookies = append(cookies, v...)
+			continue
+		}
+		result[k] = strings.Join(v, ", ")
+	}
+	return result, cookies
+}
+
+// NewHTTPRequestFromLambda rebuilds the original HTTP request from a function URL event
+func NewHTTPRequestFromLambda(ctx context.Context, req events.LambdaFunctionURLRequest) (*http.Request, error) {
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			return nil, err
 		}
+		body = decoded
+	}
+
+	path := req.RawPath
+	if path == "" {
+		path = req.RequestContext.HTTP.Path
+	}
+	target := &url.URL{Path: path, RawQuery: req.RawQueryString}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.RequestContext.HTTP.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range req.Headers {
+		httpReq.Header.Set(k, v)
 	}
-	return result
-}
\ No newline at end of file
+	if len(req.Cookies) > 0 {
+		httpReq.Header.Set("Cookie", strings.Join(req.Cookies, "; "))
+	}
+
+	httpReq.Host = req.RequestContext.DomainName
+	if host := httpReq.Header.Get("Host"); host != "" {
+		httpReq.Host = host
+	}
+	httpReq.RemoteAddr = req.RequestContext.HTTP.SourceIP
+	httpReq.RequestURI = target.RequestURI()
+	httpReq.ContentLength = int64(len(body))
+
+	return httpReq, nil
+}
+
+// ServeLambdaFunctionURL runs handler for a function URL event and streams its
+// response back through the Lambda runtime
+func ServeLambdaFunctionURL(ctx context.Context, handler http.Handler, req events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+	httpReq, err := NewHTTPRequestFromLambda(ctx, req)
+	if err != nil {
+		return &events.LambdaFunctionURLStreamingResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       strings.NewReader("invalid request body"),
+		}, nil
+	}
+
+	reader, writer := io.Pipe()
+	w := NewLambdaResponseWriter(writer)
+
+	go func() {
+		defer func() {
+			w.WriteHeader(http.StatusOK)
+			writer.Close()
+		}()
+		handler.ServeHTTP(w, httpReq)
+	}()
+
+	select {
+	case <-w.Ready():
+		return w.Response(reader), nil
+	case <-ctx.Done():
+		reader.CloseWithError(ctx.Err())
+		return nil, ctx.Err()
+	}
+}
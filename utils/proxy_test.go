@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func functionURLRequest(method, path, query, body string, base64Body bool) events.LambdaFunctionURLRequest {
+	req := events.LambdaFunctionURLRequest{
+		RawPath:         path,
+		RawQueryString:  query,
+		Body:            body,
+		IsBase64Encoded: base64Body,
+		Headers:         map[string]string{"authorization": "Bearer t", "accept": "text/event-stream"},
+		Cookies:         []string{"a=1", "b=2"},
+	}
+	req.RequestContext.DomainName = "abc.lambda-url.eu-west-1.on.aws"
+	req.RequestContext.HTTP.Method = method
+	req.RequestContext.HTTP.Path = path
+	req.RequestContext.HTTP.SourceIP = "203.0.113.9"
+	return req
+}
+
+func TestNewHTTPRequestFromLambda(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      events.LambdaFunctionURLRequest
+		wantBody string
+		wantURI  string
+		wantErr  bool
+	}{
+		{name: "plain body", req: functionURLRequest(http.MethodPost, "/api/v1/facelog/upload", "stream=s1", "hello", false), wantBody: "hello", wantURI: "/api/v1/facelog/upload?stream=s1"},
+		{name: "base64 body", req: functionURLRequest(http.MethodPost, "/u", "", base64.StdEncoding.EncodeToString([]byte("\x00\xff")), true), wantBody: "\x00\xff", wantURI: "/u"},
+		{name: "bad base64", req: functionURLRequest(http.MethodPost, "/u", "", "!!", true), wantErr: true},
+		{name: "path from the request context", req: func() events.LambdaFunctionURLRequest {
+			req := functionURLRequest(http.MethodGet, "/api/v1/health", "", "", false)
+			req.RawPath = ""
+			return req
+		}(), wantURI: "/api/v1/health"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewHTTPRequestFromLambda(context.Background(), tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewHTTPRequestFromLambda = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != tt.wantBody || r.ContentLength != int64(len(tt.wantBody)) {
+				t.Errorf("body = %q (length %d), want %q", body, r.ContentLength, tt.wantBody)
+			}
+			if r.RequestURI != tt.wantURI {
+				t.Errorf("RequestURI = %q, want %q", r.RequestURI, tt.wantURI)
+			}
+			if r.Header.Get("Authorization") != "Bearer t" || r.Header.Get("Cookie") != "a=1; b=2" {
+				t.Errorf("headers = %v", r.Header)
+			}
+			if r.Host != "abc.lambda-url.eu-west-1.on.aws" || r.RemoteAddr != "203.0.113.9" || r.Method != tt.req.RequestContext.HTTP.Method {
+				t.Errorf("host %q, remote %q, method %q", r.Host, r.RemoteAddr, r.Method)
+			}
+		})
+	}
+}
+
+func TestFlattenHeaders(t *testing.T) {
+	headers, cookies := FlattenHeaders(http.Header{
+		"Content-Type": {"text/event-stream"},
+		"Vary":         {"Origin", "Accept"},
+		"Set-Cookie":   {"a=1", "b=2"},
+		"Empty":        {},
+	})
+	want := map[string]string{"Content-Type": "text/event-stream", "Vary": "Origin, Accept"}
+	if fmt.Sprint(headers) != fmt.Sprint(want) {
+		t.Errorf("headers = %v, want %v", headers, want)
+	}
+	if fmt.Sprint(cookies) != "[a=1 b=2]" {
+		t.Errorf("cookies = %v", cookies)
+	}
+}
+
+func TestServeLambdaFunctionURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		req        events.LambdaFunctionURLRequest
+		wantStatus int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{
+			name: "status and headers",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Add("Set-Cookie", "s=1")
+				w.WriteHeader(http.StatusCreated)
+				io.WriteString(w, `{"ok":true}`)
+			},
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"ok":true}`,
+			wantHeader: map[string]string{"Content-Type": "application/json"},
+		},
+		{
+			name: "headers set after the first write are not sent",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "a")
+				w.Header().Set("X-Late", "1")
+				io.WriteString(w, "b")
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "ab",
+			wantHeader: map[string]string{"X-Late": ""},
+		},
+		{
+			name:       "handler writes nothing",
+			handler:    func(w http.ResponseWriter, r *http.Request) {},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "echoes the request",
+			handler:    func(w http.ResponseWriter, r *http.Request) { io.Copy(w, r.Body) },
+			req:        functionURLRequest(http.MethodPost, "/echo", "", "ping", false),
+			wantStatus: http.StatusOK,
+			wantBody:   "ping",
+		},
+		{
+			name:       "undecodable body",
+			handler:    func(w http.ResponseWriter, r *http.Request) { t.Error("handler ran") },
+			req:        functionURLRequest(http.MethodPost, "/echo", "", "!!", true),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid request body",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req.RequestContext.HTTP.Method == "" {
+				req = functionURLRequest(http.MethodGet, "/", "", "", false)
+			}
+			resp, err := ServeLambdaFunctionURL(context.Background(), tt.handler, req)
+			if err != nil {
+				t.Fatalf("ServeLambdaFunctionURL: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus || string(body) != tt.wantBody {
+				t.Fatalf("response = %d %q, want %d %q", resp.StatusCode, body, tt.wantStatus, tt.wantBody)
+			}
+			for k, v := range tt.wantHeader {
+				if resp.Headers[k] != v {
+					t.Errorf("header %s = %q, want %q", k, resp.Headers[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestServeLambdaFunctionURLStreams(t *testing.T) {
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "event: ready\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		io.WriteString(w, "event: done\n\n")
+	})
+
+	resp, err := ServeLambdaFunctionURL(context.Background(), handler, functionURLRequest(http.MethodGet, "/", "", "", false))
+	if err != nil {
+		t.Fatalf("ServeLambdaFunctionURL: %v", err)
+	}
+	// the response is handed back while the handler is still running
+	first := make([]byte, len("event: ready\n\n"))
+	if _, err := io.ReadFull(resp.Body, first); err != nil || string(first) != "event: ready\n\n" {
+		t.Fatalf("first frame = %q, %v", first, err)
+	}
+	close(release)
+	rest, _ := io.ReadAll(resp.Body)
+	if string(rest) != "event: done\n\n" {
+		t.Fatalf("rest = %q", rest)
+	}
+}
+
+func TestServeLambdaFunctionURLContextEnds(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	block := make(chan struct{})
+	defer close(block)
+
+	_, err := ServeLambdaFunctionURL(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-block }), functionURLRequest(http.MethodGet, "/", "", "", false))
+	if err != context.DeadlineExceeded {
+		t.Fatalf("ServeLambdaFunctionURL = %v, want the context's error", err)
+	}
+}
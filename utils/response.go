@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"io"
@@ -30,6 +31,11 @@ func BindHttpResponseToStruct(response *http.Response, responseTypeStruct interf
 	}
 	defer response.Body.Close()
 
+	// a body is expected here, unlike in BindByteResponseToStruct
+	if len(bytes.TrimSpace(body)) == 0 {
+		return errors.New("response body is empty")
+	}
+
 	if err := BindByteResponseToStruct(body, &responseTypeStruct); err != nil {
 		return err
 	}
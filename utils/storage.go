@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/muthu-kumar-u/go-sse/globals"
+	"github.com/muthu-kumar-u/go-sse/storage"
+)
+
+// ConfigureImageStore keeps uploaded images in the S3 bucket IMAGE_BUCKET when
+// it is set. Objects are encrypted with IMAGE_SSE (AES256 by default, or
+// aws:kms with IMAGE_SSE_KMS_KEY_ID). S3_ENDPOINT and S3_FORCE_PATH_STYLE point
+// the client at a local S3-compatible stand-in.
+func ConfigureImageStore() error {
+	bucket := os.Getenv("IMAGE_BUCKET")
+	if bucket == "" {
+		return nil
+	}
+
+	encryption := os.Getenv("IMAGE_SSE")
+	switch encryption {
+	case "":
+		encryption = "AES256"
+	case "AES256", "aws:kms":
+	default:
+		return fmt.Errorf("unknown IMAGE_SSE %q", encryption)
+	}
+	pathStyle, _ := strconv.ParseBool(os.Getenv("S3_FORCE_PATH_STYLE"))
+
+	globals.ImageStore = storage.NewS3(globals.AWSSession, storage.S3Options{
+		Bucket:     bucket,
+		Encryption: encryption,
+		KMSKeyID:   os.Getenv("IMAGE_SSE_KMS_KEY_ID"),
+		Endpoint:   os.Getenv("S3_ENDPOINT"),
+		PathStyle:  pathStyle,
+	})
+	log.Printf("Image store: s3://%s/%s (%s)", bucket, ImageStorePrefix(), encryption)
+	return nil
+}
+
+// ImageStorePrefix is the key prefix of stored images, IMAGE_BUCKET_PREFIX or
+// uploads
+func ImageStorePrefix() string {
+	if prefix := os.Getenv("IMAGE_BUCKET_PREFIX"); prefix != "" {
+		return prefix
+	}
+	return "uploads"
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/muthu-kumar-u/go-sse/events/webhook"
+	"github.com/muthu-kumar-u/go-sse/globals"
+)
+
+// ConfigureWebhooks builds the completion webhook dispatcher and starts its retry loop.
+// Without a WEBHOOK_SIGNING_SECRET nothing could authenticate the payloads, so
+// webhooks stay disabled. Callbacks must use https in production and may only
+// reach private addresses with WEBHOOK_ALLOW_PRIVATE_HOSTS outside it.
+//
+// Clients may register a default callback, kept at WEBHOOK_REGISTRY_PATH or
+// next to the retry queue in WEBHOOK_QUEUE_DIR. Registrations belong to this
+// instance alone, so production, where every Lambda instance has its own,
+// leaves registration off and only takes callback URLs with each upload.
+func ConfigureWebhooks(ctx context.Context) error {
+	globals.WebhookRegistry = nil
+
+	secret := os.Getenv("WEBHOOK_SIGNING_SECRET")
+	if secret == "" {
+		log.Println("WEBHOOK_SIGNING_SECRET is empty, completion webhooks are disabled")
+		return nil
+	}
+
+	production := os.Getenv("PRODUCTION") == "true"
+	allowPrivate, _ := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_PRIVATE_HOSTS"))
+	if allowPrivate && production {
+		return errors.New("WEBHOOK_ALLOW_PRIVATE_HOSTS cannot be used in production")
+	}
+	maxAttempts, _ := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS"))
+
+	queueDir := os.Getenv("WEBHOOK_QUEUE_DIR")
+	queue, err := webhook.NewQueue(queueDir)
+	if err != nil {
+		return err
+	}
+
+	if production {
+		log.Println("Webhook registration is off in production, callbacks come with each upload")
+	} else {
+		registryPath := os.Getenv("WEBHOOK_REGISTRY_PATH")
+		if registryPath == "" && queueDir != "" {
+			// a directory of its own, the queue reads every json file in its own
+			registryPath = filepath.Join(queueDir, "registry", "clients.json")
+		}
+		registry, err := webhook.NewRegistry(registryPath)
+		if err != nil {
+			return fmt.Errorf("failed to load webhook registry %s: %w", registryPath, err)
+		}
+		globals.WebhookRegistry = registry
+	}
+
+	globals.Webhooks = webhook.NewDispatcher(webhook.Config{
+		Secret:            secret,
+		MaxAttempts:       maxAttempts,
+		RequireHTTPS:      production,
+		AllowPrivateHosts: allowPrivate,
+	}, nil, queue)
+
+	go globals.Webhooks.Start(ctx)
+	return nil
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/muthu-kumar-u/go-sse/globals"
+)
+
+func TestConfigureWebhooks(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantErr      bool
+		wantEnabled  bool
+		wantRegistry bool
+		acceptedURL  string
+		rejectedURLs []string
+	}{
+		{
+			name: "no secret",
+		},
+		{
+			name:         "development",
+			env:          map[string]string{"WEBHOOK_SIGNING_SECRET": "s"},
+			wantEnabled:  true,
+			wantRegistry: true,
+			acceptedURL:  "http://93.184.216.34/hook",
+			rejectedURLs: []string{"http://127.0.0.1/hook", "http://169.254.169.254/"},
+		},
+		{
+			name:         "production",
+			env:          map[string]string{"WEBHOOK_SIGNING_SECRET": "s", "PRODUCTION": "true"},
+			wantEnabled:  true,
+			acceptedURL:  "https://93.184.216.34/hook",
+			rejectedURLs: []string{"http://93.184.216.34/hook", "https://10.0.0.1/hook"},
+		},
+		{
+			name:         "private hosts outside production",
+			env:          map[string]string{"WEBHOOK_SIGNING_SECRET": "s", "WEBHOOK_ALLOW_PRIVATE_HOSTS": "true"},
+			wantEnabled:  true,
+			wantRegistry: true,
+			acceptedURL:  "http://127.0.0.1:9000/hook",
+		},
+		{
+			name:    "private hosts in production",
+			env:     map[string]string{"WEBHOOK_SIGNING_SECRET": "s", "WEBHOOK_ALLOW_PRIVATE_HOSTS": "true", "PRODUCTION": "true"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"WEBHOOK_SIGNING_SECRET", "WEBHOOK_ALLOW_PRIVATE_HOSTS", "WEBHOOK_QUEUE_DIR", "WEBHOOK_MAX_ATTEMPTS", "WEBHOOK_REGISTRY_PATH", "PRODUCTION"} {
+				t.Setenv(key, tt.env[key])
+			}
+			globals.Webhooks = nil
+			defer func() { globals.Webhooks, globals.WebhookRegistry = nil, nil }()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err := ConfigureWebhooks(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConfigureWebhooks = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			// registrations would differ per production instance
+			if (globals.WebhookRegistry != nil) != tt.wantRegistry {
+				t.Fatalf("registry configured = %v, want %v", globals.WebhookRegistry != nil, tt.wantRegistry)
+			}
+			if (globals.Webhooks != nil) != tt.wantEnabled {
+				t.Fatalf("webhooks enabled = %v, want %v", globals.Webhooks != nil, tt.wantEnabled)
+			}
+			if !tt.wantEnabled {
+				return
+			}
+			if err := globals.Webhooks.ValidateURL(tt.acceptedURL); err != nil {
+				t.Errorf("ValidateURL(%q) = %v", tt.acceptedURL, err)
+			}
+			for _, url := range tt.rejectedURLs {
+				if err := globals.Webhooks.ValidateURL(url); err == nil {
+					t.Errorf("ValidateURL(%q) accepted", url)
+				}
+			}
+		})
+	}
+}